@@ -4,9 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdbranches"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdcheckpoint"
 	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdcode"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdcodeowners"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdlicenses"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdsecrets"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdstats"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdsummary"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdtodos"
 	"github.com/spf13/cobra"
 )
 
@@ -25,6 +33,20 @@ var codeCmd = &cobra.Command{
 		opts.Dir = args[0]
 		opts.CommitFromIncl, _ = cmd.Flags().GetString("sha")
 		opts.Profile, _ = cmd.Flags().GetString("profile")
+		opts.IgnoreWhitespace, _ = cmd.Flags().GetBool("ignore-whitespace")
+		opts.IgnoreRevs, _ = cmd.Flags().GetStringSlice("ignore-rev")
+		opts.IgnoreRevsFile, _ = cmd.Flags().GetString("ignore-revs-file")
+		opts.NoIgnoreRevsFile, _ = cmd.Flags().GetBool("no-ignore-revs-file")
+		opts.Submodules, _ = cmd.Flags().GetBool("submodules")
+		opts.Bots, _ = cmd.Flags().GetString("bots")
+		opts.BotAuthors, _ = cmd.Flags().GetStringSlice("bot-author")
+		opts.MaxMemoryMB, _ = cmd.Flags().GetInt("max-memory-mb")
+		opts.CheckpointEvery, _ = cmd.Flags().GetString("checkpoint-every")
+		opts.Resume, _ = cmd.Flags().GetBool("resume")
+		opts.RebuildInvalidCheckpoint, _ = cmd.Flags().GetBool("rebuild-invalid-checkpoint")
+		opts.CheckpointStore, _ = cmd.Flags().GetString("checkpoint-store")
+		opts.DiffSource, _ = cmd.Flags().GetString("diff-source")
+		opts.SinglePassLog, _ = cmd.Flags().GetBool("single-pass-log")
 		cmdcode.Run(ctx, os.Stdout, opts)
 	},
 }
@@ -43,6 +65,157 @@ var branchesCmd = &cobra.Command{
 	},
 }
 
+var codeownersCmd = &cobra.Command{
+	Use:   "codeowners <dir>",
+	Short: "Validates CODEOWNERS file against blame data and suggests owners",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdcodeowners.Opts{}
+		opts.RepoDir = args[0]
+		opts.SuggestFile, _ = cmd.Flags().GetString("suggest")
+		opts.Bots, _ = cmd.Flags().GetString("bots")
+		opts.BotAuthors, _ = cmd.Flags().GetStringSlice("bot-author")
+		opts.CodeOwners.SuggestDepth, _ = cmd.Flags().GetInt("suggest-depth")
+		opts.CodeOwners.SuggestMinShare, _ = cmd.Flags().GetFloat64("suggest-min-share")
+		aliases, _ := cmd.Flags().GetStringToString("alias")
+		if len(aliases) != 0 {
+			opts.CodeOwners.Aliases = map[string][]string{}
+			for owner, emails := range aliases {
+				opts.CodeOwners.Aliases[owner] = strings.Split(emails, ",")
+			}
+		}
+		cmdcodeowners.Run(ctx, os.Stdout, opts)
+	},
+}
+
+var summaryCmd = &cobra.Command{
+	Use:   "summary <dir>",
+	Short: "Outputs totals for languages, files, authors, licenses and skipped files as json",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdsummary.Opts{}
+		opts.RepoDir = args[0]
+		opts.Commits, _ = cmd.Flags().GetStringSlice("commits")
+		opts.Bots, _ = cmd.Flags().GetString("bots")
+		opts.BotAuthors, _ = cmd.Flags().GetStringSlice("bot-author")
+		cmdsummary.Run(ctx, os.Stdout, opts)
+	},
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <dir>",
+	Short: "Outputs running repo totals after each commit as json lines",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdstats.Opts{}
+		opts.RepoDir = args[0]
+		opts.CommitFromIncl, _ = cmd.Flags().GetString("sha")
+		cmdstats.Run(ctx, os.Stdout, opts)
+	},
+}
+
+var licensesCmd = &cobra.Command{
+	Use:   "licenses <dir>",
+	Short: "Outputs licenses from license files, SPDX tags and copyright holders as json",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdlicenses.Opts{}
+		opts.RepoDir = args[0]
+		opts.Commits, _ = cmd.Flags().GetStringSlice("commits")
+		opts.LicenseMinConfidence, _ = cmd.Flags().GetFloat32("min-confidence")
+		opts.CustomLicensesDir, _ = cmd.Flags().GetString("custom-licenses")
+		cmdlicenses.Run(ctx, os.Stdout, opts)
+	},
+}
+
+var secretsCmd = &cobra.Command{
+	Use:   "secrets <dir>",
+	Short: "Outputs possible secrets in lines added in all commits as json lines",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdsecrets.Opts{}
+		opts.RepoDir = args[0]
+		opts.CommitFromIncl, _ = cmd.Flags().GetString("sha")
+		opts.Rules, _ = cmd.Flags().GetStringToString("rule")
+		cmdsecrets.Run(ctx, os.Stdout, opts)
+	},
+}
+
+var todosCmd = &cobra.Command{
+	Use:   "todos <dir>",
+	Short: "Outputs TODO comments with author and age from blame as json",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdtodos.Opts{}
+		opts.RepoDir = args[0]
+		opts.Commits, _ = cmd.Flags().GetStringSlice("commits")
+		opts.Tags, _ = cmd.Flags().GetStringSlice("tags")
+		cmdtodos.Run(ctx, os.Stdout, opts)
+	},
+}
+
+var checkpointCmd = &cobra.Command{
+	Use:   "checkpoint",
+	Short: "Inspects incremental blame checkpoints",
+}
+
+var checkpointInspectCmd = &cobra.Command{
+	Use:   "inspect <dir>",
+	Short: "Outputs checkpoint header and commits stored with number of files and lines",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdcheckpoint.InspectOpts{}
+		opts.Dir = args[0]
+		opts.Files, _ = cmd.Flags().GetBool("files")
+		opts.Debug, _ = cmd.Flags().GetBool("debug")
+		cmdcheckpoint.Inspect(ctx, os.Stdout, opts)
+	},
+}
+
+var checkpointBlameCmd = &cobra.Command{
+	Use:   "blame <dir> <commit> <file>",
+	Short: "Outputs blame stored in checkpoint for file at commit",
+	Args:  cobra.ExactArgs(3),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdcheckpoint.BlameOpts{}
+		opts.Dir = args[0]
+		opts.Commit = args[1]
+		opts.File = args[2]
+		cmdcheckpoint.Blame(ctx, os.Stdout, opts)
+	},
+}
+
+var checkpointDiffCmd = &cobra.Command{
+	Use:   "diff <dir1> <dir2>",
+	Short: "Outputs commits, files and blames that differ between two checkpoints",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opts := cmdcheckpoint.DiffOpts{}
+		opts.Dir1 = args[0]
+		opts.Dir2 = args[1]
+		opts.Lines, _ = cmd.Flags().GetBool("lines")
+		cmdcheckpoint.Diff(ctx, os.Stdout, opts)
+	},
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -51,11 +224,62 @@ func Execute() {
 
 	codeCmd.Flags().String("sha", "", "start streaming from sha")
 	codeCmd.Flags().String("profile", "", "one of mem, mutex, cpu, block, trace or empty to disable")
+	codeCmd.Flags().Bool("ignore-whitespace", false, "keep previous author for lines where only whitespace changed")
+	codeCmd.Flags().StringSlice("ignore-rev", nil, "commits that should not take ownership of changed lines, for example reformatting")
+	codeCmd.Flags().String("ignore-revs-file", "", "file with commits to ignore, default is blame.ignoreRevsFile from git config or .git-blame-ignore-revs")
+	codeCmd.Flags().Bool("no-ignore-revs-file", false, "do not read ignore revs file")
+	codeCmd.Flags().Bool("submodules", false, "also process checked out submodules")
+	codeCmd.Flags().String("bots", "keep", "how lines from bot commits are handled: keep, exclude or previous-author")
+	codeCmd.Flags().StringSlice("bot-author", nil, "regexp matching bot author name or email, replaces default list")
+	codeCmd.Flags().String("checkpoint-every", "", "write intermediate checkpoints every number of commits (1000) or duration (10m)")
+	codeCmd.Flags().Bool("resume", false, "continue from the latest checkpoint")
+	codeCmd.Flags().String("checkpoint-store", "", "dir to keep checkpoint bundles in, checkpoint is fetched from it on resume and stored after each write")
+	codeCmd.Flags().Bool("rebuild-invalid-checkpoint", false, "process from the beginning if checkpoint is corrupted or written in unsupported format")
+	codeCmd.Flags().String("diff-source", "git", "where diffs come from: git parses git log output, native reads repo objects directly")
+	codeCmd.Flags().Bool("single-pass-log", false, "read commit graph, metadata and patches using one git log when processing from the beginning")
+	codeCmd.Flags().Int("max-memory-mb", 0, "approximate memory budget for blame data, least recently used commits are moved to disk when exceeded, 0 to keep all in memory")
 	rootCmd.AddCommand(codeCmd)
 
 	branchesCmd.Flags().String("profile", "", "one of mem, mutex, cpu, block, trace or empty to disable")
 	rootCmd.AddCommand(branchesCmd)
 
+	codeownersCmd.Flags().String("suggest", "", "write suggested CODEOWNERS to this file")
+	codeownersCmd.Flags().Int("suggest-depth", 1, "directory depth for suggested CODEOWNERS rules")
+	codeownersCmd.Flags().Float64("suggest-min-share", 0.1, "min share of lines in directory for suggested owner")
+	codeownersCmd.Flags().StringToString("alias", nil, "map owner to author emails, for example @org/team=a@example.com,b@example.com")
+	codeownersCmd.Flags().String("bots", "keep", "how lines from bot commits are handled: keep, exclude or previous-author")
+	codeownersCmd.Flags().StringSlice("bot-author", nil, "regexp matching bot author name or email, replaces default list")
+	rootCmd.AddCommand(codeownersCmd)
+
+	summaryCmd.Flags().StringSlice("commits", nil, "commits to output summary for, defaults to HEAD")
+	summaryCmd.Flags().String("bots", "keep", "how lines from bot commits are handled: keep, exclude or previous-author")
+	summaryCmd.Flags().StringSlice("bot-author", nil, "regexp matching bot author name or email, replaces default list")
+	rootCmd.AddCommand(summaryCmd)
+
+	statsCmd.Flags().String("sha", "", "start streaming from sha")
+	rootCmd.AddCommand(statsCmd)
+
+	licensesCmd.Flags().StringSlice("commits", nil, "commits to output license inventory for, defaults to HEAD")
+	licensesCmd.Flags().Float32("min-confidence", 0.85, "minimum confidence for license to be detected in license file")
+	licensesCmd.Flags().String("custom-licenses", "", "dir with custom license texts, file name is used as license name")
+	rootCmd.AddCommand(licensesCmd)
+
+	secretsCmd.Flags().String("sha", "", "start streaming from sha")
+	secretsCmd.Flags().StringToString("rule", nil, "additional rule as id=regexp, first capture group is used as secret value")
+	rootCmd.AddCommand(secretsCmd)
+
+	todosCmd.Flags().StringSlice("commits", nil, "commits to output TODOs for, defaults to HEAD")
+	todosCmd.Flags().StringSlice("tags", nil, "tags to find in comments, defaults to TODO,FIXME,HACK")
+	rootCmd.AddCommand(todosCmd)
+
+	checkpointInspectCmd.Flags().Bool("files", false, "list files with line counts for each commit")
+	checkpointInspectCmd.Flags().Bool("debug", false, "output full checkpoint content")
+	checkpointCmd.AddCommand(checkpointInspectCmd)
+	checkpointCmd.AddCommand(checkpointBlameCmd)
+	checkpointDiffCmd.Flags().Bool("lines", false, "output lines that differ")
+	checkpointCmd.AddCommand(checkpointDiffCmd)
+	rootCmd.AddCommand(checkpointCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -27,6 +27,7 @@ var manuallyChecked = map[string]bool{
 	// incremental blame condiders it a new file
 	// regular git blame considers it copy + change in another
 	// both files exist
+	// with --detect-moves the copied lines keep the original commit
 	"test/ssr/fixtures/async-bar.js": true,
 }
 */
@@ -43,6 +44,12 @@ var validateIncBlameCmd = &cobra.Command{
 			opts := process.Opts{}
 			opts.RepoDir = repoDir
 			opts.CommitFromIncl, _ = cmd.Flags().GetString("commit-from-incl")
+			opts.DetectMoves, _ = cmd.Flags().GetBool("detect-moves")
+
+			var blameArgs []string
+			if opts.DetectMoves {
+				blameArgs = []string{"-M", "-C"}
+			}
 
 			pr := process.New(opts)
 
@@ -63,7 +70,7 @@ var validateIncBlameCmd = &cobra.Command{
 							// removed file, no blame
 							continue
 						}
-						bl2, err := gitblame2.Run(repoDir, r.Commit, p)
+						bl2, err := gitblame2.Run(repoDir, r.Commit, p, blameArgs...)
 						if err != nil {
 							panic(err)
 						}
@@ -128,5 +135,6 @@ var validateIncBlameCmd = &cobra.Command{
 func RegisterIncBlame() {
 	cmd := validateIncBlameCmd
 	cmd.Flags().String("commit-from-incl", "", "start from specific commit (inclusive)")
+	cmd.Flags().Bool("detect-moves", false, "detect moved and copied lines and compare with git blame -M -C")
 	rootCmd.AddCommand(cmd)
 }
@@ -134,6 +134,9 @@ func blameLineEqual(t *testing.T, l1, l2 *ripsrc.BlameLine) bool {
 	if l1.Blank != l2.Blank {
 		return false
 	}
+	if l1.Bot != l2.Bot {
+		return false
+	}
 	if l1.SHA != "" {
 		if l1.SHA != l2.SHA {
 			return false
@@ -0,0 +1,130 @@
+package e2etests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/stretchr/testify/assert"
+)
+
+// c2 is a release bot commit changing line 3 and adding line 4
+const (
+	botsC1 = "cb3f082a59062c41f8fb561cdc64730f9c6d4cdc"
+	botsC2 = "d2dd40387e05568c327452fc90d4c318bc88f5ec"
+	botsC3 = "eb49efd5b27e3e1f80acb81925a8fbe5d8f3c44f"
+)
+
+func runBots(t *testing.T, opts *ripsrc.Opts) (commits []ripsrc.CommitCode, last ripsrc.BlameResult) {
+	NewTest(t, "bots").Run(opts, func(rip *ripsrc.Ripsrc) {
+		ch := make(chan ripsrc.CommitCode)
+		done := make(chan bool)
+		go func() {
+			for c := range ch {
+				for b := range c.Blames {
+					last = b
+				}
+				commits = append(commits, c)
+			}
+			done <- true
+		}()
+		defer func() { <-done }()
+		err := rip.CodeByCommit(context.Background(), ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(commits) != 3 {
+		t.Fatalf("wanted 3 commits, got %v", len(commits))
+	}
+	return
+}
+
+func lineSHAs(lines []*ripsrc.BlameLine) (res []string) {
+	for _, l := range lines {
+		res = append(res, l.SHA)
+	}
+	return
+}
+
+func TestBotsKeep(t *testing.T) {
+	commits, last := runBots(t, nil)
+
+	assert.False(t, commits[0].Bot)
+	assert.True(t, commits[1].Bot)
+	assert.False(t, commits[2].Bot)
+	assert.False(t, commits[1].Ignored)
+
+	assert.Equal(t, []string{botsC1, botsC1, botsC2, botsC2, botsC3, botsC3}, lineSHAs(last.Lines))
+	var bot []bool
+	for _, l := range last.Lines {
+		bot = append(bot, l.Bot)
+	}
+	assert.Equal(t, []bool{false, false, true, true, false, false}, bot)
+}
+
+func TestBotsPreviousAuthor(t *testing.T) {
+	opts := &ripsrc.Opts{}
+	opts.BotLines = ripsrc.BotLinesPreviousAuthor
+	commits, last := runBots(t, opts)
+
+	assert.True(t, commits[1].Bot)
+	assert.True(t, commits[1].Ignored)
+	// changed line keeps previous author, new line is still from bot
+	assert.Equal(t, []string{botsC1, botsC1, botsC1, botsC2, botsC3, botsC3}, lineSHAs(last.Lines))
+	assert.Equal(t, "user1@example.com", last.Lines[2].Email)
+	assert.False(t, last.Lines[2].Bot)
+}
+
+func TestBotsCustomAuthors(t *testing.T) {
+	opts := &ripsrc.Opts{}
+	opts.BotAuthors = []string{`^user2@`}
+	commits, _ := runBots(t, opts)
+
+	assert.False(t, commits[0].Bot)
+	assert.False(t, commits[1].Bot)
+	assert.True(t, commits[2].Bot)
+}
+
+func TestBotsInvalidPattern(t *testing.T) {
+	opts := &ripsrc.Opts{}
+	opts.BotAuthors = []string{`(`}
+	NewTest(t, "bots").Run(opts, func(rip *ripsrc.Ripsrc) {
+		ch := make(chan ripsrc.CommitCode)
+		go func() {
+			for range ch {
+			}
+		}()
+		err := rip.CodeByCommit(context.Background(), ch)
+		if err == nil {
+			t.Fatal("expected error for invalid pattern")
+		}
+	})
+}
+
+func TestBotsSummary(t *testing.T) {
+	summary := func(opts *ripsrc.Opts) (res ripsrc.Summary) {
+		NewTest(t, "bots").Run(opts, func(rip *ripsrc.Ripsrc) {
+			got, err := rip.Summary(context.Background(), ripsrc.SummaryOpts{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			res = got[0]
+		})
+		return
+	}
+
+	got := summary(nil)
+	assert.Len(t, got.Authors, 3)
+	for _, a := range got.Authors {
+		assert.Equal(t, a.Email == "semantic-release-bot@martynus.net", a.Bot, a.Email)
+	}
+
+	opts := &ripsrc.Opts{}
+	opts.BotLines = ripsrc.BotLinesExclude
+	got = summary(opts)
+	assert.Equal(t, []ripsrc.AuthorSummary{
+		{Name: "User1", Email: "user1@example.com", Lines: 2, Sloc: 1},
+		{Name: "User2", Email: "user2@example.com", Lines: 2, Sloc: 1},
+	}, got.Authors)
+}
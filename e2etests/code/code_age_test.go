@@ -0,0 +1,105 @@
+package e2etests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodeAgeByCommit(t *testing.T) {
+	var got []ripsrc.CommitCode
+	var blames [][]ripsrc.BlameResult
+
+	opts := &ripsrc.Opts{}
+	opts.CodeAgeByCommit = true
+
+	NewTest(t, "basic").Run(opts, func(rip *ripsrc.Ripsrc) {
+		ch := make(chan ripsrc.CommitCode)
+		done := make(chan bool)
+		go func() {
+			for c := range ch {
+				var bl []ripsrc.BlameResult
+				for f := range c.Blames {
+					bl = append(bl, f)
+				}
+				got = append(got, c)
+				blames = append(blames, bl)
+			}
+			done <- true
+		}()
+		defer func() { <-done }()
+		err := rip.CodeByCommit(context.Background(), ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if len(got) != 2 {
+		t.Fatalf("wanted 2 commits, got %v", len(got))
+	}
+
+	c1d := parseGitDate("Tue Nov 27 21:55:36 2018 +0100")
+
+	age := got[0].Age
+	if age == nil {
+		t.Fatal("repo code age not set")
+	}
+	if age.Lines != 8 {
+		t.Errorf("wanted 8 lines in first commit, got %v", age.Lines)
+	}
+	if age.Buckets[0].Lines != 8 {
+		t.Errorf("wanted all lines in first age bucket, got %+v", age.Buckets)
+	}
+
+	age = got[1].Age
+	if age.Lines != 6 {
+		t.Errorf("wanted 6 lines in second commit, got %v", age.Lines)
+	}
+
+	fileAge := blames[1][0].Age
+	if fileAge.Lines != 6 {
+		t.Errorf("wanted 6 lines in file, got %v", fileAge.Lines)
+	}
+	if !fileAge.OldestDate.Equal(c1d) {
+		t.Errorf("wanted oldest line date %v, got %v", c1d, fileAge.OldestDate)
+	}
+	// all lines except one were added in first commit 35s before
+	if fileAge.Median.Seconds() != 35 {
+		t.Errorf("wanted median age 35s, got %v", fileAge.Median)
+	}
+}
+
+func TestCodeAgeByCommitBranches(t *testing.T) {
+	dirs := unzipBranchedRepo(t)
+	defer dirs.Remove()
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	opts.CodeAgeByCommit = true
+	commits := runByCommit(t, opts)
+	if len(commits) != 6 {
+		t.Fatalf("wanted 6 commits, got %v", len(commits))
+	}
+	for _, c := range commits {
+		_, loc := gitLoc(t, dirs.RepoDir, c.SHA)
+		if int64(c.Age.Lines) != loc {
+			t.Errorf("wanted %v lines in commit %v, got %v", loc, c.SHA, c.Age.Lines)
+		}
+	}
+
+	// state of the checkpoint commit is built from all files when resuming
+	opts.Resume = true
+	resumed := runByCommit(t, opts)
+	if len(resumed) != 1 {
+		t.Fatalf("wanted 1 commit, got %v", len(resumed))
+	}
+	want := commits[len(commits)-1].Age
+	got := resumed[0].Age
+	assert.Equal(t, want.Lines, got.Lines)
+	assert.Equal(t, want.Median, got.Median)
+	assert.Equal(t, want.OldestDate.Unix(), got.OldestDate.Unix())
+}
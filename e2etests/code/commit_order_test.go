@@ -0,0 +1,79 @@
+package e2etests
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+// commitFileAt writes file and commits it with author and committer date set to date
+func commitFileAt(t *testing.T, repoDir string, fp, content string, date time.Time) {
+	t.Helper()
+	err := ioutil.WriteFile(filepath.Join(repoDir, fp), []byte(content), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	git(t, repoDir, "add", fp)
+	cmd := exec.Command("git", "-c", "user.name=B", "-c", "user.email=b@example.com", "commit", "-q", "-m", fp)
+	cmd.Dir = repoDir
+	d := date.Format(time.RFC3339)
+	cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+d, "GIT_COMMITTER_DATE="+d)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatal(err, string(out))
+	}
+}
+
+// unzipSkewedDatesRepo returns repo where commit on master is dated before its parent, so that default git log order shows the parent before it
+func unzipSkewedDatesRepo(t *testing.T) testutil.TestRepoDirs {
+	dirs := testutil.UnzipTestRepo("basic")
+	base := parseGitDate(git(t, dirs.RepoDir, "log", "-1", "--format=%cd")[0])
+	git(t, dirs.RepoDir, "branch", "side")
+	commitFileAt(t, dirs.RepoDir, "a.go", "package a\n", base.Add(-10*24*time.Hour))
+	git(t, dirs.RepoDir, "checkout", "-q", "side")
+	commitFileAt(t, dirs.RepoDir, "b.go", "package b\n", base.Add(24*time.Hour))
+	git(t, dirs.RepoDir, "checkout", "-q", "master")
+	git(t, dirs.RepoDir, "-c", "user.name=B", "-c", "user.email=b@example.com", "merge", "-q", "--no-edit", "side")
+	return dirs
+}
+
+func TestCommitOrdinalSinglePassLog(t *testing.T) {
+	dirs := unzipSkewedDatesRepo(t)
+	defer dirs.Remove()
+
+	ordinals := func(singlePass bool) (res []string) {
+		opts := ripsrc.Opts{}
+		opts.RepoDir = dirs.RepoDir
+		opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, fmt.Sprintf("checkpoints-%v", singlePass))
+		opts.SinglePassLog = singlePass
+		for i, c := range runByCommit(t, opts) {
+			// ordinal follows the order commits are returned in
+			assert.Equal(t, int64(i+1), c.Ordinal, c.SHA)
+			res = append(res, c.SHA)
+		}
+		return
+	}
+	assert.Equal(t, ordinals(false), ordinals(true))
+}
+
+func TestSinglePassLogStoresGraph(t *testing.T) {
+	dirs := unzipSkewedDatesRepo(t)
+	defer dirs.Remove()
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	opts.SinglePassLog = true
+	runByCommit(t, opts)
+	if _, err := os.Stat(filepath.Join(opts.CheckpointsDir, "pp-git-cache", "graph")); err != nil {
+		t.Fatal("graph read by single pass was not stored", err)
+	}
+}
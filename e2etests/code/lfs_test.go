@@ -0,0 +1,50 @@
+package e2etests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLFSPointer(t *testing.T) {
+	var got []ripsrc.BlameResult
+	var summary []ripsrc.Summary
+	NewTest(t, "lfs").Run(nil, func(rip *ripsrc.Ripsrc) {
+		var err error
+		got, err = rip.CodeSlice(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	NewTest(t, "lfs").Run(nil, func(rip *ripsrc.Ripsrc) {
+		var err error
+		summary, err = rip.Summary(context.Background(), ripsrc.SummaryOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	var pointer *ripsrc.BlameResult
+	for i, r := range got {
+		if r.Filename == "assets/logo.png" {
+			pointer = &got[i]
+		}
+	}
+	if pointer == nil {
+		t.Fatal("lfs pointer file not found in results")
+	}
+	assert.Equal(t, "File was a git lfs pointer", pointer.Skipped)
+	assert.Equal(t, "", pointer.Language)
+	assert.Equal(t, &ripsrc.LFSPointer{
+		Oid:  "sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393",
+		Size: 12345,
+	}, pointer.LFS)
+
+	s := summary[0]
+	assert.Equal(t, 1, s.LFSFiles)
+	assert.Equal(t, int64(12345), s.LFSSize)
+	// only main.go is counted as source
+	assert.Equal(t, 1, s.SourceFiles)
+}
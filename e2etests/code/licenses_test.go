@@ -0,0 +1,84 @@
+package e2etests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLicenses(t *testing.T) {
+	c1 := "66af720c8192b8a2a45afc8c51dd2e9cb15ea0ac"
+	c2 := "b0591110d8328071389f266908de16ecb4a9ed38"
+
+	var got []ripsrc.LicenseInventory
+	NewTest(t, "spdx").Run(nil, func(rip *ripsrc.Ripsrc) {
+		var err error
+		got, err = rip.Licenses(context.Background(), ripsrc.LicensesOpts{Commits: []string{c1, "HEAD"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if len(got) != 2 {
+		t.Fatalf("wanted 2 inventories, got %v", len(got))
+	}
+
+	assert.Equal(t, c1, got[0].Commit)
+	if len(got[0].Declared) != 2 {
+		t.Fatalf("wanted 2 declared licenses, got %+v", got[0].Declared)
+	}
+	assert.Equal(t, "lib.c", got[0].Declared[0].Filename)
+	assert.Equal(t, "Apache-2.0 OR MIT", got[0].Declared[0].License)
+	assert.Equal(t, 1, got[0].Declared[0].Line)
+	assert.Equal(t, "main.go", got[0].Declared[1].Filename)
+	assert.Equal(t, "MIT", got[0].Declared[1].License)
+	assert.Equal(t, "", got[0].Declared[1].Skipped)
+	assert.Equal(t, map[string]int{"Apache-2.0 OR MIT": 1, "MIT": 1}, got[0].Counts)
+
+	assert.Equal(t, c2, got[1].Commit)
+	if len(got[1].Declared) != 2 {
+		t.Fatalf("wanted 2 declared licenses, got %+v", got[1].Declared)
+	}
+	assert.Equal(t, "lib.c", got[1].Declared[0].Filename)
+	assert.Equal(t, "vendor/dep/dep.go", got[1].Declared[1].Filename)
+	assert.Equal(t, "BSD-3-Clause", got[1].Declared[1].License)
+	assert.NotEmpty(t, got[1].Declared[1].Skipped)
+	assert.Equal(t, []ripsrc.CopyrightHolderSummary{{Holder: "Example", Files: 1}}, got[1].CopyrightHolders)
+}
+
+// unzipParallelBranchesRepo returns merge_basic repo with a file added on master and another file added on branch from the first commit, merged at the end. Master commit is processed before branch commit, so it must not be included in branch state.
+func unzipParallelBranchesRepo(t *testing.T, masterFile, masterContent, sideFile, sideContent string) testutil.TestRepoDirs {
+	dirs := testutil.UnzipTestRepo("merge_basic")
+	now := time.Now()
+	git(t, dirs.RepoDir, "branch", "side", "cb78f81991af4120b649c5e2ae18cceba598220a")
+	commitFileAt(t, dirs.RepoDir, masterFile, masterContent, now.Add(-2*time.Hour))
+	git(t, dirs.RepoDir, "checkout", "-q", "side")
+	commitFileAt(t, dirs.RepoDir, sideFile, sideContent, now.Add(-time.Hour))
+	git(t, dirs.RepoDir, "checkout", "-q", "master")
+	git(t, dirs.RepoDir, "-c", "user.name=B", "-c", "user.email=b@example.com", "merge", "-q", "--no-edit", "side")
+	return dirs
+}
+
+func TestLicensesBranches(t *testing.T) {
+	dirs := unzipParallelBranchesRepo(t, "x.go", "// SPDX-License-Identifier: MIT\npackage main\n", "y.go", "// SPDX-License-Identifier: Apache-2.0\npackage main\n")
+	defer dirs.Remove()
+
+	side := git(t, dirs.RepoDir, "rev-parse", "side")[0]
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	got, err := ripsrc.New(opts).Licenses(context.Background(), ripsrc.LicensesOpts{Commits: []string{side, "HEAD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("wanted 2 inventories, got %v", len(got))
+	}
+	assert.Equal(t, side, got[0].Commit)
+	assert.Equal(t, map[string]int{"Apache-2.0": 1}, got[0].Counts)
+	assert.Equal(t, map[string]int{"Apache-2.0": 1, "MIT": 1}, got[1].Counts)
+}
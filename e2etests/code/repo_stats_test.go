@@ -0,0 +1,185 @@
+package e2etests
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepoStatsByCommit(t *testing.T) {
+	var got []*ripsrc.RepoStats
+
+	opts := &ripsrc.Opts{}
+	opts.RepoStatsByCommit = true
+
+	NewTest(t, "basic").Run(opts, func(rip *ripsrc.Ripsrc) {
+		ch := make(chan ripsrc.CommitCode)
+		done := make(chan bool)
+		go func() {
+			for c := range ch {
+				for range c.Blames {
+				}
+				got = append(got, c.Stats)
+			}
+			done <- true
+		}()
+		defer func() { <-done }()
+		err := rip.CodeByCommit(context.Background(), ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if len(got) != 2 {
+		t.Fatalf("wanted 2 commits, got %v", len(got))
+	}
+	if got[0] == nil || got[1] == nil {
+		t.Fatal("repo stats not set")
+	}
+
+	assert.Equal(t, "b4dadc54e312e976694161c2ac59ab76feb0c40d", got[0].Commit)
+	assert.Equal(t, 1, got[0].Files)
+	assert.Equal(t, 1, got[0].SourceFiles)
+	assert.Equal(t, int64(8), got[0].Loc)
+	assert.Equal(t, 1, got[0].Authors)
+	assert.Len(t, got[0].Languages, 1)
+	assert.Equal(t, "Go", got[0].Languages[0].Language)
+
+	assert.Equal(t, "69ba50fff990c169f80de96674919033a0a9b66d", got[1].Commit)
+	assert.Equal(t, 1, got[1].Files)
+	assert.Equal(t, int64(6), got[1].Loc)
+	assert.Equal(t, 2, got[1].Authors)
+	assert.Equal(t, got[1].Sloc, got[1].Languages[0].Sloc)
+}
+
+// unzipBranchedRepo returns merge_basic repo with a branch from the first commit adding b.go merged at the end. Branch commit is processed after the first merge, so state of previously processed commit differs from its parent.
+func unzipBranchedRepo(t *testing.T) testutil.TestRepoDirs {
+	dirs := testutil.UnzipTestRepo("merge_basic")
+	git(t, dirs.RepoDir, "checkout", "-q", "-b", "side", "cb78f81991af4120b649c5e2ae18cceba598220a")
+	err := ioutil.WriteFile(filepath.Join(dirs.RepoDir, "b.go"), []byte("package main\n\nfunc b(){\n}\n\n"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	git(t, dirs.RepoDir, "add", "b.go")
+	git(t, dirs.RepoDir, "-c", "user.name=B", "-c", "user.email=b@example.com", "commit", "-q", "-m", "b")
+	git(t, dirs.RepoDir, "checkout", "-q", "master")
+	git(t, dirs.RepoDir, "-c", "user.name=B", "-c", "user.email=b@example.com", "merge", "-q", "--no-edit", "side")
+	return dirs
+}
+
+func git(t *testing.T, repoDir string, args ...string) []string {
+	t.Helper()
+	r, err := gitexec.Exec(context.Background(), "git", repoDir, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Split(strings.TrimSuffix(string(b), "\n"), "\n")
+}
+
+// gitLoc returns the number of files and lines at commit
+func gitLoc(t *testing.T, repoDir string, commit string) (files int, loc int64) {
+	for _, fp := range git(t, repoDir, "ls-tree", "-r", "--name-only", commit) {
+		files++
+		loc += int64(len(git(t, repoDir, "show", commit+":"+fp)))
+	}
+	return
+}
+
+func runByCommit(t *testing.T, opts ripsrc.Opts) (res []ripsrc.CommitCode) {
+	rip := ripsrc.New(opts)
+	ch := make(chan ripsrc.CommitCode)
+	done := make(chan bool)
+	go func() {
+		for c := range ch {
+			for range c.Blames {
+			}
+			res = append(res, c)
+		}
+		done <- true
+	}()
+	err := rip.CodeByCommit(context.Background(), ch)
+	<-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestRepoStatsByCommitBranches(t *testing.T) {
+	dirs := unzipBranchedRepo(t)
+	defer dirs.Remove()
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	opts.RepoStatsByCommit = true
+	commits := runByCommit(t, opts)
+	if len(commits) != 6 {
+		t.Fatalf("wanted 6 commits, got %v", len(commits))
+	}
+	for _, c := range commits {
+		files, loc := gitLoc(t, dirs.RepoDir, c.SHA)
+		assert.Equal(t, files, c.Stats.Files, "files in commit %v", c.SHA)
+		assert.Equal(t, loc, c.Stats.Loc, "loc in commit %v", c.SHA)
+	}
+
+	// state of the checkpoint commit is built from all files when resuming
+	opts.Resume = true
+	resumed := runByCommit(t, opts)
+	if len(resumed) != 1 {
+		t.Fatalf("wanted 1 commit, got %v", len(resumed))
+	}
+	want := commits[len(commits)-1].Stats
+	got := resumed[0].Stats
+	assert.Equal(t, want.Files, got.Files)
+	assert.Equal(t, want.Loc, got.Loc)
+	assert.Equal(t, want.Sloc, got.Sloc)
+	assert.Equal(t, want.Authors, got.Authors)
+}
+
+// TestRepoStatsByCommitSharedState checks that state copied for one child is not changed by another child of the same commit. Master commit is the first child of fork commit and gets a copy of its state, side branch commit is the last child and changes x.go in the original state. Next master commit continues from the copy and changes x.go again, removing the stats of x.go from the copy.
+func TestRepoStatsByCommitSharedState(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("merge_basic")
+	defer dirs.Remove()
+	// processing order is by commit date
+	date := parseGitDate(git(t, dirs.RepoDir, "log", "-1", "--format=%cd")[0])
+	commit := func(name string, content string) {
+		date = date.Add(time.Hour)
+		commitFileAt(t, dirs.RepoDir, name, content, date)
+	}
+	commit("x.go", "package main\n")
+	git(t, dirs.RepoDir, "branch", "side")
+	commit("c.go", "package main\n")
+	git(t, dirs.RepoDir, "checkout", "-q", "side")
+	commit("x.go", "package main\n\nfunc x(){\n}\n")
+	git(t, dirs.RepoDir, "checkout", "-q", "master")
+	commit("x.go", "package main\n\n// x\n")
+	git(t, dirs.RepoDir, "-c", "user.name=B", "-c", "user.email=b@example.com", "merge", "-q", "-s", "ours", "--no-edit", "side")
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	opts.RepoStatsByCommit = true
+	commits := runByCommit(t, opts)
+	if len(commits) != 9 {
+		t.Fatalf("wanted 9 commits, got %v", len(commits))
+	}
+	for _, c := range commits {
+		files, loc := gitLoc(t, dirs.RepoDir, c.SHA)
+		assert.Equal(t, files, c.Stats.Files, "files in commit %v", c.SHA)
+		assert.Equal(t, loc, c.Stats.Loc, "loc in commit %v", c.SHA)
+	}
+}
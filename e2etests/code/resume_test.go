@@ -0,0 +1,162 @@
+package e2etests
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/stretchr/testify/assert"
+)
+
+func runCommits(t *testing.T, opts *ripsrc.Opts) (commits []ripsrc.CommitCode, last ripsrc.BlameResult) {
+	NewTest(t, "bots").Run(opts, func(rip *ripsrc.Ripsrc) {
+		ch := make(chan ripsrc.CommitCode)
+		done := make(chan bool)
+		go func() {
+			for c := range ch {
+				for b := range c.Blames {
+					last = b
+				}
+				commits = append(commits, c)
+			}
+			done <- true
+		}()
+		defer func() { <-done }()
+		err := rip.CodeByCommit(context.Background(), ch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	return
+}
+
+func TestResumeFromCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ripsrc-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// no checkpoint yet, processes all commits
+	commits, want := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir:  dir,
+		CheckpointEvery: ripsrc.CheckpointEvery{Commits: 1},
+		Resume:          true,
+	})
+	assert.Len(t, commits, 3)
+
+	commits, got := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir: dir,
+		Resume:         true,
+	})
+	// checkpoint commit is processed again
+	if len(commits) != 1 {
+		t.Fatalf("wanted 1 commit, got %v", len(commits))
+	}
+	assert.Equal(t, botsC3, commits[0].SHA)
+	assert.Equal(t, lineSHAs(want.Lines), lineSHAs(got.Lines))
+	// lines from commits processed before use commit meta cache
+	assert.Equal(t, lineAuthors(want.Lines), lineAuthors(got.Lines))
+}
+
+func TestResumeRebuildInvalidCheckpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ripsrc-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, want := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir: dir,
+	})
+
+	// truncate blame data
+	loc := filepath.Join(dir, "pp-git-cache", "checkpoint", "blames")
+	b, err := ioutil.ReadFile(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(loc, b[:len(b)/2], 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commits, got := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir:           dir,
+		Resume:                   true,
+		RebuildInvalidCheckpoint: true,
+	})
+	assert.Len(t, commits, 3)
+	assert.Equal(t, lineSHAs(want.Lines), lineSHAs(got.Lines))
+}
+
+func TestResumeFromCheckpointStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ripsrc-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := ripsrc.NewLocalCheckpointStore(filepath.Join(dir, "store", "bots.tar.zst"))
+
+	commits, want := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir:  filepath.Join(dir, "worker1"),
+		CheckpointStore: store,
+		Resume:          true,
+	})
+	assert.Len(t, commits, 3)
+
+	// another worker without local checkpoint
+	commits, got := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir:  filepath.Join(dir, "worker2"),
+		CheckpointStore: store,
+		Resume:          true,
+	})
+	if len(commits) != 1 {
+		t.Fatalf("wanted 1 commit, got %v", len(commits))
+	}
+	assert.Equal(t, botsC3, commits[0].SHA)
+	assert.Equal(t, lineSHAs(want.Lines), lineSHAs(got.Lines))
+	// lines from commits processed before use commit meta cache
+	assert.Equal(t, lineAuthors(want.Lines), lineAuthors(got.Lines))
+}
+
+func lineAuthors(lines []*ripsrc.BlameLine) (res []string) {
+	for _, l := range lines {
+		res = append(res, l.Name+" <"+l.Email+"> "+l.Date.UTC().String())
+	}
+	return
+}
+
+func TestResumeCommitGraphCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ripsrc-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	_, want := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir:  dir,
+		CheckpointEvery: ripsrc.CheckpointEvery{Commits: 1},
+	})
+	for _, name := range []string{"graph", "commit-meta"} {
+		_, err := os.Stat(filepath.Join(dir, "pp-git-cache", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// invalid cache is read again from git
+	err = ioutil.WriteFile(filepath.Join(dir, "pp-git-cache", "commit-meta"), []byte("invalid"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits, got := runCommits(t, &ripsrc.Opts{
+		CheckpointsDir: dir,
+		Resume:         true,
+	})
+	assert.Len(t, commits, 1)
+	assert.Equal(t, lineAuthors(want.Lines), lineAuthors(got.Lines))
+}
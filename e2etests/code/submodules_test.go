@@ -0,0 +1,111 @@
+package e2etests
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmodules(t *testing.T) {
+	libC1 := "c2686f241779605060e96d8e9318ec5c598c611c"
+	libC2 := "14e1cd9b6d3c513a819f0d8ec792ebb94ee43dcb"
+
+	var subs [][]ripsrc.SubmoduleChange
+	var files [][]string
+	var head []ripsrc.Submodule
+
+	NewTest(t, "submodules").Run(nil, func(rip *ripsrc.Ripsrc) {
+		ch := make(chan ripsrc.CommitCode)
+		done := make(chan bool)
+		go func() {
+			for c := range ch {
+				var fs []string
+				for b := range c.Blames {
+					fs = append(fs, b.Filename)
+				}
+				sort.Strings(fs)
+				files = append(files, fs)
+				subs = append(subs, c.Submodules)
+			}
+			done <- true
+		}()
+		err := rip.CodeByCommit(context.Background(), ch)
+		<-done
+		if err != nil {
+			t.Fatal(err)
+		}
+		head, err = rip.Submodules(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	assert.Equal(t, [][]string{
+		{"main.go"},
+		{".gitmodules"},
+		nil,
+		{".gitmodules"},
+		{".gitmodules"},
+	}, files)
+
+	assert.Equal(t, [][]ripsrc.SubmoduleChange{
+		nil,
+		{{Path: "lib", Status: ripsrc.GitFileCommitStatusAdded, To: libC1}},
+		{{Path: "lib", Status: ripsrc.GitFileCommitStatusModified, From: libC1, To: libC2}},
+		{{Path: "other", Status: ripsrc.GitFileCommitStatusAdded, To: libC2}},
+		{{Path: "other", Status: ripsrc.GitFileCommitStatusRemoved, From: libC2}},
+	}, subs)
+
+	if len(head) != 1 {
+		t.Fatalf("wanted 1 submodule at head, got %v", head)
+	}
+	// lib is checked out in test repo
+	assert.Equal(t, "lib", filepath.Base(head[0].Dir))
+	head[0].Dir = ""
+	assert.Equal(t, ripsrc.Submodule{Path: "lib", Commit: libC2}, head[0])
+}
+
+// TestSubmoduleContentInRegularFile checks that regular file with the same content as git shows for submodule is processed as file.
+func TestSubmoduleContentInRegularFile(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("basic")
+	defer dirs.Remove()
+	date := parseGitDate(git(t, dirs.RepoDir, "log", "-1", "--format=%cd")[0])
+	commitFileAt(t, dirs.RepoDir, "sub.txt", "Subproject commit c2686f241779605060e96d8e9318ec5c598c611c\n", date.Add(time.Hour))
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	opts.RepoStatsByCommit = true
+	commits := runByCommit(t, opts)
+	if len(commits) != 3 {
+		t.Fatalf("wanted 3 commits, got %v", len(commits))
+	}
+	last := commits[2]
+	assert.Empty(t, last.Submodules)
+	assert.Equal(t, 2, last.Stats.Files)
+
+	summary, err := ripsrc.New(opts).Summary(context.Background(), ripsrc.SummaryOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, 2, summary[0].Files)
+}
+
+func TestSubmodulesSummary(t *testing.T) {
+	var summary []ripsrc.Summary
+	NewTest(t, "submodules").Run(nil, func(rip *ripsrc.Ripsrc) {
+		var err error
+		summary, err = rip.Summary(context.Background(), ripsrc.SummaryOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	// lib is not counted as file
+	assert.Equal(t, 2, summary[0].Files)
+}
@@ -0,0 +1,105 @@
+package e2etests
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummary(t *testing.T) {
+	c1 := "b4dadc54e312e976694161c2ac59ab76feb0c40d"
+	c2 := "69ba50fff990c169f80de96674919033a0a9b66d"
+
+	var head, both []ripsrc.Summary
+	NewTest(t, "basic").Run(nil, func(rip *ripsrc.Ripsrc) {
+		var err error
+		head, err = rip.Summary(context.Background(), ripsrc.SummaryOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	NewTest(t, "basic").Run(nil, func(rip *ripsrc.Ripsrc) {
+		var err error
+		both, err = rip.Summary(context.Background(), ripsrc.SummaryOpts{Commits: []string{c1, c2[0:8]}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	if len(head) != 1 {
+		t.Fatalf("wanted 1 summary, got %v", len(head))
+	}
+	got := head[0]
+	assert.Equal(t, c2, got.Commit)
+	assert.Equal(t, 1, got.Files)
+	assert.Equal(t, 1, got.SourceFiles)
+	assert.Equal(t, int64(6), got.Loc)
+	assert.Len(t, got.Languages, 1)
+	assert.Equal(t, "Go", got.Languages[0].Language)
+	assert.Equal(t, 1, got.Languages[0].Files)
+	assert.Len(t, got.Authors, 2)
+	assert.Equal(t, "user1@example.com", got.Authors[0].Email)
+	assert.Equal(t, int64(5), got.Authors[0].Lines)
+	assert.Equal(t, "user2@example.com", got.Authors[1].Email)
+	assert.Equal(t, int64(1), got.Authors[1].Lines)
+	assert.Empty(t, got.Licenses)
+	assert.Empty(t, got.Skipped)
+
+	if len(both) != 2 {
+		t.Fatalf("wanted 2 summaries, got %v", len(both))
+	}
+	assert.Equal(t, c1, both[0].Commit)
+	assert.Equal(t, int64(8), both[0].Loc)
+	assert.Len(t, both[0].Authors, 1)
+	assert.Equal(t, c2, both[1].Commit)
+}
+
+func TestSummaryInvalidCommit(t *testing.T) {
+	NewTest(t, "basic").Run(nil, func(rip *ripsrc.Ripsrc) {
+		_, err := rip.Summary(context.Background(), ripsrc.SummaryOpts{Commits: []string{"invalid"}})
+		if err == nil {
+			t.Fatal("expected error for invalid commit")
+		}
+	})
+}
+
+func TestSummaryBranches(t *testing.T) {
+	dirs := unzipBranchedRepo(t)
+	defer dirs.Remove()
+
+	side := git(t, dirs.RepoDir, "rev-parse", "side")[0]
+	head := git(t, dirs.RepoDir, "rev-parse", "HEAD")[0]
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	got, err := ripsrc.New(opts).Summary(context.Background(), ripsrc.SummaryOpts{Commits: []string{side, head}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("wanted 2 summaries, got %v", len(got))
+	}
+	for _, s := range got {
+		files, loc := gitLoc(t, dirs.RepoDir, s.Commit)
+		assert.Equal(t, files, s.Files, "files in commit %v", s.Commit)
+		assert.Equal(t, loc, s.Loc, "loc in commit %v", s.Commit)
+	}
+
+	// files not changed in the checkpoint commit are included when resuming
+	opts.Resume = true
+	resumed, err := ripsrc.New(opts).Summary(context.Background(), ripsrc.SummaryOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resumed) != 1 {
+		t.Fatalf("wanted 1 summary, got %v", len(resumed))
+	}
+	assert.Equal(t, head, resumed[0].Commit)
+	assert.Equal(t, got[1].Files, resumed[0].Files)
+	assert.Equal(t, got[1].Loc, resumed[0].Loc)
+	assert.Equal(t, got[1].Authors, resumed[0].Authors)
+}
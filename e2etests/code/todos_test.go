@@ -0,0 +1,98 @@
+package e2etests
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTODOs(t *testing.T) {
+	c1 := "24bb467a096c226e74747bfc38581cfa1ffbfc00"
+	c2 := "6ac49a128b19ac9d7742522bced7e2b81df4c67b"
+
+	var got []ripsrc.TODOReport
+	NewTest(t, "todos").Run(nil, func(rip *ripsrc.Ripsrc) {
+		var err error
+		got, err = rip.TODOs(context.Background(), ripsrc.TODOsOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(got) != 1 {
+		t.Fatalf("wanted 1 report, got %v", len(got))
+	}
+	rep := got[0]
+	assert.Equal(t, c2, rep.Commit)
+	if len(rep.TODOs) != 2 {
+		t.Fatalf("wanted 2 todos, got %+v", rep.TODOs)
+	}
+
+	todo := rep.TODOs[0]
+	assert.Equal(t, "main.go", todo.Filename)
+	assert.Equal(t, 3, todo.Line)
+	assert.Equal(t, "TODO", todo.Tag)
+	assert.Equal(t, "handle errors", todo.Text)
+	assert.Equal(t, "user1@example.com", todo.Email)
+	assert.Equal(t, c1, todo.SHA)
+	assert.Equal(t, 10*24*time.Hour, todo.Age)
+
+	todo = rep.TODOs[1]
+	assert.Equal(t, 8, todo.Line)
+	assert.Equal(t, "FIXME", todo.Tag)
+	assert.Equal(t, "slow", todo.Text)
+	assert.Equal(t, "user2@example.com", todo.Email)
+	assert.Equal(t, time.Duration(0), todo.Age)
+
+	assert.Equal(t, []ripsrc.TODOAuthorSummary{
+		{Name: "User1", Email: "user1@example.com", TODOs: 1, Oldest: 10 * 24 * time.Hour, Median: 10 * 24 * time.Hour},
+		{Name: "User2", Email: "user2@example.com", TODOs: 1},
+	}, rep.Authors)
+}
+
+func TestTODOsCustomTags(t *testing.T) {
+	opts := &ripsrc.Opts{}
+	opts.TODOTags = []string{"FIXME"}
+	var got []ripsrc.TODOReport
+	NewTest(t, "todos").Run(opts, func(rip *ripsrc.Ripsrc) {
+		var err error
+		got, err = rip.TODOs(context.Background(), ripsrc.TODOsOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+	if len(got) != 1 || len(got[0].TODOs) != 1 {
+		t.Fatalf("wanted 1 todo, got %+v", got)
+	}
+	assert.Equal(t, "FIXME", got[0].TODOs[0].Tag)
+}
+
+func TestTODOsBranches(t *testing.T) {
+	dirs := unzipParallelBranchesRepo(t, "x.go", "package main\n\n// TODO: master\n", "y.go", "package main\n\n// TODO: side\n")
+	defer dirs.Remove()
+
+	side := git(t, dirs.RepoDir, "rev-parse", "side")[0]
+
+	opts := ripsrc.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	got, err := ripsrc.New(opts).TODOs(context.Background(), ripsrc.TODOsOpts{Commits: []string{side, "HEAD"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("wanted 2 reports, got %v", len(got))
+	}
+	texts := func(rep ripsrc.TODOReport) (res []string) {
+		for _, todo := range rep.TODOs {
+			res = append(res, todo.Text)
+		}
+		sort.Strings(res)
+		return
+	}
+	assert.Equal(t, side, got[0].Commit)
+	assert.Equal(t, []string{"side"}, texts(got[0]))
+	assert.Equal(t, []string{"master", "side"}, texts(got[1]))
+}
@@ -0,0 +1,120 @@
+package ripsrc
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// DefaultBotAuthors are the patterns used to find bot and service account commits when Opts.BotAuthors is not set. Only matches names and emails used by bots, so that people are not flagged by mistake.
+var DefaultBotAuthors = []string{
+	`\[bot\]$`,
+	`\[bot\]@users\.noreply\.github\.com$`,
+	`^action@github\.com$`,
+	`^support@dependabot\.com$`,
+	`^bot@renovateapp\.com$`,
+	`^support@greenkeeper\.io$`,
+	`^snyk-bot@snyk\.io$`,
+	`^github-bot@pyup\.io$`,
+	`^semantic-release-bot@martynus\.net$`,
+	`^noreply@`,
+	`^no-reply@`,
+}
+
+// ServiceBotAuthors are broader patterns for common CI and release service accounts. These could also match people, for example a user named Travis, so they are not used by default. To use them set Opts.BotAuthors to DefaultBotAuthors and ServiceBotAuthors.
+var ServiceBotAuthors = []string{
+	`^dependabot`,
+	`^renovate`,
+	`^greenkeeper`,
+	`^release-bot`,
+	`^ci-bot`,
+	`^jenkins`,
+	`^travis`,
+	`^circleci`,
+	`^gitlab-ci`,
+}
+
+// BotLinesMode defines how lines from bot commits are handled.
+type BotLinesMode string
+
+const (
+	// BotLinesKeep attributes lines to bots as any other author. Lines are still flagged with BlameLine.Bot.
+	BotLinesKeep BotLinesMode = ""
+	// BotLinesExclude skips lines from bots when calculating authors and ownership in CodeOwners, Summary and RepoStats.
+	BotLinesExclude BotLinesMode = "exclude"
+	// BotLinesPreviousAuthor keeps the previous author for lines changed by bots, same as adding bot commits to Opts.IgnoreRevs. New lines added by bots are still attributed to bots.
+	BotLinesPreviousAuthor BotLinesMode = "previous-author"
+)
+
+// ParseBotLinesMode returns mode by name. Empty string and keep return BotLinesKeep.
+func ParseBotLinesMode(s string) (BotLinesMode, error) {
+	switch BotLinesMode(s) {
+	case BotLinesKeep, "keep":
+		return BotLinesKeep, nil
+	case BotLinesExclude:
+		return BotLinesExclude, nil
+	case BotLinesPreviousAuthor:
+		return BotLinesPreviousAuthor, nil
+	}
+	return "", fmt.Errorf("invalid bot lines mode %v, expected keep, exclude or previous-author", s)
+}
+
+// botRegexp returns case-insensitive regexp matching any of patterns.
+func botRegexp(patterns []string) (*regexp.Regexp, error) {
+	if len(patterns) == 0 {
+		patterns = DefaultBotAuthors
+	}
+	var groups []string
+	for _, p := range patterns {
+		_, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bot author pattern %v: %v", p, err)
+		}
+		groups = append(groups, "(?:"+p+")")
+	}
+	return regexp.Compile(`(?i)` + strings.Join(groups, "|"))
+}
+
+// prepareBots compiles bot patterns. Called after commit meta is loaded.
+func (s *Ripsrc) prepareBots() error {
+	re, err := botRegexp(s.opts.BotAuthors)
+	if err != nil {
+		return err
+	}
+	s.botRegexp = re
+	s.botCommits = map[string]bool{}
+	return nil
+}
+
+// isBot returns true if commit author name or email matches bot patterns.
+func (s *Ripsrc) isBot(commit Commit) bool {
+	if s.botRegexp == nil {
+		return false
+	}
+	return s.botRegexp.MatchString(commit.AuthorName) || s.botRegexp.MatchString(commit.AuthorEmail)
+}
+
+// isBotSHA returns true if commit is from bot. Results are cached since it is called for every blame line.
+func (s *Ripsrc) isBotSHA(sha string) bool {
+	if v, ok := s.botCommits[sha]; ok {
+		return v
+	}
+	v := s.isBot(s.lineCommit(sha))
+	s.botCommits[sha] = v
+	return v
+}
+
+// botIgnoreRevs returns all bot commits in commit meta, used for BotLinesPreviousAuthor.
+func (s *Ripsrc) botIgnoreRevs() (res []string) {
+	for sha, c := range s.commitMeta {
+		if s.isBot(c) {
+			res = append(res, sha)
+		}
+	}
+	return
+}
+
+// ownsLine returns false for lines that should not be counted for author.
+func (s *Ripsrc) ownsLine(l *BlameLine) bool {
+	return !(l.Bot && s.opts.BotLines == BotLinesExclude)
+}
@@ -0,0 +1,112 @@
+package ripsrc
+
+import (
+	"path/filepath"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+)
+
+// CheckpointEvery defines how often intermediate checkpoints are written in CodeByCommit.
+type CheckpointEvery = process.CheckpointEvery
+
+// ParseCheckpointEvery parses number of commits (for example 1000) or duration (for example 10m). Empty string disables intermediate checkpoints.
+func ParseCheckpointEvery(s string) (CheckpointEvery, error) {
+	return process.ParseCheckpointEvery(s)
+}
+
+// CheckpointStore keeps checkpoint bundles outside of CheckpointsDir. Get and Put work with a single stream, bundle is a tar archive compressed with zstd.
+type CheckpointStore = process.CheckpointStore
+
+// NewLocalCheckpointStore creates store keeping checkpoint bundle in file at loc.
+func NewLocalCheckpointStore(loc string) CheckpointStore {
+	return process.NewLocalCheckpointStore(loc)
+}
+
+// resolveResume fetches checkpoint from CheckpointStore, sets CommitFromIncl to the commit of the latest checkpoint when Opts.Resume is set, and clears it when checkpoint is invalid and Opts.RebuildInvalidCheckpoint is set. Commit meta and blame processing both start from CommitFromIncl, so they stay in sync. Called after commit graph is built.
+func (s *Ripsrc) resolveResume() error {
+	err := s.fetchCheckpoint()
+	if err != nil {
+		return err
+	}
+	err = s.setResumeCommit()
+	if err != nil {
+		return err
+	}
+	return s.checkCheckpoint()
+}
+
+func (s *Ripsrc) checkpointProcess() *process.Process {
+	return process.New(process.Opts{
+		Logger:          s.opts.Logger,
+		RepoDir:         s.opts.RepoDir,
+		CheckpointsDir:  s.opts.CheckpointsDir,
+		CheckpointStore: s.opts.CheckpointStore,
+	})
+}
+
+// cacheLoc returns location of data kept between runs next to checkpoints.
+func (s *Ripsrc) cacheLoc(name string) string {
+	return filepath.Join(s.checkpointProcess().CheckpointsDir(), name)
+}
+
+// fetchCheckpoint restores checkpoint from CheckpointStore when processing continues from checkpoint.
+func (s *Ripsrc) fetchCheckpoint() error {
+	if s.opts.CheckpointStore == nil || s.opts.CommitFromMakeNonIncl {
+		return nil
+	}
+	if !s.opts.Resume && s.opts.CommitFromIncl == "" {
+		return nil
+	}
+	_, err := s.checkpointProcess().FetchCheckpoint()
+	if err != nil {
+		if s.opts.RebuildInvalidCheckpoint && process.IsCheckpointInvalid(err) {
+			s.opts.Logger.Info("checkpoint in store could not be used", "err", err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Ripsrc) setResumeCommit() error {
+	if !s.opts.Resume || s.opts.CommitFromIncl != "" {
+		return nil
+	}
+	commit, err := s.checkpointProcess().LastCheckpointCommit()
+	if err != nil {
+		if s.opts.RebuildInvalidCheckpoint && process.IsCheckpointInvalid(err) {
+			s.opts.Logger.Info("checkpoint could not be used, processing from the beginning", "err", err)
+			return nil
+		}
+		return err
+	}
+	if commit == "" {
+		s.opts.Logger.Info("no checkpoint to resume from, processing from the beginning")
+		return nil
+	}
+	if _, ok := s.commitGraph.Parents[commit]; !ok {
+		// history was rewritten or branch removed
+		s.opts.Logger.Info("checkpoint commit not found in repo, processing from the beginning", "commit", commit)
+		return nil
+	}
+	s.opts.Logger.Info("resuming from checkpoint", "commit", commit)
+	s.opts.CommitFromIncl = commit
+	return nil
+}
+
+// checkCheckpoint verifies checkpoint for CommitFromIncl when RebuildInvalidCheckpoint is set and clears CommitFromIncl if it could not be used, so that all commits are processed again.
+func (s *Ripsrc) checkCheckpoint() error {
+	if !s.opts.RebuildInvalidCheckpoint || s.opts.CommitFromIncl == "" || s.opts.CommitFromMakeNonIncl {
+		return nil
+	}
+	_, err := s.checkpointProcess().VerifyCheckpoint()
+	if err != nil {
+		if !process.IsCheckpointInvalid(err) {
+			return err
+		}
+		s.opts.Logger.Info("checkpoint could not be used, processing from the beginning", "err", err)
+		s.opts.CommitFromIncl = ""
+		return nil
+	}
+	return nil
+}
@@ -0,0 +1,44 @@
+package cmdcheckpoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+)
+
+type BlameOpts struct {
+	// Dir is the checkpoints dir or bundle file.
+	Dir string
+
+	// Commit is the full commit hash or unique prefix.
+	Commit string
+
+	// File is the path of file in repo.
+	File string
+}
+
+// Blame outputs blame stored in checkpoint for file at commit, one line per file line with the commit it is attributed to.
+func Blame(ctx context.Context, out io.Writer, opts BlameOpts) {
+	cp, err := openCheckpoint(opts.Dir)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	commit, err := cp.findCommit(opts.Commit)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	bl := cp.Repo.GetFileOptional(commit, opts.File)
+	if bl == nil {
+		cmdutils.ExitWithErr(fmt.Errorf("file %v not found in commit %v", opts.File, commit))
+		return
+	}
+	fmt.Fprintln(out, "commit:", commit)
+	fmt.Fprintln(out, "file:", opts.File)
+	fmt.Fprintln(out, "last changed in:", bl.Commit)
+	fmt.Fprintln(out, "lines:", len(bl.Lines))
+	writeBlame(out, bl)
+}
@@ -0,0 +1,110 @@
+package cmdcheckpoint
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+// checkpoint is a loaded checkpoint with its header
+type checkpoint struct {
+	Loc    string
+	Header repo.CheckpointHeader
+	Repo   *repo.MemRepo
+}
+
+// openCheckpoint reads checkpoint from loc. Loc could be the checkpoints dir (ripsrc CheckpointsDir or repo dir with pp-git-cache inside), pp-git-cache dir itself or a bundle file from checkpoint store.
+func openCheckpoint(loc string) (res checkpoint, _ error) {
+	res.Loc = loc
+	info, err := os.Stat(loc)
+	if err != nil {
+		return res, err
+	}
+	dir := loc
+	if !info.IsDir() {
+		// bundle from checkpoint store
+		tmp, err := ioutil.TempDir("", "ripsrc-checkpoint-")
+		if err != nil {
+			return res, err
+		}
+		defer os.RemoveAll(tmp)
+		f, err := os.Open(loc)
+		if err != nil {
+			return res, err
+		}
+		defer f.Close()
+		_, err = repo.ReadBundle(f, tmp)
+		if err != nil {
+			return res, err
+		}
+		dir = tmp
+	} else if _, err := os.Stat(filepath.Join(loc, "pp-git-cache")); err == nil {
+		dir = filepath.Join(loc, "pp-git-cache")
+	}
+
+	reader := repo.NewCheckpointReader(logger.NewDefaultLogger(os.Stderr))
+	res.Header, err = reader.Verify(dir)
+	if err != nil {
+		return res, err
+	}
+	res.Repo, err = reader.Read(dir, "")
+	if err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// commits returns commits in checkpoint sorted by hash
+func (s checkpoint) commits() []string {
+	res := s.Repo.Commits()
+	sort.Strings(res)
+	return res
+}
+
+// findCommit returns commit in checkpoint matching full hash or unique prefix.
+func (s checkpoint) findCommit(commit string) (string, error) {
+	var res []string
+	for _, c := range s.Repo.Commits() {
+		if c == commit {
+			return c, nil
+		}
+		if strings.HasPrefix(c, commit) {
+			res = append(res, c)
+		}
+	}
+	if len(res) == 0 {
+		return "", fmt.Errorf("commit %v not found in checkpoint %v", commit, s.Loc)
+	}
+	if len(res) > 1 {
+		return "", fmt.Errorf("commit prefix %v is ambiguous in checkpoint %v", commit, s.Loc)
+	}
+	return res[0], nil
+}
+
+// files returns files in commit sorted by path
+func (s checkpoint) files(commit string) (paths []string, blames map[string]*incblame.Blame) {
+	blames = s.Repo.GetCommitMust(commit).Map()
+	for p := range blames {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return
+}
+
+func writeBlame(wr io.Writer, bl *incblame.Blame) {
+	if bl.IsBinary {
+		fmt.Fprintln(wr, "binary, last changed in", bl.Commit)
+		return
+	}
+	for i, l := range bl.Lines {
+		fmt.Fprintf(wr, "%5d %v %s\n", i+1, l.Commit, l.Line)
+	}
+}
@@ -0,0 +1,161 @@
+package cmdcheckpoint
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	c1 = "aa11000000000000000000000000000000000000"
+	c2 = "aa22000000000000000000000000000000000000"
+	c3 = "bb33000000000000000000000000000000000000"
+)
+
+func blame(commit string, lines ...string) *incblame.Blame {
+	res := &incblame.Blame{Commit: commit}
+	for _, l := range lines {
+		res.Lines = append(res.Lines, &incblame.Line{Line: []byte(l), Commit: commit})
+	}
+	return res
+}
+
+// writeCheckpoint writes checkpoint with commits to pp-git-cache inside a new temp dir and returns the temp dir
+func writeCheckpoint(t *testing.T, commits map[string]map[string]*incblame.Blame, lastCommit string) string {
+	dir, err := ioutil.TempDir("", "ripsrc-checkpoint-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := repo.New()
+	for c, files := range commits {
+		r.AddCommit(c)
+		for fp, bl := range files {
+			r.SetFile(c, fp, bl)
+		}
+	}
+	err = repo.NewCheckpointWriter(logger.NewDefaultLogger(ioutil.Discard)).Write(r, filepath.Join(dir, "pp-git-cache"), lastCommit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestOpenCheckpoint(t *testing.T) {
+	dir := writeCheckpoint(t, map[string]map[string]*incblame.Blame{
+		c1: {"a.go": blame(c1, "a")},
+		c2: {"a.go": blame(c1, "a"), "b.go": blame(c2, "b")},
+	}, c2)
+	defer os.RemoveAll(dir)
+
+	bundle := filepath.Join(dir, "bundle")
+	f, err := os.Create(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.WriteBundle(f, filepath.Join(dir, "pp-git-cache"))
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locs := map[string]string{
+		"checkpoints dir": dir,
+		"cache dir":       filepath.Join(dir, "pp-git-cache"),
+		"bundle":          bundle,
+	}
+	for label, loc := range locs {
+		t.Run(label, func(t *testing.T) {
+			cp, err := openCheckpoint(loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, c2, cp.Header.LastCommit)
+			assert.Equal(t, []string{c1, c2}, cp.commits())
+			paths, blames := cp.files(c2)
+			assert.Equal(t, []string{"a.go", "b.go"}, paths)
+			assert.True(t, blames["b.go"].Eq(blame(c2, "b")))
+		})
+	}
+
+	_, err = openCheckpoint(filepath.Join(dir, "missing"))
+	if err == nil {
+		t.Fatal("expected error for missing checkpoint")
+	}
+}
+
+func TestFindCommit(t *testing.T) {
+	dir := writeCheckpoint(t, map[string]map[string]*incblame.Blame{
+		c1: {"a.go": blame(c1, "a")},
+		c2: {"a.go": blame(c1, "a")},
+		c3: {"a.go": blame(c1, "a")},
+	}, c3)
+	defer os.RemoveAll(dir)
+
+	cp, err := openCheckpoint(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := cp.findCommit(c2)
+	assert.NoError(t, err)
+	assert.Equal(t, c2, got)
+
+	got, err = cp.findCommit("bb")
+	assert.NoError(t, err)
+	assert.Equal(t, c3, got)
+
+	_, err = cp.findCommit("aa")
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") {
+		t.Fatalf("expected ambiguous prefix error, got %v", err)
+	}
+
+	_, err = cp.findCommit("cc")
+	if err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	dir1 := writeCheckpoint(t, map[string]map[string]*incblame.Blame{
+		c1: {"a.go": blame(c1, "a")},
+		c2: {"a.go": blame(c1, "a"), "b.go": blame(c2, "b"), "c.go": blame(c2, "c")},
+	}, c2)
+	defer os.RemoveAll(dir1)
+
+	changed := blame(c1, "a")
+	changed.Lines = append(changed.Lines, &incblame.Line{Line: []byte("a2"), Commit: c2})
+	dir2 := writeCheckpoint(t, map[string]map[string]*incblame.Blame{
+		c2: {"a.go": changed, "b.go": blame(c2, "b"), "d.go": blame(c2, "d")},
+		c3: {"a.go": blame(c1, "a")},
+	}, c3)
+	defer os.RemoveAll(dir2)
+
+	out := &bytes.Buffer{}
+	Diff(context.Background(), out, DiffOpts{Dir1: dir1, Dir2: dir2, Lines: true})
+
+	want := `last commit: ` + c2 + ` != ` + c3 + `
+commit only in first: ` + c1 + `
+commit: ` + c2 + ` file blame differs: a.go
+  lines: 1 != 2
+      2 -` + "  " + `
+      2 + ` + c2 + ` a2
+commit: ` + c2 + ` file only in first: c.go
+commit: ` + c2 + ` file only in second: d.go
+commit only in second: ` + c3 + `
+differences: 5
+`
+	assert.Equal(t, want, out.String())
+
+	out.Reset()
+	Diff(context.Background(), out, DiffOpts{Dir1: dir1, Dir2: dir1})
+	assert.Equal(t, "differences: 0\n", out.String())
+}
@@ -0,0 +1,116 @@
+package cmdcheckpoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+)
+
+type DiffOpts struct {
+	// Dir1 is the first checkpoints dir or bundle file.
+	Dir1 string
+
+	// Dir2 is the second checkpoints dir or bundle file.
+	Dir2 string
+
+	// Lines set to true to output lines with different content or commit.
+	Lines bool
+}
+
+// Diff outputs differences between two checkpoints: commits and files present in only one of them and files with different blame.
+func Diff(ctx context.Context, out io.Writer, opts DiffOpts) {
+	cp1, err := openCheckpoint(opts.Dir1)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	cp2, err := openCheckpoint(opts.Dir2)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+
+	if cp1.Header.LastCommit != cp2.Header.LastCommit {
+		fmt.Fprintf(out, "last commit: %v != %v\n", cp1.Header.LastCommit, cp2.Header.LastCommit)
+	}
+
+	differences := 0
+	commits2 := map[string]bool{}
+	for _, c := range cp2.commits() {
+		commits2[c] = true
+	}
+	for _, c := range cp1.commits() {
+		if !commits2[c] {
+			fmt.Fprintln(out, "commit only in first:", c)
+			differences++
+			continue
+		}
+		delete(commits2, c)
+		differences += diffCommit(out, cp1, cp2, c, opts.Lines)
+	}
+	for _, c := range cp2.commits() {
+		if commits2[c] {
+			fmt.Fprintln(out, "commit only in second:", c)
+			differences++
+		}
+	}
+	fmt.Fprintln(out, "differences:", differences)
+}
+
+func diffCommit(out io.Writer, cp1, cp2 checkpoint, commit string, lines bool) (differences int) {
+	paths1, blames1 := cp1.files(commit)
+	paths2, blames2 := cp2.files(commit)
+	for _, p := range paths1 {
+		bl2, ok := blames2[p]
+		if !ok {
+			fmt.Fprintf(out, "commit: %v file only in first: %v\n", commit, p)
+			differences++
+			continue
+		}
+		bl1 := blames1[p]
+		if bl1.String() == bl2.String() {
+			continue
+		}
+		fmt.Fprintf(out, "commit: %v file blame differs: %v\n", commit, p)
+		differences++
+		if lines {
+			diffLines(out, bl1, bl2)
+		}
+	}
+	for _, p := range paths2 {
+		if _, ok := blames1[p]; !ok {
+			fmt.Fprintf(out, "commit: %v file only in second: %v\n", commit, p)
+			differences++
+		}
+	}
+	return
+}
+
+func diffLines(out io.Writer, bl1, bl2 *incblame.Blame) {
+	if bl1.Commit != bl2.Commit {
+		fmt.Fprintf(out, "  last changed in: %v != %v\n", bl1.Commit, bl2.Commit)
+	}
+	if bl1.IsBinary != bl2.IsBinary {
+		fmt.Fprintf(out, "  binary: %v != %v\n", bl1.IsBinary, bl2.IsBinary)
+	}
+	if len(bl1.Lines) != len(bl2.Lines) {
+		fmt.Fprintf(out, "  lines: %v != %v\n", len(bl1.Lines), len(bl2.Lines))
+	}
+	for i := 0; i < len(bl1.Lines) || i < len(bl2.Lines); i++ {
+		var l1, l2 incblame.Line
+		if i < len(bl1.Lines) {
+			l1 = *bl1.Lines[i]
+		}
+		if i < len(bl2.Lines) {
+			l2 = *bl2.Lines[i]
+		}
+		if l1.Eq(l2) {
+			continue
+		}
+		fmt.Fprintf(out, "  %5d - %v %s\n", i+1, l1.Commit, l1.Line)
+		fmt.Fprintf(out, "  %5d + %v %s\n", i+1, l2.Commit, l2.Line)
+	}
+}
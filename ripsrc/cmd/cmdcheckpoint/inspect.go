@@ -0,0 +1,70 @@
+package cmdcheckpoint
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+)
+
+type InspectOpts struct {
+	// Dir is the checkpoints dir or bundle file.
+	Dir string
+
+	// Files set to true to list files with line counts for each commit.
+	Files bool
+
+	// Debug set to true to output full content using Repo.Debug.
+	Debug bool
+}
+
+// Inspect outputs checkpoint header and commits stored with number of files and lines.
+func Inspect(ctx context.Context, out io.Writer, opts InspectOpts) {
+	cp, err := openCheckpoint(opts.Dir)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	h := cp.Header
+	fmt.Fprintln(out, "format version:", h.FormatVersion)
+	fmt.Fprintln(out, "last commit:", h.LastCommit)
+	if !h.Created.IsZero() {
+		fmt.Fprintln(out, "created:", h.Created)
+	}
+	var kinds []string
+	for k := range h.Files {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	for _, k := range kinds {
+		fmt.Fprintf(out, "file: %v size=%v raw_size=%v sha256=%v\n", k, h.Files[k].Size, h.Files[k].RawSize, h.Files[k].SHA256)
+	}
+
+	commits := cp.commits()
+	fmt.Fprintln(out, "commits:", len(commits))
+	for _, c := range commits {
+		paths, blames := cp.files(c)
+		lines := 0
+		for _, bl := range blames {
+			lines += len(bl.Lines)
+		}
+		fmt.Fprintf(out, "commit: %v files=%v lines=%v\n", c, len(paths), lines)
+		if !opts.Files {
+			continue
+		}
+		for _, p := range paths {
+			bl := blames[p]
+			if bl.IsBinary {
+				fmt.Fprintf(out, "  %v binary last_commit=%v\n", p, bl.Commit)
+				continue
+			}
+			fmt.Fprintf(out, "  %v lines=%v last_commit=%v\n", p, len(bl.Lines), bl.Commit)
+		}
+	}
+
+	if opts.Debug {
+		fmt.Fprintln(out, cp.Repo.Debug())
+	}
+}
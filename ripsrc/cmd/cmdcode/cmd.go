@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path/filepath"
 	"time"
 
 	"github.com/pinpt/ripsrc/ripsrc/pkg/gitrepos"
@@ -25,6 +26,48 @@ type Opts struct {
 
 	// Profile set to one of mem, mutex, cpu, block, trace to enable profiling.
 	Profile string
+
+	// IgnoreWhitespace keeps the previous author for lines where only whitespace changed.
+	IgnoreWhitespace bool
+
+	// IgnoreRevs are commits that should not take ownership of changed lines.
+	IgnoreRevs []string
+
+	// IgnoreRevsFile is the file with commits to ignore. Default is blame.ignoreRevsFile from git config or .git-blame-ignore-revs.
+	IgnoreRevsFile string
+
+	// NoIgnoreRevsFile skips reading ignore revs file.
+	NoIgnoreRevsFile bool
+
+	// Bots is how lines from bot commits are handled, one of keep, exclude or previous-author.
+	Bots string
+
+	// BotAuthors are patterns matching bot author names and emails. Default is ripsrc.DefaultBotAuthors.
+	BotAuthors []string
+
+	// Submodules set to true to also process checked out submodules of each repo.
+	Submodules bool
+
+	// MaxMemoryMB is the approximate memory budget for blame data, 0 keeps all data in memory.
+	MaxMemoryMB int
+
+	// CheckpointEvery is the number of commits (1000) or duration (10m) between intermediate checkpoints. Empty writes checkpoint only at the end.
+	CheckpointEvery string
+
+	// Resume continues from the latest checkpoint.
+	Resume bool
+
+	// RebuildInvalidCheckpoint processes from the beginning if checkpoint could not be used.
+	RebuildInvalidCheckpoint bool
+
+	// CheckpointStore is the dir to keep checkpoint bundles in, one file per repo. Optional.
+	CheckpointStore string
+
+	// DiffSource is git or native. Empty uses git.
+	DiffSource string
+
+	// SinglePassLog reads commit graph, metadata and patches using one git log.
+	SinglePassLog bool
 }
 
 type Stats struct {
@@ -45,6 +88,25 @@ func (s RepoError) Error() string {
 func Run(ctx context.Context, out io.Writer, opts Opts) {
 	start := time.Now()
 
+	botLines, err := ripsrc.ParseBotLinesMode(opts.Bots)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	opts.Bots = string(botLines)
+
+	_, err = ripsrc.ParseCheckpointEvery(opts.CheckpointEvery)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+
+	_, err = ripsrc.ParseDiffSource(opts.DiffSource)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+
 	if opts.Profile != "" {
 		runEndHook := cmdutils.EnableProfiling(opts.Profile)
 		defer runEndHook()
@@ -80,18 +142,41 @@ func Run(ctx context.Context, out io.Writer, opts Opts) {
 
 func runOnDirs(ctx context.Context, wr io.Writer, opts Opts, dir string, start time.Time) (stats Stats, repoErrors []RepoError, rerr error) {
 
-	err := gitrepos.IterDir(dir, 1, func(dir string) error {
+	var run func(dir string) error
+	run = func(dir string) error {
 		entries, err := runOnRepo(ctx, wr, opts, dir, start)
 		stats.Repos += 1
 		stats.Entries += entries
 		if err == cmdutils.ErrRevParseFailed {
 			stats.SkippedEmptyRepos++
+			return nil
 		} else if err != nil {
 			re := RepoError{Repo: dir, Err: err}
 			repoErrors = append(repoErrors, re)
+			return nil
+		}
+		if !opts.Submodules {
+			return nil
+		}
+		subs, err := ripsrc.New(ripsrc.Opts{RepoDir: dir}).Submodules(ctx)
+		if err != nil {
+			repoErrors = append(repoErrors, RepoError{Repo: dir, Err: err})
+			return nil
+		}
+		for _, sub := range subs {
+			if sub.Dir == "" {
+				fmt.Fprintf(color.Output, "%v", color.YellowString("Skipping submodule %v in %v, not checked out\n", sub.Path, dir))
+				continue
+			}
+			err := run(sub.Dir)
+			if err != nil {
+				return err
+			}
 		}
 		return nil
-	})
+	}
+
+	err := gitrepos.IterDir(dir, 1, run)
 	if err != nil {
 		rerr = err
 		return
@@ -109,6 +194,9 @@ func runOnRepo(ctx context.Context, wr io.Writer, opts Opts, repoDir string, glo
 
 			for commit := range res {
 				fmt.Println(commit.SHA, commit.Date)
+				for _, sub := range commit.Submodules {
+					fmt.Fprintf(color.Output, "[%s][%s] submodule %s status=%s,from=%s,to=%s\n", color.YellowString("%v", repoDir), color.CyanString(commit.SHA[0:8]), color.GreenString(sub.Path), sub.Status, sub.From, sub.To)
+				}
 				for blame := range commit.Blames {
 					entries++
 					var license string
@@ -127,6 +215,22 @@ func runOnRepo(ctx context.Context, wr io.Writer, opts Opts, repoDir string, glo
 		ripOpts.RepoDir = repoDir
 		ripOpts.CommitFromIncl = opts.CommitFromIncl
 		ripOpts.NoStrictResume = true
+		ripOpts.IgnoreWhitespace = opts.IgnoreWhitespace
+		ripOpts.IgnoreRevs = opts.IgnoreRevs
+		ripOpts.IgnoreRevsFile = opts.IgnoreRevsFile
+		ripOpts.NoIgnoreRevsFile = opts.NoIgnoreRevsFile
+		ripOpts.BotLines = ripsrc.BotLinesMode(opts.Bots)
+		ripOpts.BotAuthors = opts.BotAuthors
+		ripOpts.MaxMemoryMB = opts.MaxMemoryMB
+		// validated in Run
+		ripOpts.CheckpointEvery, _ = ripsrc.ParseCheckpointEvery(opts.CheckpointEvery)
+		ripOpts.DiffSource, _ = ripsrc.ParseDiffSource(opts.DiffSource)
+		ripOpts.SinglePassLog = opts.SinglePassLog
+		ripOpts.Resume = opts.Resume
+		ripOpts.RebuildInvalidCheckpoint = opts.RebuildInvalidCheckpoint
+		if opts.CheckpointStore != "" {
+			ripOpts.CheckpointStore = ripsrc.NewLocalCheckpointStore(filepath.Join(opts.CheckpointStore, filepath.Base(repoDir)+".tar.zst"))
+		}
 
 		ripper := ripsrc.New(ripOpts)
 		err := ripper.CodeByCommit(ctx, res)
@@ -0,0 +1,104 @@
+package cmdcodeowners
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+)
+
+type Opts struct {
+	// RepoDir is the git repo to run on.
+	RepoDir string
+
+	// SuggestFile is the location to write suggested CODEOWNERS file to. Optional.
+	SuggestFile string
+
+	// CodeOwners controls mapping of owners to authors and suggestions.
+	CodeOwners ripsrc.CodeOwnersOpts
+
+	// Bots is how lines from bot commits are handled, one of keep, exclude or previous-author.
+	Bots string
+
+	// BotAuthors are patterns matching bot author names and emails. Default is ripsrc.DefaultBotAuthors.
+	BotAuthors []string
+}
+
+func Run(ctx context.Context, out io.Writer, opts Opts) {
+	botLines, err := ripsrc.ParseBotLinesMode(opts.Bots)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	err = cmdutils.RunOnRepo(ctx, out, opts.RepoDir, func() error {
+		ripOpts := ripsrc.Opts{}
+		ripOpts.RepoDir = opts.RepoDir
+		ripOpts.BotLines = botLines
+		ripOpts.BotAuthors = opts.BotAuthors
+		ripOpts.NoStrictResume = true
+
+		ripper := ripsrc.New(ripOpts)
+		res, err := ripper.CodeOwners(ctx, opts.CodeOwners)
+		if err != nil {
+			return err
+		}
+		outputReport(out, res.Report)
+		if opts.SuggestFile != "" {
+			err := ioutil.WriteFile(opts.SuggestFile, res.Suggested, 0666)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(out, "suggested CODEOWNERS written to %v\n", opts.SuggestFile)
+		}
+		return nil
+	})
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+	}
+}
+
+func outputReport(out io.Writer, rep ripsrc.CodeOwnersReport) {
+	fmt.Fprintln(out)
+	if rep.Source == "" {
+		fmt.Fprintf(color.Output, "%v", color.YellowString("No CODEOWNERS file found at commit %v\n", rep.Commit))
+	} else {
+		fmt.Fprintf(out, "CODEOWNERS %v at commit %v, files %v, lines %v\n", rep.Source, rep.Commit, rep.Files, rep.Lines)
+	}
+	for _, r := range rep.Rules {
+		var owners []string
+		for _, o := range r.Rule.Owners {
+			owners = append(owners, o.Value)
+		}
+		share := 0.0
+		if r.Lines != 0 {
+			share = 100 * float64(r.OwnerLines) / float64(r.Lines)
+		}
+		line := fmt.Sprintf("line %v %v owners=%v files=%v lines=%v owner_lines=%.0f%%", r.Rule.Line, r.Rule.Pattern, strings.Join(owners, ","), r.Files, r.Lines, share)
+		if r.Stale() {
+			fmt.Fprintf(color.Output, "%v\n", color.YellowString("%v matches no files", line))
+			continue
+		}
+		var authors []string
+		for i, a := range r.Authors {
+			if i == 3 {
+				break
+			}
+			authors = append(authors, fmt.Sprintf("%v(%v)", a.Email, a.Lines))
+		}
+		fmt.Fprintf(out, "%v top_authors=%v\n", line, strings.Join(authors, ","))
+	}
+	for _, o := range rep.StaleOwners {
+		fmt.Fprintf(color.Output, "%v\n", color.RedString("stale owner %v has no lines in owned files", o))
+	}
+	for _, o := range rep.UnresolvedOwners {
+		fmt.Fprintf(color.Output, "%v\n", color.YellowString("could not map owner %v to commit authors", o))
+	}
+	for _, f := range rep.UnownedFiles {
+		fmt.Fprintf(color.Output, "%v\n", color.RedString("no owner for %v", f))
+	}
+}
@@ -0,0 +1,56 @@
+package cmdlicenses
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+	"github.com/pinpt/ripsrc/ripsrc/fileinfo"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+type Opts struct {
+	// RepoDir is the git repo to run on.
+	RepoDir string
+
+	// Commits to output license inventory for. If empty, outputs inventory for HEAD.
+	Commits []string
+
+	// LicenseMinConfidence is the minimum confidence for license to be detected. Default is 0.85.
+	LicenseMinConfidence float32
+
+	// CustomLicensesDir is a dir with custom license texts, file name without extension is used as license name.
+	CustomLicensesDir string
+}
+
+// Run outputs license inventory as json array to out. Logs are written to stderr.
+func Run(ctx context.Context, out io.Writer, opts Opts) {
+	ripOpts := ripsrc.Opts{}
+	ripOpts.RepoDir = opts.RepoDir
+	ripOpts.Logger = logger.NewDefaultLogger(os.Stderr)
+	ripOpts.LicenseMinConfidence = opts.LicenseMinConfidence
+	if opts.CustomLicensesDir != "" {
+		custom, err := fileinfo.LoadCustomLicenses(opts.CustomLicensesDir)
+		if err != nil {
+			cmdutils.ExitWithErr(err)
+			return
+		}
+		ripOpts.CustomLicenses = custom
+	}
+
+	ripper := ripsrc.New(ripOpts)
+	res, err := ripper.Licenses(ctx, ripsrc.LicensesOpts{Commits: opts.Commits})
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(res)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+	}
+}
@@ -0,0 +1,81 @@
+package cmdsecrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+	"github.com/pinpt/ripsrc/ripsrc/secrets"
+)
+
+type Opts struct {
+	// RepoDir is the git repo to run on.
+	RepoDir string
+
+	// CommitFromIncl starts from specific commit (inclusive). May also include some previous commits.
+	CommitFromIncl string
+
+	// Rules are additional rules by id with regexp. Used together with default rules.
+	Rules map[string]string
+}
+
+// Run outputs secrets found in lines added in all commits as json, one line per finding. Logs are written to stderr.
+func Run(ctx context.Context, out io.Writer, opts Opts) {
+	ripOpts := ripsrc.Opts{}
+	ripOpts.RepoDir = opts.RepoDir
+	ripOpts.CommitFromIncl = opts.CommitFromIncl
+	ripOpts.Logger = logger.NewDefaultLogger(os.Stderr)
+	ripOpts.ScanSecrets = true
+
+	if len(opts.Rules) != 0 {
+		var ids []string
+		for id := range opts.Rules {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		rules := append([]ripsrc.SecretRule{}, secrets.DefaultRules...)
+		for _, id := range ids {
+			re, err := regexp.Compile(opts.Rules[id])
+			if err != nil {
+				cmdutils.ExitWithErr(fmt.Errorf("invalid regexp for rule %v: %v", id, err))
+				return
+			}
+			rules = append(rules, ripsrc.SecretRule{ID: id, Regexp: re})
+		}
+		ripOpts.SecretRules = rules
+	}
+
+	ripper := ripsrc.New(ripOpts)
+
+	res := make(chan ripsrc.BlameResult)
+	done := make(chan error)
+	go func() {
+		enc := json.NewEncoder(out)
+		var err error
+		for r := range res {
+			for _, f := range r.Secrets {
+				if err != nil {
+					break
+				}
+				err = enc.Encode(f)
+			}
+		}
+		done <- err
+	}()
+	err := ripper.Code(ctx, res)
+	encErr := <-done
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	if encErr != nil {
+		cmdutils.ExitWithErr(encErr)
+	}
+}
@@ -0,0 +1,56 @@
+package cmdstats
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+type Opts struct {
+	// RepoDir is the git repo to run on.
+	RepoDir string
+
+	// CommitFromIncl starts from specific commit (inclusive). May also include some previous commits.
+	CommitFromIncl string
+}
+
+// Run outputs repo stats after each commit as json, one line per commit. Logs are written to stderr.
+func Run(ctx context.Context, out io.Writer, opts Opts) {
+	ripOpts := ripsrc.Opts{}
+	ripOpts.RepoDir = opts.RepoDir
+	ripOpts.CommitFromIncl = opts.CommitFromIncl
+	ripOpts.Logger = logger.NewDefaultLogger(os.Stderr)
+	ripOpts.RepoStatsByCommit = true
+
+	ripper := ripsrc.New(ripOpts)
+
+	res := make(chan ripsrc.CommitCode)
+	done := make(chan error)
+	go func() {
+		enc := json.NewEncoder(out)
+		var err error
+		for c := range res {
+			for range c.Blames {
+			}
+			if err != nil {
+				continue
+			}
+			err = enc.Encode(c.Stats)
+		}
+		done <- err
+	}()
+	err := ripper.CodeByCommit(ctx, res)
+	encErr := <-done
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	if encErr != nil {
+		cmdutils.ExitWithErr(encErr)
+	}
+}
@@ -0,0 +1,53 @@
+package cmdsummary
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+type Opts struct {
+	// RepoDir is the git repo to run on.
+	RepoDir string
+
+	// Commits to output summary for. If empty, outputs summary for HEAD.
+	Commits []string
+
+	// Bots is how lines from bot commits are handled, one of keep, exclude or previous-author.
+	Bots string
+
+	// BotAuthors are patterns matching bot author names and emails. Default is ripsrc.DefaultBotAuthors.
+	BotAuthors []string
+}
+
+// Run outputs repo summary as json array to out. Logs are written to stderr.
+func Run(ctx context.Context, out io.Writer, opts Opts) {
+	botLines, err := ripsrc.ParseBotLinesMode(opts.Bots)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	ripOpts := ripsrc.Opts{}
+	ripOpts.RepoDir = opts.RepoDir
+	ripOpts.BotLines = botLines
+	ripOpts.BotAuthors = opts.BotAuthors
+	ripOpts.Logger = logger.NewDefaultLogger(os.Stderr)
+
+	ripper := ripsrc.New(ripOpts)
+	res, err := ripper.Summary(ctx, ripsrc.SummaryOpts{Commits: opts.Commits})
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(res)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+	}
+}
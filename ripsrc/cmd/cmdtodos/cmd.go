@@ -0,0 +1,44 @@
+package cmdtodos
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/pinpt/ripsrc/ripsrc"
+	"github.com/pinpt/ripsrc/ripsrc/cmd/cmdutils"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+type Opts struct {
+	// RepoDir is the git repo to run on.
+	RepoDir string
+
+	// Commits to output TODOs for. If empty, outputs TODOs at HEAD.
+	Commits []string
+
+	// Tags matched in comments. Default is ripsrc.DefaultTODOTags.
+	Tags []string
+}
+
+// Run outputs TODO reports as json array to out. Logs are written to stderr.
+func Run(ctx context.Context, out io.Writer, opts Opts) {
+	ripOpts := ripsrc.Opts{}
+	ripOpts.RepoDir = opts.RepoDir
+	ripOpts.Logger = logger.NewDefaultLogger(os.Stderr)
+	ripOpts.TODOTags = opts.Tags
+
+	ripper := ripsrc.New(ripOpts)
+	res, err := ripper.TODOs(ctx, ripsrc.TODOsOpts{Commits: opts.Commits})
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+		return
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	err = enc.Encode(res)
+	if err != nil {
+		cmdutils.ExitWithErr(err)
+	}
+}
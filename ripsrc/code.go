@@ -3,11 +3,14 @@ package ripsrc
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
+	"github.com/pinpt/ripsrc/ripsrc/codeage"
 	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
 	"github.com/pinpt/ripsrc/ripsrc/fileinfo"
 	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+	"github.com/pinpt/ripsrc/ripsrc/singlepass"
 )
 
 // Commit is a specific detail around a commit
@@ -31,7 +34,23 @@ type BlameResult struct {
 	WeightedComplexity float64
 	Skipped            string
 	License            *License
-	Status             CommitStatus
+	// DeclaredLicense is the license expression from SPDX-License-Identifier tag in the file header.
+	DeclaredLicense string
+	// LicenseTags are all SPDX-License-Identifier tags in file. Tags after the header usually mark copied code.
+	LicenseTags []LicenseTag
+	// Copyrights are the copyright notices in file.
+	Copyrights []Copyright
+	// CopyrightHolders are the distinct holders from Copyrights in order of appearance.
+	CopyrightHolders []string
+	// Secrets are the possible secrets in lines added in this commit. Only set when Opts.ScanSecrets is true. Skipped files are also checked.
+	Secrets []Secret
+	// LFS is set for git lfs pointer files with the size and oid of stored file. These files are skipped.
+	LFS *LFSPointer
+	// TODOs are the comment lines with TODO tags. Only set when Opts.TODOs is true.
+	TODOs  []TODO
+	Status CommitStatus
+	// Age is the distribution of line age relative to the commit date.
+	Age CodeAge
 }
 
 // BlameLine is a single line entry in blame
@@ -43,11 +62,28 @@ type BlameLine struct {
 	Code    bool
 	Blank   bool
 	SHA     string
+	// Bot is true if line is from bot commit, see Opts.BotAuthors.
+	Bot bool
 }
 
+// CodeAge is the distribution of line age, with histogram by age, median and oldest lines.
+type CodeAge = codeage.Dist
+
+// CodeAgeBucket is the number of lines in age range.
+type CodeAgeBucket = codeage.Bucket
+
 // License holds details about detected license
 type License = fileinfo.License
 
+// LicenseTag is a license expression declared in file using SPDX-License-Identifier tag
+type LicenseTag = fileinfo.LicenseTag
+
+// Copyright is a copyright notice found in file
+type Copyright = fileinfo.Copyright
+
+// LFSPointer is the reference to file stored in git lfs
+type LFSPointer = fileinfo.LFSPointer
+
 // CommitStatus is a commit status type
 type CommitStatus = commitmeta.CommitStatus
 
@@ -88,6 +124,16 @@ func (s *Ripsrc) Code(ctx context.Context, res chan BlameResult) error {
 type CommitCode struct {
 	Commit
 	Blames chan BlameResult
+	// Age is the distribution of line age for the whole repo at this commit. Only set when Opts.CodeAgeByCommit is true.
+	Age *CodeAge
+	// Stats are the totals for the whole repo at this commit. Only set when Opts.RepoStatsByCommit is true.
+	Stats *RepoStats
+	// Ignored is true for commits in ignore revs, including bot commits when Opts.BotLines is BotLinesPreviousAuthor. These commits do not take ownership of the lines they change.
+	Ignored bool
+	// Bot is true if commit author matches Opts.BotAuthors.
+	Bot bool
+	// Submodules are the submodule pointer changes in this commit.
+	Submodules []SubmoduleChange
 }
 
 // CodeByCommit returns code information using one record per commit that includes records by file
@@ -99,7 +145,15 @@ func (s *Ripsrc) CodeByCommit(ctx context.Context, res chan CommitCode) error {
 		return err
 	}
 
-	err = s.buildCommitGraph(ctx)
+	singlePass := s.useSinglePass()
+	if !singlePass {
+		err = s.buildCommitGraph(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.resolveResume()
 	if err != nil {
 		return err
 	}
@@ -135,10 +189,45 @@ func (s *Ripsrc) CodeByCommit(ctx context.Context, res chan CommitCode) error {
 		s.opts.Logger.Debug("processing additional branches", "branches", wantedBranchNames)
 	}
 
-	err = s.getCommitInfo(ctx, wantedBranchRefs)
+	var singlePassRes *singlepass.Result
+	if singlePass {
+		singlePassRes, err = s.runSinglePass()
+		if err != nil {
+			return err
+		}
+		defer singlePassRes.Remove()
+	} else {
+		err = s.getCommitInfo(ctx, wantedBranchRefs)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.updateCommitMetaCache()
+	if err != nil {
+		return err
+	}
+
+	err = s.prepareBots()
+	if err != nil {
+		return err
+	}
+
+	ignoreRevs, err := s.resolveIgnoreRevs(ctx)
 	if err != nil {
 		return err
 	}
+	if s.opts.BotLines == BotLinesPreviousAuthor {
+		ignoreRevs = append(ignoreRevs, s.botIgnoreRevs()...)
+	}
+
+	var patches io.ReadCloser
+	if singlePassRes != nil {
+		patches, err = singlePassRes.Patches()
+		if err != nil {
+			return err
+		}
+	}
 
 	gitRes := make(chan process.Result)
 	done := make(chan bool)
@@ -153,11 +242,33 @@ func (s *Ripsrc) CodeByCommit(ctx context.Context, res chan CommitCode) error {
 				panic(fmt.Errorf("commit not found in commit meta: %v", r1.Commit))
 			}
 			rc.Commit = commit
+			rc.Ignored = r1.Ignored
+			rc.Bot = s.isBot(commit)
 
-			rs, err := s.codeInfoFiles(r1)
+			rs, subs, err := s.codeInfoFiles(r1)
 			if err != nil {
 				panic(err)
 			}
+			rc.Submodules = subs
+			if s.snapshot != nil {
+				err := s.snapshot(commit, rs, r1.Tree)
+				if err != nil {
+					panic(err)
+				}
+			}
+			if s.opts.CodeAgeByCommit || s.opts.RepoStatsByCommit {
+				st, err := s.updateCommitState(ctx, commit, r1.Tree, rs, subs)
+				if err != nil {
+					panic(err)
+				}
+				if st.age != nil {
+					age := st.age.Dist(commit.Date)
+					rc.Age = &age
+				}
+				if st.stats != nil {
+					rc.Stats = st.stats.Stats(commit)
+				}
+			}
 			res <- rc
 			for _, r := range rs {
 				rc.Blames <- r
@@ -177,6 +288,18 @@ func (s *Ripsrc) CodeByCommit(ctx context.Context, res chan CommitCode) error {
 		AllBranches:           s.opts.AllBranches,
 		ParentsGraph:          s.commitGraph,
 		WantedBranchRefs:      wantedBranchRefs,
+		DetectMoves:           s.opts.DetectMoves,
+		DetectCopiesAllFiles:  s.opts.DetectCopiesAllFiles,
+		IgnoreWhitespace:      s.opts.IgnoreWhitespace,
+		IgnoreRevs:            ignoreRevs,
+		IgnoreRevsFile:        s.opts.IgnoreRevsFile,
+		NoIgnoreRevsFile:      s.opts.NoIgnoreRevsFile,
+		MaxMemoryMB:           s.opts.MaxMemoryMB,
+		CheckpointEvery:       s.opts.CheckpointEvery,
+		CheckpointStore:       s.opts.CheckpointStore,
+		DiffSource:            s.opts.DiffSource,
+		Patches:               patches,
+		AddedLines:            s.secrets != nil,
 	}
 	gitProcessor := process.New(processOpts)
 	err = gitProcessor.Run(gitRes)
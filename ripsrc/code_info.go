@@ -7,6 +7,7 @@ import (
 	"runtime/debug"
 	"time"
 
+	"github.com/pinpt/ripsrc/ripsrc/codeage"
 	"github.com/pinpt/ripsrc/ripsrc/fileinfo"
 
 	"github.com/boyter/scc/processor"
@@ -14,7 +15,7 @@ import (
 	"github.com/pinpt/ripsrc/ripsrc/history3/process"
 )
 
-func (s *Ripsrc) codeInfoFiles(blame process.Result) (res []BlameResult, _ error) {
+func (s *Ripsrc) codeInfoFiles(blame process.Result) (res []BlameResult, subs []SubmoduleChange, _ error) {
 	commit := s.commitMeta[blame.Commit]
 
 	// check that files are included in both
@@ -55,6 +56,11 @@ func (s *Ripsrc) codeInfoFiles(blame process.Result) (res []BlameResult, _ error
 			//panic(fmt.Errorf("Changed file was not found in stats log entry, file %v commit %v", r.Filename, commit.SHA))
 		}
 
+		if ch, ok := submoduleChange(filePath, f, blf, len(commit.Parents) > 1); ok {
+			subs = append(subs, ch)
+			continue
+		}
+
 		r.Status = f.Status
 
 		if r.Status == GitFileCommitStatusRemoved {
@@ -64,21 +70,13 @@ func (s *Ripsrc) codeInfoFiles(blame process.Result) (res []BlameResult, _ error
 			continue
 		}
 
-		fileBytes := blameToFileContent(blf)
-		fileLines := blameToByteLines(blf)
-		info, skipReason := s.fileInfo.GetInfo(fileinfo.InfoArgs{FilePath: filePath, Content: fileBytes, Lines: fileLines})
-		r.License = info.License
-		r.Language = info.Language
-
-		if skipReason != "" {
-			r.Skipped = skipReason
-			res = append(res, r)
-			continue
+		if s.secrets != nil {
+			r.Secrets = s.scanSecrets(commit, filePath, blf, blame.AddedLines[filePath])
 		}
 
-		r, err := s.codeInfoFile(filePath, blf, fileBytes, r)
+		r, err := s.codeInfoBlame(filePath, blf, r)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
 		res = append(res, r)
@@ -86,6 +84,38 @@ func (s *Ripsrc) codeInfoFiles(blame process.Result) (res []BlameResult, _ error
 	return
 }
 
+// codeInfoBlame sets file info and code stats for file with blame data. Commit, Filename and Status should be set in res.
+func (s *Ripsrc) codeInfoBlame(filePath string, blf *incblame.Blame, res BlameResult) (BlameResult, error) {
+	fileBytes := blameToFileContent(blf)
+	fileLines := blameToByteLines(blf)
+	info, skipReason := s.fileInfo.GetInfo(fileinfo.InfoArgs{FilePath: filePath, Content: fileBytes, Lines: fileLines})
+	res.License = info.License
+	res.LFS = info.LFS
+	setNotices(&res, info)
+	res.Language = info.Language
+
+	if skipReason != "" {
+		res.Skipped = skipReason
+		return res, nil
+	}
+
+	return s.codeInfoFile(filePath, blf, fileBytes, res)
+}
+
+func setNotices(r *BlameResult, info fileinfo.Info) {
+	r.DeclaredLicense = info.DeclaredLicense
+	r.LicenseTags = info.LicenseTags
+	r.Copyrights = info.Copyrights
+	seen := map[string]bool{}
+	for _, c := range r.Copyrights {
+		if c.Holder == "" || seen[c.Holder] {
+			continue
+		}
+		seen[c.Holder] = true
+		r.CopyrightHolders = append(r.CopyrightHolders, c.Holder)
+	}
+}
+
 const (
 	generatedFile = "file was a generated file"
 	//whitelisted      = "File was not on the inclusion list"
@@ -119,7 +149,7 @@ func (s *Ripsrc) codeInfoFile(filePath string, bl *incblame.Blame, fileBytes []b
 
 	// assign lines to result
 	for _, line := range bl.Lines {
-		meta := s.commitMeta[line.Commit]
+		meta := s.lineCommit(line.Commit)
 		line2 := &statsLine{}
 		line2.BlameLine = &BlameLine{}
 		line2.Name = meta.AuthorName
@@ -127,6 +157,7 @@ func (s *Ripsrc) codeInfoFile(filePath string, bl *incblame.Blame, fileBytes []b
 		line2.Date = meta.Date
 		line2.line = line.Line
 		line2.SHA = line.Commit
+		line2.Bot = s.isBotSHA(line.Commit)
 		lines = append(lines, line2)
 	}
 
@@ -179,8 +210,15 @@ func (s *Ripsrc) codeStats(filePath string, bl *incblame.Blame, fileBytes []byte
 		res.Skipped = generatedFile
 	}
 
-	for _, l := range lines {
+	ageLines := make([]codeage.Line, 0, len(lines))
+	for i, l := range lines {
 		res.Lines = append(res.Lines, l.BlameLine)
+		ageLines = append(ageLines, codeage.Line{Line: i + 1, SHA: l.SHA, Date: l.Date})
+	}
+	res.Age = codeage.FromLines(res.Commit.Date, ageLines, s.opts.CodeAgeBuckets)
+
+	if s.opts.TODOs && res.Skipped == "" {
+		res.TODOs = s.findTODOs(res, bl)
 	}
 
 	return res, nil
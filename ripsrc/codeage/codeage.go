@@ -0,0 +1,114 @@
+// Package codeage calculates distribution of line age based on blame data.
+package codeage
+
+import (
+	"sort"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// DefaultBuckets are the upper bounds of age buckets used by default. Lines older than the last bound are counted in an additional bucket.
+var DefaultBuckets = []time.Duration{
+	30 * day,
+	90 * day,
+	180 * day,
+	365 * day,
+	2 * 365 * day,
+	5 * 365 * day,
+}
+
+// maxOldest is the number of oldest lines returned per file
+const maxOldest = 5
+
+// Bucket is the number of lines with age less than MaxAge and greater or equal to MaxAge of the previous bucket.
+type Bucket struct {
+	// MaxAge is the upper bound of the bucket. 0 for the last bucket containing all older lines.
+	MaxAge time.Duration
+	Lines  int
+}
+
+// Line is a line with age.
+type Line struct {
+	// Line is the line number starting from 1.
+	Line int
+	SHA  string
+	Date time.Time
+	Age  time.Duration
+}
+
+// Dist is the distribution of line age relative to a point in time, usually the date of the processed commit.
+type Dist struct {
+	// Lines is the number of lines included.
+	Lines int
+	// Buckets is the histogram of lines by age.
+	Buckets []Bucket
+	// Median is the median age of the lines.
+	Median time.Duration
+	// OldestDate is the date of the oldest line.
+	OldestDate time.Time
+	// Oldest are the oldest lines in file, oldest first. Only set for single file.
+	Oldest []Line
+}
+
+func newDist(buckets []time.Duration) Dist {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	res := Dist{}
+	for _, b := range buckets {
+		res.Buckets = append(res.Buckets, Bucket{MaxAge: b})
+	}
+	res.Buckets = append(res.Buckets, Bucket{})
+	return res
+}
+
+func age(at, date time.Time) time.Duration {
+	res := at.Sub(date)
+	if res < 0 {
+		// line date could be after commit date when rebasing
+		return 0
+	}
+	return res
+}
+
+func (s *Dist) add(a time.Duration) {
+	s.Lines++
+	for i := range s.Buckets {
+		b := &s.Buckets[i]
+		if b.MaxAge == 0 || a < b.MaxAge {
+			b.Lines++
+			return
+		}
+	}
+}
+
+// FromLines calculates age distribution for lines of one file. Line.Line and Line.Date need to be set, Age is calculated relative to at.
+func FromLines(at time.Time, lines []Line, buckets []time.Duration) Dist {
+	res := newDist(buckets)
+	if len(lines) == 0 {
+		return res
+	}
+	ages := make([]time.Duration, 0, len(lines))
+	sorted := make([]Line, 0, len(lines))
+	for _, l := range lines {
+		l.Age = age(at, l.Date)
+		res.add(l.Age)
+		ages = append(ages, l.Age)
+		sorted = append(sorted, l)
+	}
+	sort.Slice(ages, func(i, j int) bool {
+		return ages[i] < ages[j]
+	})
+	// same as tracker, for even number of lines use the older of the two middle lines
+	res.Median = ages[len(ages)-(len(ages)+1)/2]
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	res.OldestDate = sorted[0].Date
+	if len(sorted) > maxOldest {
+		sorted = sorted[:maxOldest]
+	}
+	res.Oldest = sorted
+	return res
+}
@@ -0,0 +1,126 @@
+package codeage
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var now = time.Date(2019, 6, 1, 0, 0, 0, 0, time.UTC)
+
+func daysAgo(d int) time.Time {
+	return now.Add(-time.Duration(d) * day)
+}
+
+func bucketLines(d Dist) (res []int) {
+	for _, b := range d.Buckets {
+		res = append(res, b.Lines)
+	}
+	return
+}
+
+func TestFromLines(t *testing.T) {
+	lines := []Line{
+		{Line: 1, SHA: "c1", Date: daysAgo(1000)},
+		{Line: 2, SHA: "c2", Date: daysAgo(10)},
+		{Line: 3, SHA: "c3", Date: daysAgo(100)},
+		{Line: 4, SHA: "c3", Date: daysAgo(100)},
+		{Line: 5, SHA: "c4", Date: daysAgo(3000)},
+		// author date after commit date
+		{Line: 6, SHA: "c5", Date: now.Add(time.Hour)},
+	}
+	d := FromLines(now, lines, nil)
+	assert.Equal(t, 6, d.Lines)
+	assert.Equal(t, []int{2, 0, 2, 0, 0, 1, 1}, bucketLines(d))
+	assert.Equal(t, 100*day, d.Median)
+	assert.Equal(t, daysAgo(3000), d.OldestDate)
+	assert.Len(t, d.Oldest, 5)
+	assert.Equal(t, 5, d.Oldest[0].Line)
+	assert.Equal(t, 3000*day, d.Oldest[0].Age)
+	assert.Equal(t, 1, d.Oldest[1].Line)
+	assert.Equal(t, 3, d.Oldest[2].Line)
+	assert.Equal(t, 4, d.Oldest[3].Line)
+}
+
+func TestFromLinesEmpty(t *testing.T) {
+	d := FromLines(now, nil, []time.Duration{day})
+	assert.Equal(t, 0, d.Lines)
+	assert.Equal(t, []int{0, 0}, bucketLines(d))
+}
+
+func TestTracker(t *testing.T) {
+	tr := NewTracker(nil)
+	tr.Set("a", []time.Time{daysAgo(1000), daysAgo(10), daysAgo(100)})
+	tr.Set("b", []time.Time{daysAgo(100), daysAgo(3000), now.Add(time.Hour)})
+
+	d := tr.Dist(now)
+	assert.Equal(t, 6, d.Lines)
+	assert.Equal(t, []int{2, 0, 2, 0, 0, 1, 1}, bucketLines(d))
+	assert.Equal(t, 100*day, d.Median)
+	assert.Equal(t, daysAgo(3000), d.OldestDate)
+	assert.Empty(t, d.Oldest)
+
+	tr.Set("b", []time.Time{daysAgo(5)})
+	tr.Remove("missing")
+	d = tr.Dist(now)
+	assert.Equal(t, 4, d.Lines)
+	assert.Equal(t, []int{2, 0, 1, 0, 0, 1, 0}, bucketLines(d))
+	assert.Equal(t, daysAgo(1000), d.OldestDate)
+
+	tr.Remove("a")
+	tr.Remove("b")
+	d = tr.Dist(now)
+	assert.Equal(t, 0, d.Lines)
+	assert.Equal(t, []int{0, 0, 0, 0, 0, 0, 0}, bucketLines(d))
+}
+
+func TestTrackerCopy(t *testing.T) {
+	tr := NewTracker(nil)
+	tr.Set("a", []time.Time{daysAgo(1000), daysAgo(10)})
+	c := tr.Copy()
+	c.Set("b", []time.Time{daysAgo(100)})
+	c.Remove("a")
+	assert.Equal(t, 2, tr.Dist(now).Lines)
+	assert.Equal(t, daysAgo(1000), tr.Dist(now).OldestDate)
+	assert.Equal(t, 1, c.Dist(now).Lines)
+	assert.Equal(t, daysAgo(100), c.Dist(now).OldestDate)
+}
+
+func TestTrackerSameAsFromLines(t *testing.T) {
+	var lines []Line
+	var dates []time.Time
+	for i := 0; i < 100; i++ {
+		d := daysAgo(i * i)
+		lines = append(lines, Line{Line: i + 1, Date: d})
+		dates = append(dates, d)
+	}
+	tr := NewTracker(nil)
+	tr.Set("a", dates)
+	want := FromLines(now, lines, nil)
+	got := tr.Dist(now)
+	assert.Equal(t, bucketLines(want), bucketLines(got))
+	assert.Equal(t, want.Median, got.Median)
+	assert.Equal(t, want.OldestDate, got.OldestDate)
+}
+
+func TestTrackerGrowsToDateRange(t *testing.T) {
+	tr := NewTracker(nil)
+	var lines []Line
+	// newer and older dates than already tracked, in both directions
+	for i, ago := range []int{100, 90, 400, 5, 2000, 1, 1500, 3000} {
+		d := daysAgo(ago)
+		lines = append(lines, Line{Line: i + 1, Date: d})
+		tr.Set(strconv.Itoa(i), []time.Time{d})
+
+		want := FromLines(now, lines, nil)
+		got := tr.Dist(now)
+		assert.Equal(t, bucketLines(want), bucketLines(got))
+		assert.Equal(t, want.Median, got.Median)
+		assert.Equal(t, want.OldestDate, got.OldestDate)
+	}
+	// 3000 days fit into 4096, not the whole supported range
+	assert.Equal(t, 4096+1, len(tr.tree))
+	assert.Equal(t, 4096+1, len(tr.Copy().tree))
+}
@@ -0,0 +1,220 @@
+package codeage
+
+import (
+	"time"
+)
+
+// maxDay is the number of days since unix epoch supported by tracker, around year 2200. Later dates are counted as the last day.
+const maxDay = 84000
+
+// minTreeDays is the initial number of days in tracker, grown by doubling when dates outside of range are added
+const minTreeDays = 256
+
+// Tracker keeps line dates for all files in repo and calculates repo level distribution. Dates are tracked with day precision.
+//
+// Only the range of days of tracked lines is kept, so copies for each commit are small.
+type Tracker struct {
+	buckets []time.Duration
+	files   map[string][]int32
+	// fenwick tree of line counts by day, index i is day from+i-1
+	tree []int
+	// from is the first day in tree
+	from  int32
+	lines int
+}
+
+// NewTracker creates a tracker using passed age buckets. Uses DefaultBuckets if empty.
+func NewTracker(buckets []time.Duration) *Tracker {
+	s := &Tracker{}
+	s.buckets = buckets
+	s.files = map[string][]int32{}
+	return s
+}
+
+// Copy returns a copy to be updated separately.
+func (s *Tracker) Copy() *Tracker {
+	res := &Tracker{}
+	res.buckets = s.buckets
+	res.files = make(map[string][]int32, len(s.files))
+	// days of file are not modified after set
+	for fp, days := range s.files {
+		res.files[fp] = days
+	}
+	res.tree = make([]int, len(s.tree))
+	copy(res.tree, s.tree)
+	res.from = s.from
+	res.lines = s.lines
+	return res
+}
+
+func toDay(t time.Time) int32 {
+	d := t.Unix() / int64(day/time.Second)
+	if d < 0 {
+		return 0
+	}
+	if d >= maxDay {
+		return maxDay - 1
+	}
+	return int32(d)
+}
+
+func fromDay(d int32) time.Time {
+	return time.Unix(int64(d)*int64(day/time.Second), 0).UTC()
+}
+
+// size returns the number of days in tree
+func (s *Tracker) size() int {
+	if len(s.tree) == 0 {
+		return 0
+	}
+	return len(s.tree) - 1
+}
+
+// fit grows tree to include days from min to max. Tree is rebuilt from files, size is doubled so that it is rebuilt rarely.
+func (s *Tracker) fit(min, max int32) {
+	n := s.size()
+	if n != 0 {
+		if min >= s.from && int(max-s.from) < n {
+			return
+		}
+		last := s.from + int32(n) - 1
+		if s.from < min {
+			min = s.from
+		}
+		if last > max {
+			max = last
+		}
+	}
+	size := minTreeDays
+	for size < int(max-min)+1 {
+		size *= 2
+	}
+	from := min
+	if n != 0 && min < s.from {
+		// growing to older days, keep free space before them
+		from = max - int32(size) + 1
+		if from < 0 {
+			from = 0
+		}
+	}
+	s.from = from
+	s.tree = make([]int, size+1)
+	for _, days := range s.files {
+		for _, d := range days {
+			s.tree[int(d-from)+1]++
+		}
+	}
+	// build fenwick tree from counts in linear time
+	for i := 1; i <= size; i++ {
+		if j := i + (i & -i); j <= size {
+			s.tree[j] += s.tree[i]
+		}
+	}
+}
+
+func (s *Tracker) treeAdd(d int32, v int) {
+	n := s.size()
+	for i := int(d-s.from) + 1; i <= n; i += i & -i {
+		s.tree[i] += v
+	}
+	s.lines += v
+}
+
+// countUpTo returns number of lines with day <= d
+func (s *Tracker) countUpTo(d int32) (res int) {
+	if d < s.from {
+		return 0
+	}
+	n := s.size()
+	i := int(d-s.from) + 1
+	if i > n {
+		return s.lines
+	}
+	for ; i > 0; i -= i & -i {
+		res += s.tree[i]
+	}
+	return
+}
+
+// kth returns the day of k-th (starting from 1) oldest line
+func (s *Tracker) kth(k int) int32 {
+	n := s.size()
+	pos := 0
+	step := 1
+	for step*2 <= n {
+		step *= 2
+	}
+	for ; step > 0; step /= 2 {
+		if pos+step <= n && s.tree[pos+step] < k {
+			pos += step
+			k -= s.tree[pos]
+		}
+	}
+	return s.from + int32(pos)
+}
+
+// Set replaces line dates for file.
+func (s *Tracker) Set(filePath string, dates []time.Time) {
+	s.Remove(filePath)
+	if len(dates) == 0 {
+		return
+	}
+	days := make([]int32, len(dates))
+	min, max := int32(maxDay), int32(0)
+	for i, d := range dates {
+		days[i] = toDay(d)
+		if days[i] < min {
+			min = days[i]
+		}
+		if days[i] > max {
+			max = days[i]
+		}
+	}
+	s.fit(min, max)
+	for _, d := range days {
+		s.treeAdd(d, 1)
+	}
+	s.files[filePath] = days
+}
+
+// Remove removes file from tracker.
+func (s *Tracker) Remove(filePath string) {
+	days, ok := s.files[filePath]
+	if !ok {
+		return
+	}
+	for _, d := range days {
+		s.treeAdd(d, -1)
+	}
+	delete(s.files, filePath)
+}
+
+// Dist returns age distribution for all tracked lines relative to at.
+func (s *Tracker) Dist(at time.Time) Dist {
+	res := newDist(s.buckets)
+	res.Lines = s.lines
+	if s.lines == 0 {
+		return res
+	}
+	atDay := toDay(at)
+	prev := 0
+	for i := range res.Buckets {
+		b := &res.Buckets[i]
+		if b.MaxAge == 0 {
+			b.Lines = s.lines - prev
+			break
+		}
+		// lines younger than MaxAge have day > atDay - MaxAge
+		cutoff := atDay - int32(b.MaxAge/day)
+		younger := s.lines
+		if cutoff >= 0 {
+			younger -= s.countUpTo(cutoff)
+		}
+		b.Lines = younger - prev
+		prev = younger
+	}
+	medianDay := s.kth((s.lines + 1) / 2)
+	res.Median = age(fromDay(atDay), fromDay(medianDay))
+	res.OldestDate = fromDay(s.kth(1))
+	return res
+}
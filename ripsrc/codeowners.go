@@ -0,0 +1,101 @@
+package ripsrc
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/pinpt/ripsrc/ripsrc/codeowners"
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+)
+
+// CodeOwnersOpts controls mapping of CODEOWNERS entries to blame authors.
+type CodeOwnersOpts = codeowners.Opts
+
+// CodeOwnersReport is the result of validating CODEOWNERS against blame data.
+type CodeOwnersReport = codeowners.Report
+
+// CodeOwnersResult contains CODEOWNERS validation report and suggested CODEOWNERS file.
+type CodeOwnersResult struct {
+	Report CodeOwnersReport
+	// Suggested is the content of CODEOWNERS file generated from blame data.
+	Suggested []byte
+}
+
+// CodeOwners reads CODEOWNERS file as of HEAD and maps each rule to the authors of the lines in the files it matches.
+func (s *Ripsrc) CodeOwners(ctx context.Context, opts CodeOwnersOpts) (res CodeOwnersResult, _ error) {
+	err := s.prepareGitExec(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	source, data, err := s.readCodeOwnersFile(ctx)
+	if err != nil {
+		return res, err
+	}
+	file, err := codeowners.Parse(bytes.NewReader(data))
+	if err != nil {
+		return res, err
+	}
+
+	commit, blames, err := s.codeAtHead(ctx)
+	if err != nil {
+		return res, err
+	}
+
+	var files []codeowners.FileAuthors
+	for fp, bl := range blames {
+		f := codeowners.FileAuthors{}
+		f.Path = fp
+		f.Authors = map[codeowners.Author]int{}
+		for _, l := range bl.Lines {
+			if !s.ownsLine(l) {
+				continue
+			}
+			f.Authors[codeowners.Author{Name: l.Name, Email: l.Email}]++
+		}
+		files = append(files, f)
+	}
+
+	res.Report = codeowners.Analyze(file, files, opts)
+	res.Report.Commit = commit
+	res.Report.Source = source
+	res.Suggested = codeowners.Suggest(commit, files, opts)
+	return res, nil
+}
+
+// readCodeOwnersFile returns the content of CODEOWNERS file at HEAD. Returns empty source if the file does not exist.
+func (s *Ripsrc) readCodeOwnersFile(ctx context.Context) (source string, data []byte, _ error) {
+	args := append([]string{"ls-tree", "--name-only", "HEAD", "--"}, codeowners.Locations...)
+	r, err := gitexec.Exec(ctx, gitCommand, s.opts.RepoDir, args)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+	buf := bytes.NewBuffer(nil)
+	_, err = buf.ReadFrom(r)
+	if err != nil {
+		return "", nil, err
+	}
+	exists := map[string]bool{}
+	for _, p := range strings.Split(buf.String(), "\n") {
+		exists[p] = true
+	}
+	for _, loc := range codeowners.Locations {
+		if !exists[loc] {
+			continue
+		}
+		r, err := gitexec.Exec(ctx, gitCommand, s.opts.RepoDir, []string{"show", "HEAD:" + loc})
+		if err != nil {
+			return "", nil, err
+		}
+		defer r.Close()
+		buf := bytes.NewBuffer(nil)
+		_, err = buf.ReadFrom(r)
+		if err != nil {
+			return "", nil, err
+		}
+		return loc, buf.Bytes(), nil
+	}
+	return "", nil, nil
+}
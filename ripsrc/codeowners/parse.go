@@ -0,0 +1,243 @@
+package codeowners
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Locations are the paths where github and gitlab look for CODEOWNERS file, in order of precedence.
+var Locations = []string{
+	".github/CODEOWNERS",
+	"CODEOWNERS",
+	"docs/CODEOWNERS",
+	".gitlab/CODEOWNERS",
+}
+
+// File is a parsed CODEOWNERS file.
+type File struct {
+	Rules []Rule
+}
+
+// Rule is one pattern line from CODEOWNERS file.
+type Rule struct {
+	// Line is the line number in the file, starting from 1.
+	Line int
+	// Pattern as written in the file.
+	Pattern string
+	// Owners listed for the pattern. Empty if pattern explicitly has no owners.
+	Owners []Owner
+	// Section is the gitlab section name, empty for rules outside of sections.
+	Section string
+
+	re *regexp.Regexp
+}
+
+// Match returns true if the rule pattern matches file path.
+func (r Rule) Match(filePath string) bool {
+	return r.re.MatchString(strings.TrimPrefix(filePath, "/"))
+}
+
+// OwnerKind is the type of owner reference.
+type OwnerKind string
+
+const (
+	// OwnerUser is a user reference, for example @user
+	OwnerUser OwnerKind = "user"
+	// OwnerTeam is a team or group reference, for example @org/team
+	OwnerTeam OwnerKind = "team"
+	// OwnerEmail is an email reference, for example user@example.com
+	OwnerEmail OwnerKind = "email"
+)
+
+// Owner is a single owner listed in CODEOWNERS.
+type Owner struct {
+	Value string
+	Kind  OwnerKind
+}
+
+func (o Owner) String() string {
+	return o.Value
+}
+
+func parseOwner(v string) Owner {
+	switch {
+	case strings.HasPrefix(v, "@") && strings.Contains(v, "/"):
+		return Owner{Value: v, Kind: OwnerTeam}
+	case strings.HasPrefix(v, "@"):
+		return Owner{Value: v, Kind: OwnerUser}
+	default:
+		return Owner{Value: v, Kind: OwnerEmail}
+	}
+}
+
+// gitlab section header, for example [Docs], ^[Optional][2] @default-owner
+var sectionRe = regexp.MustCompile(`^\^?\[([^\]]+)\](\[\d+\])?(.*)$`)
+
+// Parse parses CODEOWNERS file in github or gitlab syntax.
+func Parse(r io.Reader) (res File, _ error) {
+	scanner := bufio.NewScanner(r)
+	section := ""
+	var sectionOwners []Owner
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := sectionRe.FindStringSubmatch(line); m != nil {
+			section = m[1]
+			sectionOwners = nil
+			for _, f := range strings.Fields(stripComment(m[3])) {
+				sectionOwners = append(sectionOwners, parseOwner(f))
+			}
+			continue
+		}
+		fields := splitFields(stripComment(line))
+		if len(fields) == 0 {
+			continue
+		}
+		rule := Rule{}
+		rule.Line = lineNum
+		rule.Pattern = fields[0]
+		rule.Section = section
+		for _, f := range fields[1:] {
+			rule.Owners = append(rule.Owners, parseOwner(f))
+		}
+		if len(rule.Owners) == 0 && section != "" {
+			// gitlab uses section default owners for rules without owners
+			rule.Owners = sectionOwners
+		}
+		re, err := compilePattern(rule.Pattern)
+		if err != nil {
+			return res, fmt.Errorf("codeowners: invalid pattern on line %v: %v", lineNum, err)
+		}
+		rule.re = re
+		res.Rules = append(res.Rules, rule)
+	}
+	if err := scanner.Err(); err != nil {
+		return res, err
+	}
+	return res, nil
+}
+
+// stripComment removes trailing comment, keeping escaped \#
+func stripComment(line string) string {
+	for i := 0; i < len(line); i++ {
+		if line[i] == '#' && (i == 0 || line[i-1] != '\\') {
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// splitFields splits line by whitespace, keeping escaped spaces in pattern
+func splitFields(line string) (res []string) {
+	cur := []byte{}
+	escaped := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case escaped:
+			cur = append(cur, c)
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == ' ' || c == '\t':
+			if len(cur) != 0 {
+				res = append(res, string(cur))
+				cur = []byte{}
+			}
+		default:
+			cur = append(cur, c)
+		}
+	}
+	if len(cur) != 0 {
+		res = append(res, string(cur))
+	}
+	return
+}
+
+// compilePattern converts gitignore style pattern used in CODEOWNERS to regexp.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	p := pattern
+	dirOnly := strings.HasSuffix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	// pattern containing slash at the beginning or in the middle is relative to repo root
+	anchored := strings.Contains(p, "/")
+	p = strings.TrimPrefix(p, "/")
+
+	var b strings.Builder
+	if anchored {
+		b.WriteString("^")
+	} else {
+		b.WriteString("^(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		switch c {
+		case '*':
+			if i+1 < len(p) && p[i+1] == '*' {
+				i++
+				if i+1 < len(p) && p[i+1] == '/' {
+					// **/ matches zero or more dirs
+					i++
+					b.WriteString("(?:.*/)?")
+				} else {
+					b.WriteString(".*")
+				}
+				continue
+			}
+			b.WriteString("[^/]*")
+		case '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if dirOnly {
+		b.WriteString("/.*$")
+	} else if strings.HasSuffix(p, "/*") {
+		// github does not match files in subdirectories for dir/*
+		b.WriteString("$")
+	} else {
+		// pattern matching a dir also matches all files inside it
+		b.WriteString("(?:/.*)?$")
+	}
+	return regexp.Compile(b.String())
+}
+
+// Match returns the rules that define owners for the file. For github syntax this is the last matching rule. For gitlab the last matching rule in each section is used.
+func (f File) Match(filePath string) (res []Rule) {
+	bySection := map[string]int{}
+	for _, r := range f.Rules {
+		if !r.Match(filePath) {
+			continue
+		}
+		if i, ok := bySection[r.Section]; ok {
+			res[i] = r
+			continue
+		}
+		bySection[r.Section] = len(res)
+		res = append(res, r)
+	}
+	return
+}
+
+// Owners returns the owners for the file. Empty if no owners are defined.
+func (f File) Owners(filePath string) (res []Owner) {
+	seen := map[string]bool{}
+	for _, r := range f.Match(filePath) {
+		for _, o := range r.Owners {
+			if seen[o.Value] {
+				continue
+			}
+			seen[o.Value] = true
+			res = append(res, o)
+		}
+	}
+	return
+}
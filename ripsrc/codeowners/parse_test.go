@@ -0,0 +1,113 @@
+package codeowners
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parse(t *testing.T, data string) File {
+	t.Helper()
+	res, err := Parse(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestParseBasic(t *testing.T) {
+	f := parse(t, `# comment
+*       @global-owner1 @global-owner2
+
+*.js    @js-owner # inline comment
+/docs/  docs@example.com
+/build/logs/
+apps/   @org/apps-team
+`)
+	assert.Len(t, f.Rules, 5)
+
+	r := f.Rules[0]
+	assert.Equal(t, 2, r.Line)
+	assert.Equal(t, "*", r.Pattern)
+	assert.Equal(t, []Owner{{"@global-owner1", OwnerUser}, {"@global-owner2", OwnerUser}}, r.Owners)
+
+	assert.Equal(t, []Owner{{"@js-owner", OwnerUser}}, f.Rules[1].Owners)
+	assert.Equal(t, []Owner{{"docs@example.com", OwnerEmail}}, f.Rules[2].Owners)
+	assert.Empty(t, f.Rules[3].Owners)
+	assert.Equal(t, []Owner{{"@org/apps-team", OwnerTeam}}, f.Rules[4].Owners)
+}
+
+func TestParseGitlabSections(t *testing.T) {
+	f := parse(t, `* @default
+[Docs] @docs-team
+*.md
+/docs/guide.md @writer
+^[Optional][2]
+*.go @go-owner
+`)
+	assert.Len(t, f.Rules, 4)
+	assert.Equal(t, "", f.Rules[0].Section)
+	assert.Equal(t, "Docs", f.Rules[1].Section)
+	assert.Equal(t, []Owner{{"@docs-team", OwnerUser}}, f.Rules[1].Owners)
+	assert.Equal(t, []Owner{{"@writer", OwnerUser}}, f.Rules[2].Owners)
+	assert.Equal(t, "Optional", f.Rules[3].Section)
+
+	assert.Equal(t, []Owner{{"@default", OwnerUser}, {"@writer", OwnerUser}}, f.Owners("docs/guide.md"))
+	assert.Equal(t, []Owner{{"@default", OwnerUser}, {"@go-owner", OwnerUser}}, f.Owners("main.go"))
+}
+
+func TestParseEscaped(t *testing.T) {
+	f := parse(t, `\#file\ with\ spaces @owner`)
+	assert.Equal(t, "#file with spaces", f.Rules[0].Pattern)
+	assert.True(t, f.Rules[0].Match("#file with spaces"))
+}
+
+func TestPatternMatch(t *testing.T) {
+	cases := []struct {
+		Pattern string
+		Path    string
+		Match   bool
+	}{
+		{"*", "a.go", true},
+		{"*", "dir/a.go", true},
+		{"*.js", "a.js", true},
+		{"*.js", "dir/sub/a.js", true},
+		{"*.js", "a.go", false},
+		{"/docs/", "docs/a.md", true},
+		{"/docs/", "docs/sub/a.md", true},
+		{"/docs/", "other/docs/a.md", false},
+		{"docs/", "other/docs/a.md", true},
+		{"docs/*", "docs/a.md", true},
+		{"docs/*", "docs/sub/a.md", false},
+		{"docs/*", "other/docs/a.md", false},
+		{"/build/logs/", "build/logs/a.log", true},
+		{"apps", "x/apps/a.go", true},
+		{"apps", "x/apps", true},
+		{"**/logs", "a/b/logs/x.log", true},
+		{"**/logs", "logs/x.log", true},
+		{"/src/**/*.go", "src/a/b/c.go", true},
+		{"/src/**/*.go", "src/c.go", true},
+		{"/src/**/*.go", "src/c.js", false},
+		{"a?.go", "ab.go", true},
+		{"a?.go", "a/.go", false},
+		{"main.go", "main.go", true},
+		{"main.go", "main_go", false},
+	}
+	for _, c := range cases {
+		f := parse(t, c.Pattern+" @o")
+		if got := f.Rules[0].Match(c.Path); got != c.Match {
+			t.Errorf("pattern %v path %v wanted match %v got %v", c.Pattern, c.Path, c.Match, got)
+		}
+	}
+}
+
+func TestMatchLastWins(t *testing.T) {
+	f := parse(t, `*  @all
+*.go @gopher
+/vendor/
+`)
+	assert.Equal(t, []Owner{{"@gopher", OwnerUser}}, f.Owners("main.go"))
+	assert.Equal(t, []Owner{{"@all", OwnerUser}}, f.Owners("README.md"))
+	assert.Empty(t, f.Owners("vendor/a.go"))
+}
@@ -0,0 +1,224 @@
+package codeowners
+
+import (
+	"sort"
+	"strings"
+)
+
+// Opts controls mapping of CODEOWNERS entries to blame authors.
+type Opts struct {
+	// Aliases maps owner as written in CODEOWNERS (@user, @org/team or email) to author emails used in commits.
+	// Owners without an alias are matched by email, by email local part or by author name.
+	Aliases map[string][]string
+
+	// SuggestDepth is the directory depth used when generating suggested CODEOWNERS. Default is 1.
+	SuggestDepth int
+
+	// SuggestMinShare is the minimum share of lines (0-1) an author needs in a directory to be suggested as owner. Default is 0.1.
+	SuggestMinShare float64
+
+	// SuggestMaxOwners is the max number of owners suggested per directory. Default is 3.
+	SuggestMaxOwners int
+}
+
+// Author identifies the author of blame lines.
+type Author struct {
+	Name  string
+	Email string
+}
+
+// FileAuthors contains the number of lines by author for one file.
+type FileAuthors struct {
+	Path    string
+	Authors map[Author]int
+}
+
+// Lines returns the total number of lines in file.
+func (s FileAuthors) Lines() (res int) {
+	for _, c := range s.Authors {
+		res += c
+	}
+	return
+}
+
+// AuthorLines is the number of lines written by author.
+type AuthorLines struct {
+	Author
+	Lines int
+}
+
+// Report is the result of validating CODEOWNERS against blame data.
+type Report struct {
+	// Commit is the commit that was used for both CODEOWNERS file and blame.
+	Commit string
+	// Source is the location of CODEOWNERS file. Empty if repo does not have one.
+	Source string
+	// Rules contains stats for each rule in CODEOWNERS.
+	Rules []RuleReport
+	// StaleOwners are owners that do not have any lines in the files they own.
+	StaleOwners []string
+	// UnresolvedOwners are owners that could not be mapped to commit authors, usually teams without Opts.Aliases entry.
+	UnresolvedOwners []string
+	// UnownedFiles are files without any owner.
+	UnownedFiles []string
+	// Files is the total number of files.
+	Files int
+	// Lines is the total number of lines.
+	Lines int
+}
+
+// RuleReport contains files and authors for which rule defines the owners.
+type RuleReport struct {
+	Rule Rule
+	// Files is the number of files owned by the rule.
+	Files int
+	// Lines is the number of lines in these files.
+	Lines int
+	// OwnerLines is the number of lines written by one of the listed owners.
+	OwnerLines int
+	// Authors of the lines sorted by number of lines desc.
+	Authors []AuthorLines
+}
+
+// Stale returns true if rule does not match any files.
+func (s RuleReport) Stale() bool {
+	return s.Files == 0
+}
+
+// Analyze maps CODEOWNERS rules to file authors.
+func Analyze(file File, files []FileAuthors, opts Opts) (res Report) {
+	type ruleData struct {
+		files   int
+		authors map[Author]int
+	}
+	rules := make([]ruleData, len(file.Rules))
+	ruleInd := map[int]int{}
+	for i, r := range file.Rules {
+		ruleInd[r.Line] = i
+		rules[i].authors = map[Author]int{}
+	}
+
+	// lines written by the owner in the files they own
+	ownerLines := map[string]int{}
+	ownerResolved := map[string]bool{}
+
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].Path < files[j].Path
+	})
+
+	for _, f := range files {
+		res.Files++
+		res.Lines += f.Lines()
+		matched := file.Match(f.Path)
+		hasOwners := false
+		for _, r := range matched {
+			d := &rules[ruleInd[r.Line]]
+			d.files++
+			for a, c := range f.Authors {
+				d.authors[a] += c
+			}
+			if len(r.Owners) != 0 {
+				hasOwners = true
+			}
+			for _, o := range r.Owners {
+				if _, ok := ownerLines[o.Value]; !ok {
+					ownerLines[o.Value] = 0
+				}
+				for a, c := range f.Authors {
+					if ownerMatchesAuthor(o, a, opts) {
+						ownerLines[o.Value] += c
+					}
+				}
+			}
+		}
+		if !hasOwners {
+			res.UnownedFiles = append(res.UnownedFiles, f.Path)
+		}
+	}
+
+	for _, r := range file.Rules {
+		for _, o := range r.Owners {
+			if _, ok := ownerLines[o.Value]; !ok {
+				// owner of rules that do not match anything
+				ownerLines[o.Value] = 0
+			}
+			if o.Kind != OwnerTeam || len(opts.Aliases[o.Value]) != 0 {
+				ownerResolved[o.Value] = true
+			}
+		}
+	}
+
+	for i, r := range file.Rules {
+		d := rules[i]
+		rr := RuleReport{}
+		rr.Rule = r
+		rr.Files = d.files
+		for a, c := range d.authors {
+			rr.Lines += c
+			rr.Authors = append(rr.Authors, AuthorLines{Author: a, Lines: c})
+			for _, o := range r.Owners {
+				if ownerMatchesAuthor(o, a, opts) {
+					rr.OwnerLines += c
+					break
+				}
+			}
+		}
+		sortAuthorLines(rr.Authors)
+		res.Rules = append(res.Rules, rr)
+	}
+
+	for o, c := range ownerLines {
+		if !ownerResolved[o] {
+			res.UnresolvedOwners = append(res.UnresolvedOwners, o)
+			continue
+		}
+		if c == 0 {
+			res.StaleOwners = append(res.StaleOwners, o)
+		}
+	}
+	sort.Strings(res.StaleOwners)
+	sort.Strings(res.UnresolvedOwners)
+	return
+}
+
+func sortAuthorLines(arr []AuthorLines) {
+	sort.Slice(arr, func(i, j int) bool {
+		a := arr[i]
+		b := arr[j]
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		return a.Email < b.Email
+	})
+}
+
+func ownerMatchesAuthor(o Owner, a Author, opts Opts) bool {
+	email := strings.ToLower(a.Email)
+	if aliases, ok := opts.Aliases[o.Value]; ok {
+		for _, al := range aliases {
+			if strings.ToLower(al) == email {
+				return true
+			}
+		}
+		return false
+	}
+	switch o.Kind {
+	case OwnerEmail:
+		return strings.ToLower(o.Value) == email
+	case OwnerUser:
+		handle := strings.ToLower(strings.TrimPrefix(o.Value, "@"))
+		if i := strings.Index(email, "@"); i > 0 {
+			local := email[:i]
+			// github noreply emails are in form 123+user@users.noreply.github.com
+			if j := strings.Index(local, "+"); j >= 0 {
+				local = local[j+1:]
+			}
+			if local == handle {
+				return true
+			}
+		}
+		name := strings.ToLower(strings.Replace(a.Name, " ", "", -1))
+		return name == handle
+	}
+	return false
+}
@@ -0,0 +1,105 @@
+package codeowners
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	alice = Author{Name: "Alice", Email: "alice@example.com"}
+	bob   = Author{Name: "Bob Smith", Email: "12345+bobs@users.noreply.github.com"}
+	carol = Author{Name: "Carol", Email: "carol@example.com"}
+)
+
+func testFiles() []FileAuthors {
+	return []FileAuthors{
+		{Path: "main.go", Authors: map[Author]int{alice: 10, bob: 2}},
+		{Path: "docs/a.md", Authors: map[Author]int{carol: 5}},
+		{Path: "tools/t.go", Authors: map[Author]int{bob: 8}},
+		{Path: "tools/u.go", Authors: map[Author]int{bob: 1, alice: 1}},
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	f := parse(t, `*.go alice@example.com @dave
+/docs/ @bobs @org/writers
+/legacy/ @carol
+`)
+	rep := Analyze(f, testFiles(), Opts{})
+
+	assert.Equal(t, 4, rep.Files)
+	assert.Equal(t, 27, rep.Lines)
+	assert.Len(t, rep.Rules, 3)
+
+	r := rep.Rules[0]
+	assert.Equal(t, 3, r.Files)
+	assert.Equal(t, 22, r.Lines)
+	assert.Equal(t, 11, r.OwnerLines)
+	assert.Equal(t, []AuthorLines{{bob, 11}, {alice, 11}}, r.Authors)
+
+	r = rep.Rules[1]
+	assert.Equal(t, 1, r.Files)
+	assert.Equal(t, 0, r.OwnerLines)
+
+	assert.True(t, rep.Rules[2].Stale())
+
+	assert.Equal(t, []string{"@bobs", "@carol", "@dave"}, rep.StaleOwners)
+	assert.Equal(t, []string{"@org/writers"}, rep.UnresolvedOwners)
+	assert.Empty(t, rep.UnownedFiles)
+}
+
+func TestAnalyzeAliasesAndUnowned(t *testing.T) {
+	f := parse(t, `/docs/ @org/writers
+/tools/ @bobs
+`)
+	opts := Opts{}
+	opts.Aliases = map[string][]string{
+		"@org/writers": {"Carol@example.com"},
+	}
+	rep := Analyze(f, testFiles(), opts)
+
+	assert.Equal(t, 5, rep.Rules[0].OwnerLines)
+	assert.Equal(t, 9, rep.Rules[1].OwnerLines)
+	assert.Empty(t, rep.StaleOwners)
+	assert.Empty(t, rep.UnresolvedOwners)
+	assert.Equal(t, []string{"main.go"}, rep.UnownedFiles)
+}
+
+func TestAnalyzeMatchByName(t *testing.T) {
+	f := parse(t, `* @bobsmith`)
+	rep := Analyze(f, testFiles(), Opts{})
+	assert.Equal(t, 11, rep.Rules[0].OwnerLines)
+}
+
+func TestSuggest(t *testing.T) {
+	got := Suggest("c1", testFiles(), Opts{})
+	want := `# Generated by ripsrc based on blame at commit c1
+* 12345+bobs@users.noreply.github.com alice@example.com carol@example.com
+/docs/ carol@example.com
+/main.go alice@example.com 12345+bobs@users.noreply.github.com
+/tools/ 12345+bobs@users.noreply.github.com alice@example.com
+`
+	assert.Equal(t, want, string(got))
+
+	// generated file should be parsable and assign main.go to alice
+	f := parse(t, string(got))
+	assert.Equal(t, "alice@example.com", f.Owners("main.go")[0].Value)
+}
+
+func TestSuggestDepth(t *testing.T) {
+	files := []FileAuthors{
+		{Path: "a/b/c.go", Authors: map[Author]int{alice: 1}},
+		{Path: "a/d.go", Authors: map[Author]int{bob: 1}},
+	}
+	opts := Opts{}
+	opts.SuggestDepth = 2
+	opts.SuggestMinShare = 0.5
+	got := Suggest("c1", files, opts)
+	want := `# Generated by ripsrc based on blame at commit c1
+* 12345+bobs@users.noreply.github.com alice@example.com
+/a/b/ alice@example.com
+/a/d.go 12345+bobs@users.noreply.github.com
+`
+	assert.Equal(t, want, string(got))
+}
@@ -0,0 +1,100 @@
+package codeowners
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Suggest generates CODEOWNERS file content based on the authors of the lines. Owners are listed by email, which is supported by both github and gitlab.
+func Suggest(commit string, files []FileAuthors, opts Opts) []byte {
+	if opts.SuggestDepth == 0 {
+		opts.SuggestDepth = 1
+	}
+	if opts.SuggestMinShare == 0 {
+		opts.SuggestMinShare = 0.1
+	}
+	if opts.SuggestMaxOwners == 0 {
+		opts.SuggestMaxOwners = 3
+	}
+
+	all := map[Author]int{}
+	byPattern := map[string]map[Author]int{}
+	for _, f := range files {
+		p := suggestPattern(f.Path, opts.SuggestDepth)
+		if _, ok := byPattern[p]; !ok {
+			byPattern[p] = map[Author]int{}
+		}
+		for a, c := range f.Authors {
+			byPattern[p][a] += c
+			all[a] += c
+		}
+	}
+
+	var patterns []string
+	for p := range byPattern {
+		patterns = append(patterns, p)
+	}
+	sort.Strings(patterns)
+
+	buf := bytes.NewBuffer(nil)
+	fmt.Fprintf(buf, "# Generated by ripsrc based on blame at commit %v\n", commit)
+	if owners := suggestOwners(all, opts); len(owners) != 0 {
+		// default owners, more specific rules below take precedence
+		fmt.Fprintf(buf, "* %v\n", strings.Join(owners, " "))
+	}
+	for _, p := range patterns {
+		owners := suggestOwners(byPattern[p], opts)
+		if len(owners) == 0 {
+			continue
+		}
+		fmt.Fprintf(buf, "%v %v\n", escapePattern(p), strings.Join(owners, " "))
+	}
+	return buf.Bytes()
+}
+
+func suggestPattern(filePath string, depth int) string {
+	parts := strings.Split(filePath, "/")
+	if len(parts) <= depth {
+		// file is at a lower depth, use it directly
+		return "/" + filePath
+	}
+	return "/" + strings.Join(parts[:depth], "/") + "/"
+}
+
+func suggestOwners(authors map[Author]int, opts Opts) (res []string) {
+	total := 0
+	byEmail := map[string]int{}
+	for a, c := range authors {
+		total += c
+		if a.Email == "" {
+			continue
+		}
+		byEmail[strings.ToLower(a.Email)] += c
+	}
+	if total == 0 {
+		return nil
+	}
+	var arr []AuthorLines
+	for e, c := range byEmail {
+		arr = append(arr, AuthorLines{Author: Author{Email: e}, Lines: c})
+	}
+	sortAuthorLines(arr)
+	for _, a := range arr {
+		if len(res) == opts.SuggestMaxOwners {
+			break
+		}
+		if float64(a.Lines)/float64(total) < opts.SuggestMinShare {
+			break
+		}
+		res = append(res, a.Email)
+	}
+	return
+}
+
+func escapePattern(p string) string {
+	p = strings.Replace(p, " ", "\\ ", -1)
+	p = strings.Replace(p, "#", "\\#", -1)
+	return p
+}
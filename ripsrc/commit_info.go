@@ -2,14 +2,25 @@ package ripsrc
 
 import (
 	"context"
+	"os"
+	"sort"
+	"time"
 
 	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
+	"github.com/pinpt/ripsrc/ripsrc/parentsgraph"
 )
 
 func (s *Ripsrc) getCommitInfo(ctx context.Context, wantedBranchRefs []string) error {
 	copts := commitmeta.Opts{}
 	copts.CommitFromIncl = s.opts.CommitFromIncl
 	copts.CommitFromMakeNonIncl = s.opts.CommitFromMakeNonIncl
+	if s.opts.CommitFromIncl != "" && !s.opts.CommitFromMakeNonIncl {
+		heads, err := s.checkpointProcess().ResumeHeads(s.opts.CommitFromIncl)
+		if err != nil {
+			return err
+		}
+		copts.ResumeHeads = heads
+	}
 	copts.AllBranches = s.opts.AllBranches
 	copts.WantedBranchRefs = wantedBranchRefs
 	cm := commitmeta.New(s.opts.RepoDir, copts)
@@ -20,3 +31,86 @@ func (s *Ripsrc) getCommitInfo(ctx context.Context, wantedBranchRefs []string) e
 	s.commitMeta = res
 	return nil
 }
+
+// updateCommitMetaCache loads commit meta kept from previous runs, adds commits read in this run and removes commits no longer in commit graph. Incremental runs read only meta of new commits, cache has the older commits needed for blame lines. Commits missing from cache, for example on the first run or on other branches, are read from git without files.
+func (s *Ripsrc) updateCommitMetaCache() error {
+	start := time.Now()
+	loc := s.cacheLoc("commit-meta")
+	cache, err := commitmeta.LoadCache(loc)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			s.opts.Logger.Info("could not load commit meta cache, reading all commits", "err", err)
+		}
+		cache = commitmeta.NewCache()
+	}
+	for _, c := range s.commitMeta {
+		cache.Add(c)
+	}
+	removed := cache.Retain(func(sha string) bool {
+		_, ok := s.commitGraph.Parents[sha]
+		return ok
+	})
+	missing := map[string]bool{}
+	for sha := range s.commitGraph.Parents {
+		if _, ok := cache.Commits[sha]; !ok {
+			missing[sha] = true
+		}
+	}
+	if len(missing) != 0 {
+		cm := commitmeta.New(s.opts.RepoDir, commitmeta.Opts{
+			Revs:    revsForCommits(s.commitGraph, missing),
+			NoFiles: true,
+		})
+		res, err := cm.RunSlice()
+		if err != nil {
+			return err
+		}
+		for _, c := range res {
+			cache.Add(c)
+		}
+	}
+	err = cache.Save(loc)
+	if err != nil {
+		return err
+	}
+	s.commitMetaCache = cache
+	s.opts.Logger.Info("updated commit meta cache", "commits", len(cache.Commits), "read", len(missing), "removed", removed, "d", time.Since(start))
+	return nil
+}
+
+// revsForCommits returns git log revs selecting commits in set. Includes commits without children in set and excludes their parents outside of set.
+func revsForCommits(graph *parentsgraph.Graph, commits map[string]bool) (res []string) {
+	excluded := map[string]bool{}
+	for commit := range commits {
+		tip := true
+		for _, ch := range graph.Children[commit] {
+			if commits[ch] {
+				tip = false
+				break
+			}
+		}
+		if tip {
+			res = append(res, commit)
+		}
+		for _, p := range graph.Parents[commit] {
+			if !commits[p] && !excluded[p] {
+				excluded[p] = true
+				res = append(res, "^"+p)
+			}
+		}
+	}
+	// sorted for the same git command on the same data
+	sort.Strings(res)
+	return
+}
+
+// lineCommit returns commit meta for blame line. Lines could be from commits processed in previous runs, these are only in cache.
+func (s *Ripsrc) lineCommit(sha string) Commit {
+	if c, ok := s.commitMeta[sha]; ok {
+		return c
+	}
+	if s.commitMetaCache == nil {
+		return Commit{}
+	}
+	return s.commitMetaCache.Commits[sha]
+}
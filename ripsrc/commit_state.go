@@ -0,0 +1,164 @@
+package ripsrc
+
+import (
+	"context"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc/codeage"
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo"
+)
+
+// commitState is the state of the whole repo after commit, used for totals returned for each commit.
+type commitState struct {
+	// tree has blame data of all files, used to find files that differ from the parent
+	tree  *repo.Tree
+	stats *repoStats
+	age   *codeage.Tracker
+}
+
+func (s *Ripsrc) newCommitState() *commitState {
+	res := &commitState{}
+	res.tree = repo.NewTree()
+	if s.opts.RepoStatsByCommit {
+		res.stats = newRepoStats(s.opts.BotLines == BotLinesExclude)
+	}
+	if s.opts.CodeAgeByCommit {
+		res.age = codeage.NewTracker(s.opts.CodeAgeBuckets)
+	}
+	return res
+}
+
+func (s *commitState) copy() *commitState {
+	res := &commitState{}
+	res.tree = s.tree
+	if s.stats != nil {
+		res.stats = s.stats.copy()
+	}
+	if s.age != nil {
+		res.age = s.age.Copy()
+	}
+	return res
+}
+
+func (s *commitState) set(r BlameResult) {
+	if s.stats != nil {
+		s.stats.Set(r)
+	}
+	if s.age != nil {
+		// skipped files do not have lines and are not counted
+		dates := make([]time.Time, 0, len(r.Lines))
+		for _, l := range r.Lines {
+			dates = append(dates, l.Date)
+		}
+		s.age.Set(r.Filename, dates)
+	}
+}
+
+func (s *commitState) remove(filePath string) {
+	if s.stats != nil {
+		s.stats.Remove(filePath)
+	}
+	if s.age != nil {
+		s.age.Remove(filePath)
+	}
+}
+
+// updateCommitState returns the repo state after commit. State is forked from the first parent and updated with files that differ from it, so totals are exact for branches and merges. When the parent was processed in previous run, state is built from all files in commit.
+//
+// States are kept until all children of commit are processed.
+func (s *Ripsrc) updateCommitState(ctx context.Context, commit Commit, tree *repo.Tree, rs []BlameResult, subs []SubmoduleChange) (*commitState, error) {
+	if s.commitStates == nil {
+		s.commitStates = map[string]*commitState{}
+		s.commitStatesChildren = map[string]int{}
+	}
+	parents := s.commitGraph.Parents[commit.SHA]
+	var st *commitState
+	if len(parents) != 0 {
+		st = s.forkCommitState(parents[0])
+	}
+	// submodules are known from 160000 mode for files changed compared to the first parent, files from other parents of merges are checked by content
+	submodules := map[string]bool{}
+	for _, sub := range subs {
+		submodules[sub.Path] = true
+	}
+	checkContent := len(parents) > 1
+	if st == nil {
+		st = s.newCommitState()
+		// state is built from all files in commit, so get modes from tree
+		paths, err := s.submodulePaths(ctx, commit.SHA)
+		if err != nil {
+			return nil, err
+		}
+		for fp := range paths {
+			submodules[fp] = true
+		}
+		checkContent = false
+	}
+
+	// blame results for changed files are already calculated, other files are in merges only
+	results := map[string]BlameResult{}
+	for _, r := range rs {
+		results[r.Filename] = r
+	}
+	var rerr error
+	st.tree.Diff(tree, func(filePath string, _, bl *incblame.Blame) {
+		if rerr != nil || filePath == "" {
+			return
+		}
+		if bl == nil {
+			st.remove(filePath)
+			return
+		}
+		if submodules[filePath] {
+			// could replace a file with the same path
+			st.remove(filePath)
+			return
+		}
+		r, ok := results[filePath]
+		if !ok {
+			if _, ok := submoduleCommit(bl); ok && checkContent {
+				return
+			}
+			r = BlameResult{Commit: commit, Filename: filePath, Status: GitFileCommitStatusModified}
+			r, rerr = s.codeInfoBlame(filePath, bl, r)
+			if rerr != nil {
+				return
+			}
+		}
+		if r.Status == GitFileCommitStatusRemoved {
+			st.remove(filePath)
+			return
+		}
+		st.set(r)
+	})
+	if rerr != nil {
+		return nil, rerr
+	}
+	st.tree = tree
+
+	if len(s.commitGraph.Children[commit.SHA]) != 0 {
+		s.commitStates[commit.SHA] = st
+	}
+	for _, p := range parents {
+		s.commitStatesChildren[p]++
+		if s.commitStatesChildren[p] >= len(s.commitGraph.Children[p]) {
+			delete(s.commitStates, p)
+			delete(s.commitStatesChildren, p)
+		}
+	}
+	return st, nil
+}
+
+// forkCommitState returns state of parent to be updated by its child. State is copied if parent has other children not processed yet. Returns nil if parent state is not available.
+func (s *Ripsrc) forkCommitState(parent string) *commitState {
+	st, ok := s.commitStates[parent]
+	if !ok {
+		return nil
+	}
+	if s.commitStatesChildren[parent]+1 >= len(s.commitGraph.Children[parent]) {
+		// last child, no need to copy
+		return st
+	}
+	return st.copy()
+}
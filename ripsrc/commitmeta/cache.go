@@ -0,0 +1,97 @@
+package commitmeta
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc/pkg/msgpfile"
+)
+
+// storedCacheVersion is increased on incompatible changes, cache with other version is not loaded
+const storedCacheVersion = 1
+
+// Cache keeps commit meta between runs, so that incremental runs have authors and dates for blame lines of commits processed before. Only commit header is kept, Files, Parents and Ordinal are empty.
+type Cache struct {
+	Commits map[string]Commit
+}
+
+// NewCache creates empty cache.
+func NewCache() *Cache {
+	s := &Cache{}
+	s.Commits = map[string]Commit{}
+	return s
+}
+
+// LoadCache reads cache stored at loc. Returns error satisfying os.IsNotExist if there is no cache.
+func LoadCache(loc string) (*Cache, error) {
+	st := &storedCache{}
+	err := msgpfile.Load(loc, st)
+	if err != nil {
+		return nil, err
+	}
+	if st.Version != storedCacheVersion {
+		return nil, fmt.Errorf("unsupported commit meta cache version %v", st.Version)
+	}
+	s := NewCache()
+	for _, c := range st.Commits {
+		s.Commits[c.SHA] = Commit{
+			SHA:            c.SHA,
+			AuthorName:     c.AuthorName,
+			AuthorEmail:    c.AuthorEmail,
+			CommitterName:  c.CommitterName,
+			CommitterEmail: c.CommitterEmail,
+			Date:           time.Unix(c.Date, 0).In(time.FixedZone("", c.DateOffset)),
+			Message:        c.Message,
+		}
+	}
+	return s, nil
+}
+
+// Add adds commit header to cache.
+func (s *Cache) Add(c Commit) {
+	s.Commits[c.SHA] = Commit{
+		SHA:            c.SHA,
+		AuthorName:     c.AuthorName,
+		AuthorEmail:    c.AuthorEmail,
+		CommitterName:  c.CommitterName,
+		CommitterEmail: c.CommitterEmail,
+		Date:           c.Date,
+		Message:        c.Message,
+	}
+}
+
+// Retain removes commits for which keep returns false, for example commits no longer in repo after history was rewritten. Returns the number of removed commits.
+func (s *Cache) Retain(keep func(sha string) bool) (removed int) {
+	for sha := range s.Commits {
+		if !keep(sha) {
+			delete(s.Commits, sha)
+			removed++
+		}
+	}
+	return
+}
+
+// Save writes cache to loc.
+func (s *Cache) Save(loc string) error {
+	st := &storedCache{}
+	st.Version = storedCacheVersion
+	for _, c := range s.Commits {
+		_, offset := c.Date.Zone()
+		st.Commits = append(st.Commits, storedCommit{
+			SHA:            c.SHA,
+			AuthorName:     c.AuthorName,
+			AuthorEmail:    c.AuthorEmail,
+			CommitterName:  c.CommitterName,
+			CommitterEmail: c.CommitterEmail,
+			Date:           c.Date.Unix(),
+			DateOffset:     offset,
+			Message:        c.Message,
+		})
+	}
+	// sorted for the same output on the same data
+	sort.Slice(st.Commits, func(i, j int) bool {
+		return st.Commits[i].SHA < st.Commits[j].SHA
+	})
+	return msgpfile.Save(loc, st)
+}
@@ -24,17 +24,27 @@ type Opts struct {
 	// CommitFromMakeNonIncl by default we start from passed commit and include it. Set CommitFromMakeNonIncl to true to avoid returning it, and skipping reading/writing checkpoint.
 	CommitFromMakeNonIncl bool
 
+	// ResumeHeads are the commits processed again together with CommitFromIncl when resuming from checkpoint, heads of branches that were not merged when checkpoint was written. Defaults to CommitFromIncl.
+	ResumeHeads []string
+
 	// WantedBranchRefs filter branches.  When CommitFromIncl and AllBranches is set this is required.
 	WantedBranchRefs []string
 
 	// AllBranches set to true to process all branches. If false, processes commits reachable from HEAD only.
 	AllBranches bool
+
+	// Revs are passed to git log instead of the whole history or the range starting from CommitFromIncl, for example commits to include and ^commits to exclude. Optional.
+	Revs []string
+
+	// NoFiles set to true to skip reading changed files, Commit.Files is empty. Much faster for large histories when only authors and dates are needed.
+	NoFiles bool
 }
 
 type Processor struct {
 	repoDir    string
 	gitCommand string
 	opts       Opts
+	log        io.ReadCloser
 }
 
 func New(repoDir string, opts Opts) *Processor {
@@ -46,6 +56,15 @@ func New(repoDir string, opts Opts) *Processor {
 	return s
 }
 
+// NewFromLog creates processor parsing git log output from r instead of running git log. Output must be in the same format as git log run by Processor, see LogHeader. Used when one git log pass is shared with other consumers. Processor closes r.
+func NewFromLog(repoDir string, r io.ReadCloser) *Processor {
+	s := &Processor{
+		repoDir: repoDir,
+		log:     r,
+	}
+	return s
+}
+
 // Commit is a specific detail around a commit
 type Commit struct {
 	//Dir            string
@@ -55,7 +74,8 @@ type Commit struct {
 	CommitterName  string
 	CommitterEmail string
 
-	Date    time.Time
+	Date time.Time
+	// Ordinal is the position of commit in processing order (git log --date-order --reverse), starting from 1.
 	Ordinal int64
 	Message string
 
@@ -85,6 +105,12 @@ type CommitFile struct {
 	Additions   int
 	Deletions   int
 	Binary      bool
+	// Submodule is true if this is a submodule entry (gitlink).
+	Submodule bool
+	// SubmoduleFrom is the submodule commit before change. Empty for added submodules.
+	SubmoduleFrom string
+	// SubmoduleTo is the submodule commit after change. Empty for removed submodules.
+	SubmoduleTo string
 }
 
 // CommitStatus is a commit status type
@@ -134,9 +160,13 @@ func (s *Processor) RunMap() (map[string]Commit, error) {
 
 func (s *Processor) Run(res chan Commit) error {
 	defer close(res)
-	r, err := s.gitLog()
-	if err != nil {
-		return err
+	r := s.log
+	if r == nil {
+		var err error
+		r, err = s.gitLog()
+		if err != nil {
+			return err
+		}
 	}
 	defer r.Close()
 
@@ -186,26 +216,40 @@ func (s *Processor) gitLog() (io.ReadCloser, error) {
 		"-c", "core.attributesFile=" + f.Name(),
 		"-c", "diff.renameLimit=10000",
 		"log",
-		"-c",
-		"--raw",
+	}
+	if !s.opts.NoFiles {
+		args = append(args, "-c", "--raw", "--numstat")
+	}
+	// same order as history3 process and singlepass, so that Ordinal follows processing order
+	args = append(args,
+		"--date-order",
 		"--reverse",
-		"--numstat",
+		"--no-abbrev",
 		"--pretty=format:!SHA: %H%n!Parents: %P%n!Committer: %ce%n!CName: %cn%n!Author: %ae%n!AName: %an%n!Date: %aI%n!Message: %s%n",
-	}
+	)
 
-	if s.opts.CommitFromIncl != "" {
+	if len(s.opts.Revs) != 0 {
+		args = append(args, s.opts.Revs...)
+		args = append(args, "--")
+	} else if s.opts.CommitFromIncl != "" {
 		if s.opts.AllBranches {
 			for _, c := range s.opts.WantedBranchRefs {
 				args = append(args, c)
 			}
 		}
-		pf := ""
 		if s.opts.CommitFromMakeNonIncl {
-			pf = "..HEAD"
+			args = append(args, s.opts.CommitFromIncl+"..HEAD")
 		} else {
-			pf = "^..HEAD"
+			// exclude all parents instead of using <commit>^..HEAD, which for merge commit includes commits from other parents that were already processed, and fails for root commit
+			heads := s.opts.ResumeHeads
+			if len(heads) == 0 {
+				heads = []string{s.opts.CommitFromIncl}
+			}
+			args = append(args, "HEAD")
+			for _, h := range heads {
+				args = append(args, "^"+h+"^@")
+			}
 		}
-		args = append(args, s.opts.CommitFromIncl+pf)
 	} else {
 		if s.opts.AllBranches {
 			args = append(args, "--all")
@@ -215,6 +259,24 @@ func (s *Processor) gitLog() (io.ReadCloser, error) {
 	return gitexec.ExecPiped(context.Background(), s.gitCommand, s.repoDir, args)
 }
 
+// LogHeader returns commit header in the format of git log run by Processor. Used to convert other git log output for NewFromLog, header is followed by --raw and --numstat lines of the commit and an empty line.
+func LogHeader(c Commit) string {
+	res := []string{
+		string(commitPrefix) + c.SHA,
+		string(parentsPrefix) + strings.Join(c.Parents, " "),
+		string(committerPrefix) + c.CommitterEmail,
+		string(committerNamePrefix) + c.CommitterName,
+		string(authorPrefix) + c.AuthorEmail,
+		string(authorNamePrefix) + c.AuthorName,
+		string(datePrefix) + c.Date.Format(isoStrictDate),
+		string(messagePrefix) + c.Message,
+	}
+	return strings.Join(res, "\n") + "\n"
+}
+
+// isoStrictDate is the layout of git %aI
+const isoStrictDate = "2006-01-02T15:04:05-07:00"
+
 var (
 	commitPrefix        = []byte("!SHA: ")
 	authorPrefix        = []byte("!Author: ")
@@ -232,6 +294,8 @@ var (
 	copyPrefix          = []byte("C")
 	filenameMask        = regexp.MustCompile("^(100644|100755)$")
 	deletedMask         = []byte("000000")
+	submoduleMask       = []byte("160000")
+	zeroSHA             = []byte("0000000000000000000000000000000000000000")
 	renameRe            = regexp.MustCompile("(.*)\\{(.*) => (.*)\\}(.*)")
 )
 
@@ -247,6 +311,13 @@ func toCommitStatus(name []byte) CommitStatus {
 	panic("unknown commit status: " + string(name))
 }
 
+func submoduleSHA(sha []byte) string {
+	if bytes.Equal(sha, zeroSHA) {
+		return ""
+	}
+	return string(sha)
+}
+
 func parseDate(d string) (time.Time, error) {
 	t, err := time.Parse(time.RFC3339, d)
 	if err != nil {
@@ -413,14 +484,27 @@ func (p *parser) parse(line string) (bool, error) {
 				tok1 := bytes.Split(buf, space)
 				mask := tok1[1]
 				// fmt.Println(p.commit.SHA, line, string(mask))
-				// if the mask isn't a regular file or deleted file, skip it
-				if !filenameMask.Match(mask) && !bytes.Equal(mask, deletedMask) {
+				// combined format for merges (::) is not checked for submodules
+				submodule := tok1[0][1] != ':' && (bytes.Equal(mask, submoduleMask) || bytes.HasSuffix(tok1[0], submoduleMask))
+				// if the mask isn't a regular file, submodule or deleted file, skip it
+				if !filenameMask.Match(mask) && !bytes.Equal(mask, deletedMask) && !submodule {
 					return true, nil
 				}
 				tok2 := bytes.Split(bytes.Join(tok1[4:], space), tab)
 				action := tok2[0]
 				paths := tok2[1:]
-				if len(action) == 1 {
+				if submodule && len(action) == 1 {
+					fn := string(bytes.TrimLeft(paths[0], " "))
+					cf := &CommitFile{
+						Filename:      fn,
+						Status:        toCommitStatus(action),
+						Submodule:     true,
+						SubmoduleFrom: submoduleSHA(tok1[2]),
+						SubmoduleTo:   submoduleSHA(tok1[3]),
+					}
+					p.commit.Files[fn] = cf
+					p.filejobs <- cf
+				} else if len(action) == 1 {
 					fn := string(bytes.TrimLeft(paths[0], " "))
 					cf := &CommitFile{
 						Filename: fn,
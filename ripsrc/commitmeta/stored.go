@@ -0,0 +1,25 @@
+//go:generate msgp -unexported
+
+package commitmeta
+
+// storedCache is the commit meta kept between runs, see Cache.
+type storedCache struct {
+	Version int            `msg:"v"`
+	Commits []storedCommit `msg:"c"`
+}
+
+// storedCommit is encoded as array, since there is one for each commit in repo.
+
+//msgp:tuple storedCommit
+
+type storedCommit struct {
+	SHA            string `msg:"s"`
+	AuthorName     string `msg:"an"`
+	AuthorEmail    string `msg:"ae"`
+	CommitterName  string `msg:"cn"`
+	CommitterEmail string `msg:"ce"`
+	// Date is unix time, with zone offset in seconds stored separately to return the same date as git %aI.
+	Date       int64  `msg:"d"`
+	DateOffset int    `msg:"o"`
+	Message    string `msg:"m"`
+}
@@ -0,0 +1,361 @@
+package commitmeta
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *storedCache) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "v":
+			z.Version, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "Version")
+				return
+			}
+		case "c":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Commits")
+				return
+			}
+			if cap(z.Commits) >= int(zb0002) {
+				z.Commits = (z.Commits)[:zb0002]
+			} else {
+				z.Commits = make([]storedCommit, zb0002)
+			}
+			for za0001 := range z.Commits {
+				err = z.Commits[za0001].DecodeMsg(dc)
+				if err != nil {
+					err = msgp.WrapError(err, "Commits", za0001)
+					return
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *storedCache) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 2
+	// write "v"
+	err = en.Append(0x82, 0xa1, 0x76)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.Version)
+	if err != nil {
+		err = msgp.WrapError(err, "Version")
+		return
+	}
+	// write "c"
+	err = en.Append(0xa1, 0x63)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Commits)))
+	if err != nil {
+		err = msgp.WrapError(err, "Commits")
+		return
+	}
+	for za0001 := range z.Commits {
+		err = z.Commits[za0001].EncodeMsg(en)
+		if err != nil {
+			err = msgp.WrapError(err, "Commits", za0001)
+			return
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *storedCache) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 2
+	// string "v"
+	o = append(o, 0x82, 0xa1, 0x76)
+	o = msgp.AppendInt(o, z.Version)
+	// string "c"
+	o = append(o, 0xa1, 0x63)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Commits)))
+	for za0001 := range z.Commits {
+		o, err = z.Commits[za0001].MarshalMsg(o)
+		if err != nil {
+			err = msgp.WrapError(err, "Commits", za0001)
+			return
+		}
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *storedCache) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "v":
+			z.Version, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Version")
+				return
+			}
+		case "c":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Commits")
+				return
+			}
+			if cap(z.Commits) >= int(zb0002) {
+				z.Commits = (z.Commits)[:zb0002]
+			} else {
+				z.Commits = make([]storedCommit, zb0002)
+			}
+			for za0001 := range z.Commits {
+				bts, err = z.Commits[za0001].UnmarshalMsg(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Commits", za0001)
+					return
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *storedCache) Msgsize() (s int) {
+	s = 1 + 2 + msgp.IntSize + 2 + msgp.ArrayHeaderSize
+	for za0001 := range z.Commits {
+		s += z.Commits[za0001].Msgsize()
+	}
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *storedCommit) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 8 {
+		err = msgp.ArrayError{Wanted: 8, Got: zb0001}
+		return
+	}
+	z.SHA, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "SHA")
+		return
+	}
+	z.AuthorName, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "AuthorName")
+		return
+	}
+	z.AuthorEmail, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "AuthorEmail")
+		return
+	}
+	z.CommitterName, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "CommitterName")
+		return
+	}
+	z.CommitterEmail, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "CommitterEmail")
+		return
+	}
+	z.Date, err = dc.ReadInt64()
+	if err != nil {
+		err = msgp.WrapError(err, "Date")
+		return
+	}
+	z.DateOffset, err = dc.ReadInt()
+	if err != nil {
+		err = msgp.WrapError(err, "DateOffset")
+		return
+	}
+	z.Message, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "Message")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *storedCommit) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 8
+	err = en.Append(0x98)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.SHA)
+	if err != nil {
+		err = msgp.WrapError(err, "SHA")
+		return
+	}
+	err = en.WriteString(z.AuthorName)
+	if err != nil {
+		err = msgp.WrapError(err, "AuthorName")
+		return
+	}
+	err = en.WriteString(z.AuthorEmail)
+	if err != nil {
+		err = msgp.WrapError(err, "AuthorEmail")
+		return
+	}
+	err = en.WriteString(z.CommitterName)
+	if err != nil {
+		err = msgp.WrapError(err, "CommitterName")
+		return
+	}
+	err = en.WriteString(z.CommitterEmail)
+	if err != nil {
+		err = msgp.WrapError(err, "CommitterEmail")
+		return
+	}
+	err = en.WriteInt64(z.Date)
+	if err != nil {
+		err = msgp.WrapError(err, "Date")
+		return
+	}
+	err = en.WriteInt(z.DateOffset)
+	if err != nil {
+		err = msgp.WrapError(err, "DateOffset")
+		return
+	}
+	err = en.WriteString(z.Message)
+	if err != nil {
+		err = msgp.WrapError(err, "Message")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *storedCommit) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 8
+	o = append(o, 0x98)
+	o = msgp.AppendString(o, z.SHA)
+	o = msgp.AppendString(o, z.AuthorName)
+	o = msgp.AppendString(o, z.AuthorEmail)
+	o = msgp.AppendString(o, z.CommitterName)
+	o = msgp.AppendString(o, z.CommitterEmail)
+	o = msgp.AppendInt64(o, z.Date)
+	o = msgp.AppendInt(o, z.DateOffset)
+	o = msgp.AppendString(o, z.Message)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *storedCommit) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 8 {
+		err = msgp.ArrayError{Wanted: 8, Got: zb0001}
+		return
+	}
+	z.SHA, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "SHA")
+		return
+	}
+	z.AuthorName, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "AuthorName")
+		return
+	}
+	z.AuthorEmail, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "AuthorEmail")
+		return
+	}
+	z.CommitterName, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "CommitterName")
+		return
+	}
+	z.CommitterEmail, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "CommitterEmail")
+		return
+	}
+	z.Date, bts, err = msgp.ReadInt64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Date")
+		return
+	}
+	z.DateOffset, bts, err = msgp.ReadIntBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "DateOffset")
+		return
+	}
+	z.Message, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Message")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *storedCommit) Msgsize() (s int) {
+	s = 1 + msgp.StringPrefixSize + len(z.SHA) + msgp.StringPrefixSize + len(z.AuthorName) + msgp.StringPrefixSize + len(z.AuthorEmail) + msgp.StringPrefixSize + len(z.CommitterName) + msgp.StringPrefixSize + len(z.CommitterEmail) + msgp.Int64Size + msgp.IntSize + msgp.StringPrefixSize + len(z.Message)
+	return
+}
@@ -1,6 +1,7 @@
 package tests
 
 import (
+	"os"
 	"testing"
 	"time"
 
@@ -8,7 +9,9 @@ import (
 
 	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
 	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
 	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+	"github.com/pinpt/ripsrc/ripsrc/singlepass"
 )
 
 type Test struct {
@@ -38,6 +41,20 @@ func (s *Test) Run(opts *commitmeta.Opts) []commitmeta.Commit {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// single pass log covers the whole history, check that it returns the same commits
+	if opts.CommitFromIncl == "" {
+		sp, err := singlepass.Run(singlepass.Opts{
+			RepoDir:     dirs.RepoDir,
+			AllBranches: opts.AllBranches,
+			Logger:      logger.NewDefaultLogger(os.Stdout),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sp.Remove()
+		assertCommits(t, res, sp.Commits)
+	}
 	return res
 }
 
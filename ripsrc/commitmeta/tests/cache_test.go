@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+)
+
+func TestNoFilesAndRevs(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("basic")
+	defer dirs.Remove()
+
+	c1 := "b4dadc54e312e976694161c2ac59ab76feb0c40d"
+	c2 := "69ba50fff990c169f80de96674919033a0a9b66d"
+
+	all, err := commitmeta.New(dirs.RepoDir, commitmeta.Opts{}).RunSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := commitmeta.New(dirs.RepoDir, commitmeta.Opts{NoFiles: true}).RunSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []commitmeta.Commit
+	for _, c := range all {
+		c.Files = map[string]*commitmeta.CommitFile{}
+		want = append(want, c)
+	}
+	assertCommits(t, want, got)
+
+	got, err = commitmeta.New(dirs.RepoDir, commitmeta.Opts{Revs: []string{c2, "^" + c1}, NoFiles: true}).RunSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].SHA != c2 {
+		t.Fatalf("wanted only commit %v, got %v", c2, got)
+	}
+}
+
+func TestCache(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("basic")
+	defer dirs.Remove()
+
+	commits, err := commitmeta.New(dirs.RepoDir, commitmeta.Opts{}).RunSlice()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := commitmeta.NewCache()
+	for _, c := range commits {
+		cache.Add(c)
+	}
+	loc := filepath.Join(dirs.TempWrapper, "cache", "commit-meta")
+	err = cache.Save(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := commitmeta.LoadCache(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got.Commits) != len(commits) {
+		t.Fatalf("wanted %v commits, got %v", len(commits), len(got.Commits))
+	}
+	for _, w := range commits {
+		g := got.Commits[w.SHA]
+		assert.Equal(t, w.SHA, g.SHA)
+		assert.Equal(t, w.AuthorName, g.AuthorName)
+		assert.Equal(t, w.AuthorEmail, g.AuthorEmail)
+		assert.Equal(t, w.CommitterName, g.CommitterName)
+		assert.Equal(t, w.CommitterEmail, g.CommitterEmail)
+		assert.Equal(t, w.Message, g.Message)
+		// same as git %aI, including zone
+		assert.Equal(t, w.Date.Format(time.RFC3339), g.Date.Format(time.RFC3339))
+		assert.Nil(t, g.Files)
+		assert.Nil(t, g.Parents)
+	}
+
+	removed := got.Retain(func(sha string) bool {
+		return sha == commits[0].SHA
+	})
+	assert.Equal(t, len(commits)-1, removed)
+	assert.Len(t, got.Commits, 1)
+}
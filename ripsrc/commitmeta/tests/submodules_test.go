@@ -0,0 +1,48 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmodules(t *testing.T) {
+	test := NewTest(t, "submodules")
+	got := test.Run(nil)
+	if len(got) != 5 {
+		t.Fatalf("wanted 5 commits, got %v", len(got))
+	}
+
+	libC1 := "c2686f241779605060e96d8e9318ec5c598c611c"
+	libC2 := "14e1cd9b6d3c513a819f0d8ec792ebb94ee43dcb"
+
+	assert.Equal(t, &commitmeta.CommitFile{
+		Filename:    "lib",
+		Status:      commitmeta.GitFileCommitStatusAdded,
+		Additions:   1,
+		Submodule:   true,
+		SubmoduleTo: libC1,
+	}, got[1].Files["lib"])
+	assert.False(t, got[1].Files[".gitmodules"].Submodule)
+
+	assert.Equal(t, &commitmeta.CommitFile{
+		Filename:      "lib",
+		Status:        commitmeta.GitFileCommitStatusModified,
+		Additions:     1,
+		Deletions:     1,
+		Submodule:     true,
+		SubmoduleFrom: libC1,
+		SubmoduleTo:   libC2,
+	}, got[2].Files["lib"])
+
+	assert.Equal(t, commitmeta.GitFileCommitStatusAdded, got[3].Files["other"].Status)
+
+	assert.Equal(t, &commitmeta.CommitFile{
+		Filename:      "other",
+		Status:        commitmeta.GitFileCommitStatusRemoved,
+		Deletions:     1,
+		Submodule:     true,
+		SubmoduleFrom: libC2,
+	}, got[4].Files["other"])
+}
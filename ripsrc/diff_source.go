@@ -0,0 +1,18 @@
+package ripsrc
+
+import "github.com/pinpt/ripsrc/ripsrc/history3/process"
+
+// DiffSource selects where diffs of processed commits come from.
+type DiffSource = process.DiffSourceType
+
+const (
+	// DiffSourceGit parses git log -p output. Default.
+	DiffSourceGit = process.DiffSourceGit
+	// DiffSourceNative reads trees and blobs from the repo directly and computes line diffs in process.
+	DiffSourceNative = process.DiffSourceNative
+)
+
+// ParseDiffSource returns diff source by name, git or native. Empty string returns DiffSourceGit.
+func ParseDiffSource(s string) (DiffSource, error) {
+	return process.ParseDiffSourceType(s)
+}
@@ -7,13 +7,29 @@ import (
 	enry "gopkg.in/src-d/enry.v1"
 )
 
+type Opts struct {
+	// LicenseMinConfidence is the minimum confidence for license to be detected in license file. Default is DefaultLicenseMinConfidence.
+	LicenseMinConfidence float32
+
+	// CustomLicenses are matched against license files in addition to the known licenses.
+	CustomLicenses []CustomLicense
+}
+
 type Process struct {
 	checkFilePathCache map[string]string
+	licenses           *licenseDetector
 }
 
+// New creates file info processor with default options.
 func New() *Process {
+	return NewWithOpts(Opts{})
+}
+
+// NewWithOpts creates file info processor with custom license detection options.
+func NewWithOpts(opts Opts) *Process {
 	s := &Process{}
 	s.checkFilePathCache = map[string]string{}
+	s.licenses = newLicenseDetector(opts)
 	return s
 }
 
@@ -27,6 +43,7 @@ const (
 	skipBlacklisted          = "File was on an exclusion list"
 	skipVendoredFile         = "File was a vendored file"
 	skipLicense              = "File is a license file"
+	skipLFSPointer           = "File was a git lfs pointer"
 )
 
 type InfoArgs struct {
@@ -36,8 +53,16 @@ type InfoArgs struct {
 }
 
 type Info struct {
-	Language   string
-	License    *License
+	Language string
+	License  *License
+	// DeclaredLicense is the license expression from SPDX-License-Identifier tag in the file header.
+	DeclaredLicense string
+	// LicenseTags are the SPDX-License-Identifier tags found in file.
+	LicenseTags []LicenseTag
+	// Copyrights are the copyright notices found in file.
+	Copyrights []Copyright
+	// LFS is set if file is a git lfs pointer, file is skipped in that case.
+	LFS        *LFSPointer
 	SkipReason string
 }
 
@@ -63,8 +88,16 @@ func (s *Process) GetInfo(args InfoArgs) (res Info, skipReason string) {
 		return res, fmt.Sprintf(skipFileSize, fileSize/1000, maxFileSize/1000)
 	}
 
+	res.LFS = parseLFSPointer(args.Content)
+	if res.LFS != nil {
+		return res, skipLFSPointer
+	}
+
+	res.DeclaredLicense = detectSPDX(args.Lines)
+	res.LicenseTags, res.Copyrights = scanNotices(args.Lines)
+
 	if possibleLicense(args.FilePath) {
-		l, err := detect(args.FilePath, args.Content)
+		l, err := s.licenses.detect(args.FilePath, args.Content)
 		if err != nil {
 			panic(err)
 		}
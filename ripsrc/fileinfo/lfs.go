@@ -0,0 +1,56 @@
+package fileinfo
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// LFSPointer is the reference to file content stored in Git LFS.
+type LFSPointer struct {
+	// Oid is the object id including hash method, for example sha256:4d7a21...
+	Oid string
+	// Size is the size of the stored file in bytes.
+	Size int64
+}
+
+// lfsMaxPointerSize is the max size of pointer file, git lfs does not check larger files
+const lfsMaxPointerSize = 1024
+
+const lfsVersionPrefix = "version https://git-lfs.github.com/spec/"
+
+// parseLFSPointer returns pointer if content is a git lfs pointer file. See https://github.com/git-lfs/git-lfs/blob/master/docs/spec.md
+func parseLFSPointer(content []byte) *LFSPointer {
+	if len(content) > lfsMaxPointerSize || !bytes.HasPrefix(content, []byte(lfsVersionPrefix)) {
+		return nil
+	}
+	res := &LFSPointer{}
+	hasSize := false
+	for i, l := range strings.Split(strings.TrimRight(string(content), "\n"), "\n") {
+		kv := strings.SplitN(l, " ", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil
+		}
+		switch kv[0] {
+		case "version":
+			if i != 0 {
+				return nil
+			}
+		case "oid":
+			res.Oid = kv[1]
+		case "size":
+			size, err := strconv.ParseInt(kv[1], 10, 64)
+			if err != nil || size < 0 {
+				return nil
+			}
+			res.Size = size
+			hasSize = true
+		default:
+			// extensions (ext-0-name) and keys from later spec versions
+		}
+	}
+	if !strings.HasPrefix(res.Oid, "sha256:") || !hasSize {
+		return nil
+	}
+	return res
+}
@@ -0,0 +1,39 @@
+package fileinfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLFSPointer(t *testing.T) {
+	oid := "sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393"
+	cases := []struct {
+		Label string
+		In    string
+		Want  *LFSPointer
+	}{
+		{"pointer", "version https://git-lfs.github.com/spec/v1\noid " + oid + "\nsize 12345\n", &LFSPointer{Oid: oid, Size: 12345}},
+		{"no trailing newline", "version https://git-lfs.github.com/spec/v1\noid " + oid + "\nsize 0", &LFSPointer{Oid: oid, Size: 0}},
+		{"extension", "version https://git-lfs.github.com/spec/v1\next-0-foo sha256:ffff\noid " + oid + "\nsize 10\n", &LFSPointer{Oid: oid, Size: 10}},
+		{"no size", "version https://git-lfs.github.com/spec/v1\noid " + oid + "\n", nil},
+		{"invalid size", "version https://git-lfs.github.com/spec/v1\noid " + oid + "\nsize 1a\n", nil},
+		{"no version", "oid " + oid + "\nsize 10\n", nil},
+		{"text", "package main\n", nil},
+		{"text with pointer header", "version https://git-lfs.github.com/spec/v1\nthis is a file about lfs\n", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			assert.Equal(t, c.Want, parseLFSPointer([]byte(c.In)))
+		})
+	}
+}
+
+func TestGetInfoLFSPointer(t *testing.T) {
+	content := []byte("version https://git-lfs.github.com/spec/v1\noid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393\nsize 12345\n")
+	p := New()
+	info, skip := p.GetInfo(InfoArgs{FilePath: "assets/logo.png", Content: content, Lines: toLines("version https://git-lfs.github.com/spec/v1", "oid sha256:4d7a214614ab2935c943f9e0ff69d22eadbb8f32b1258daaa5e2ca24d17e2393", "size 12345")})
+	assert.Equal(t, skipLFSPointer, skip)
+	assert.Equal(t, "", info.Language)
+	assert.Equal(t, int64(12345), info.LFS.Size)
+}
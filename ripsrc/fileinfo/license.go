@@ -1,6 +1,12 @@
 package fileinfo
 
-import "regexp"
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
 
 // License holds details about detected license
 type License struct {
@@ -8,8 +14,110 @@ type License struct {
 	Confidence float32
 }
 
+// CustomLicense is a license text matched in addition to the known licenses, for example an internal license.
+type CustomLicense struct {
+	Name string
+	Text string
+}
+
+// DefaultLicenseMinConfidence is the minimum confidence of the match for the license to be detected.
+const DefaultLicenseMinConfidence float32 = 0.85
+
 var licenses = regexp.MustCompile("\\/?(LICENSE|LICENCE|README|COPYING|LICENSE-.*|UNLICENSE|UNLICENCE)(\\.(md|txt))?$")
 
 func possibleLicense(filename string) bool {
 	return licenses.MatchString(filename)
 }
+
+// LoadCustomLicenses reads all files in dir as custom licenses. The name of the license is the file name without extension.
+func LoadCustomLicenses(dir string) (res []CustomLicense, _ error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		b, err := ioutil.ReadFile(filepath.Join(dir, f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		name := strings.TrimSuffix(f.Name(), filepath.Ext(f.Name()))
+		res = append(res, CustomLicense{Name: name, Text: string(b)})
+	}
+	return res, nil
+}
+
+type licenseDetector struct {
+	minConfidence float32
+	custom        []customLicense
+}
+
+type customLicense struct {
+	name    string
+	bigrams map[string]bool
+}
+
+func newLicenseDetector(opts Opts) *licenseDetector {
+	preloadKnown()
+	s := &licenseDetector{}
+	s.minConfidence = opts.LicenseMinConfidence
+	if s.minConfidence == 0 {
+		s.minConfidence = DefaultLicenseMinConfidence
+	}
+	for _, l := range opts.CustomLicenses {
+		s.custom = append(s.custom, customLicense{name: l.Name, bigrams: wordBigrams(l.Text)})
+	}
+	return s
+}
+
+// detect returns the best matching license with confidence above minimum or nil if not found.
+func (s *licenseDetector) detect(filename string, buf []byte) (*License, error) {
+	matches, err := detectKnown(filename, buf)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.custom) != 0 {
+		bigrams := wordBigrams(string(buf))
+		for _, l := range s.custom {
+			matches = append(matches, License{Name: l.name, Confidence: similarity(l.bigrams, bigrams)})
+		}
+	}
+	if len(matches) == 0 {
+		return nil, nil
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Confidence > matches[j].Confidence
+	})
+	if matches[0].Confidence >= s.minConfidence {
+		return &matches[0], nil
+	}
+	return nil, nil
+}
+
+var nonWord = regexp.MustCompile(`[^a-z0-9]+`)
+
+// wordBigrams returns the set of consecutive word pairs in normalized text, ignoring case and punctuation
+func wordBigrams(text string) map[string]bool {
+	words := strings.Fields(nonWord.ReplaceAllString(strings.ToLower(text), " "))
+	res := map[string]bool{}
+	for i := 1; i < len(words); i++ {
+		res[words[i-1]+" "+words[i]] = true
+	}
+	return res
+}
+
+// similarity returns Dice coefficient of the two sets
+func similarity(a, b map[string]bool) float32 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for k := range a {
+		if b[k] {
+			common++
+		}
+	}
+	return float32(2*common) / float32(len(a)+len(b))
+}
@@ -1,7 +1,6 @@
 package fileinfo
 
 import (
-	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -30,7 +29,7 @@ func TestLicenseMatcher(t *testing.T) {
 
 func TestLicenseDetection(t *testing.T) {
 	assert := assert.New(t)
-	lic, err := detect("LICENSE", []byte(`Copyright 2018 Pinpoint
+	lic, err := newLicenseDetector(Opts{}).detect("LICENSE", []byte(`Copyright 2018 Pinpoint
 
 Licensed under the Apache License, Version 2.0 (the "License");
 you may not use this file except in compliance with the License.
@@ -51,7 +50,7 @@ limitations under the License.`))
 
 func TestLicenseDetectionNotFound(t *testing.T) {
 	assert := assert.New(t)
-	lic, err := detect("foo.go", []byte(`package foo
+	lic, err := newLicenseDetector(Opts{}).detect("foo.go", []byte(`package foo
 func main() {
 }`))
 	assert.NoError(err)
@@ -60,7 +59,7 @@ func main() {
 
 func TestLicenseMITFilename(t *testing.T) {
 	assert := assert.New(t)
-	lic, err := detect("LICENSE-MIT", []byte(`Copyright 2018 Pinpoint
+	lic, err := newLicenseDetector(Opts{}).detect("LICENSE-MIT", []byte(`Copyright 2018 Pinpoint
 
 	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
 
@@ -75,7 +74,7 @@ func TestLicenseMITFilename(t *testing.T) {
 
 func TestLicenseMITFilenameInDirectory(t *testing.T) {
 	assert := assert.New(t)
-	lic, err := detect("this/is/some/directory/license.txt", []byte(`Copyright 2018 Pinpoint
+	lic, err := newLicenseDetector(Opts{}).detect("this/is/some/directory/license.txt", []byte(`Copyright 2018 Pinpoint
 
 	Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
 
@@ -87,26 +86,3 @@ func TestLicenseMITFilenameInDirectory(t *testing.T) {
 	assert.Equal("MIT", lic.Name)
 	assert.Equal(float32(0.9814815), lic.Confidence)
 }
-
-func TestLicenseConcurrency(t *testing.T) {
-	assert := assert.New(t)
-	var wg sync.WaitGroup
-	for i := 0; i < 10; i++ {
-		wg.Add(1)
-		go func() {
-			defer wg.Done()
-			lic, err := detect("this/is/some/directory/license.txt", []byte(`Copyright 2018 Pinpoint
-
-			Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
-
-			The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
-
-			THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.`))
-			assert.NoError(err)
-			assert.NotNil(lic)
-			assert.Equal("MIT", lic.Name)
-			assert.Equal(float32(0.9814815), lic.Confidence)
-		}()
-	}
-	wg.Wait()
-}
@@ -0,0 +1,62 @@
+package fileinfo
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const internalLicense = `Example Corp Internal Source License
+
+This source code is the confidential property of Example Corp. It may only be used by employees
+and contractors of Example Corp for the purpose of developing Example Corp products. Distribution
+outside of Example Corp requires written approval of the legal department.`
+
+func TestLicenseCustom(t *testing.T) {
+	p := NewWithOpts(Opts{CustomLicenses: []CustomLicense{{Name: "LicenseRef-Example-Internal", Text: internalLicense}}})
+	info, skipReason := p.GetInfo(makeArgs("LICENSE", "Copyright 2019 Example Corp\n\n"+internalLicense))
+	assert.Equal(t, skipLicense, skipReason)
+	if info.License == nil {
+		t.Fatal("failed to detect custom license")
+	}
+	assert.Equal(t, "LicenseRef-Example-Internal", info.License.Name)
+	assert.True(t, info.License.Confidence > 0.9, "confidence %v", info.License.Confidence)
+}
+
+func TestLicenseCustomMinConfidence(t *testing.T) {
+	text := internalLicense + "\n\nThis license is modified with an additional paragraph that is long enough to reduce the similarity with the original text below the required confidence level."
+	opts := Opts{CustomLicenses: []CustomLicense{{Name: "Internal", Text: internalLicense}}}
+
+	lic, err := newLicenseDetector(opts).detect("LICENSE", []byte(text))
+	assert.NoError(t, err)
+	assert.Nil(t, lic)
+
+	opts.LicenseMinConfidence = 0.5
+	lic, err = newLicenseDetector(opts).detect("LICENSE", []byte(text))
+	assert.NoError(t, err)
+	if assert.NotNil(t, lic) {
+		assert.Equal(t, "Internal", lic.Name)
+	}
+}
+
+func TestLoadCustomLicenses(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ripsrc-licenses")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	err = ioutil.WriteFile(filepath.Join(dir, "Internal-1.0.txt"), []byte(internalLicense), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = os.Mkdir(filepath.Join(dir, "subdir"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := LoadCustomLicenses(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, []CustomLicense{{Name: "Internal-1.0", Text: internalLicense}}, got)
+}
@@ -3,7 +3,6 @@
 package fileinfo
 
 import (
-	"sort"
 	"sync"
 
 	"gopkg.in/src-d/go-license-detector.v2/licensedb"
@@ -34,34 +33,31 @@ func (f *memoryfiler) ReadDir(path string) ([]filer.File, error) {
 func (f *memoryfiler) Close() {
 }
 
-const minConfidenceLevel float32 = 0.85
+// detectTree is licensedb.Detect, replaced in tests
+var detectTree = licensedb.Detect
 
-var mu sync.Mutex
+var preloadOnce sync.Once
 
-func detect(filename string, buf []byte) (*License, error) {
+// preloadKnown loads the license database before the first detection. The database is loaded lazily on first use, and only read afterwards, so loading it once up front allows detection to run concurrently.
+func preloadKnown() {
+	preloadOnce.Do(func() {
+		detectTree(&memoryfiler{"LICENSE", []byte("Permission is hereby granted, free of charge, to any person obtaining a copy of this software")})
+	})
+}
+
+// detectKnown returns all matches from the license database included in go-license-detector. Safe to call concurrently after preloadKnown.
+func detectKnown(filename string, buf []byte) ([]License, error) {
 	mf := &memoryfiler{filename, buf}
-	mu.Lock()
-	kv, err := licensedb.Detect(mf)
-	mu.Unlock()
+	kv, err := detectTree(mf)
 	if err != nil {
 		if err == licensedb.ErrNoLicenseFound {
 			return nil, nil
 		}
 		return nil, err
 	}
-	if len(kv) > 0 {
-		matches := make([]License, 0)
-		for k, v := range kv {
-			matches = append(matches, License{k, v})
-		}
-		if len(matches) > 0 {
-			sort.Slice(matches, func(i, j int) bool {
-				return matches[i].Confidence > matches[j].Confidence
-			})
-			if matches[0].Confidence >= minConfidenceLevel {
-				return &matches[0], nil
-			}
-		}
+	matches := make([]License, 0, len(kv))
+	for k, v := range kv {
+		matches = append(matches, License{k, v})
 	}
-	return nil, nil
+	return matches, nil
 }
@@ -0,0 +1,66 @@
+// +build !no_license
+
+package fileinfo
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-license-detector.v2/licensedb/filer"
+)
+
+const mitText = `Copyright 2018 Pinpoint
+
+Permission is hereby granted, free of charge, to any person obtaining a copy of this software and associated documentation files (the "Software"), to deal in the Software without restriction, including without limitation the rights to use, copy, modify, merge, publish, distribute, sublicense, and/or sell copies of the Software, and to permit persons to whom the Software is furnished to do so, subject to the following conditions:
+
+The above copyright notice and this permission notice shall be included in all copies or substantial portions of the Software.
+
+THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY, FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM, OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE SOFTWARE.`
+
+// TestLicenseConcurrency checks that detection runs concurrently, all calls have to be inside license database at the same time to continue. Run with -race to check for data races in license database.
+func TestLicenseConcurrency(t *testing.T) {
+	d := newLicenseDetector(Opts{})
+
+	const n = 4
+	inside := make(chan bool, n)
+	release := make(chan bool)
+	prev := detectTree
+	detectTree = func(fs filer.Filer) (map[string]float32, error) {
+		inside <- true
+		<-release
+		return prev(fs)
+	}
+	defer func() { detectTree = prev }()
+
+	var wg sync.WaitGroup
+	res := make([]*License, n)
+	errs := make([]error, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			res[i], errs[i] = d.detect("this/is/some/directory/license.txt", []byte(mitText))
+		}(i)
+	}
+	for i := 0; i < n; i++ {
+		select {
+		case <-inside:
+		case <-time.After(10 * time.Second):
+			close(release)
+			wg.Wait()
+			t.Fatalf("detect calls do not overlap, %v of %v calls running at the same time", i, n)
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	for i := 0; i < n; i++ {
+		assert.NoError(t, errs[i])
+		if assert.NotNil(t, res[i]) {
+			assert.Equal(t, "MIT", res[i].Name)
+			assert.Equal(t, float32(0.9814815), res[i].Confidence)
+		}
+	}
+}
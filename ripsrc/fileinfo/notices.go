@@ -0,0 +1,72 @@
+package fileinfo
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// LicenseTag is a license expression declared using SPDX-License-Identifier tag.
+type LicenseTag struct {
+	// Line is the line number starting from 1.
+	Line    int
+	License string
+}
+
+// Copyright is a copyright notice found in file.
+type Copyright struct {
+	// Line is the line number starting from 1.
+	Line   int
+	Years  string
+	Holder string
+}
+
+// copyrightRegexp matches Copyright followed by (c) or years, or © sign
+var copyrightRegexp = regexp.MustCompile(`(?i)(?:\bcopyright\b\s*(\(c\)|©)?|(©))\s*(\d{4}(?:\s*[-,]\s*\d{4})*)?[,.]?\s*(.*)`)
+
+var allRightsReserved = regexp.MustCompile(`(?i)[,.;]?\s*all rights reserved.*$`)
+
+// scanNotices returns SPDX-License-Identifier tags and copyright notices from all lines of the file.
+// Tags in the middle of the file usually mark code copied from other projects.
+func scanNotices(lines [][]byte) (tags []LicenseTag, copyrights []Copyright) {
+	for i, l := range lines {
+		if bytes.Contains(l, []byte("SPDX-License-Identifier:")) {
+			if expr := parseSPDX(l); expr != "" {
+				tags = append(tags, LicenseTag{Line: i + 1, License: expr})
+			}
+			continue
+		}
+		if c, ok := parseCopyright(l); ok {
+			c.Line = i + 1
+			copyrights = append(copyrights, c)
+		}
+	}
+	return
+}
+
+func parseCopyright(line []byte) (res Copyright, _ bool) {
+	// cheap check before regexp, without lowercasing each line
+	if !bytes.Contains(line, []byte("opyright")) && !bytes.Contains(line, []byte("OPYRIGHT")) && !bytes.Contains(line, []byte("©")) {
+		return res, false
+	}
+	m := copyrightRegexp.FindSubmatch(line)
+	if m == nil {
+		return res, false
+	}
+	sign := len(m[1]) != 0 || len(m[2]) != 0
+	res.Years = string(m[3])
+	// require sign or years, to skip copyright word used in text or code
+	if !sign && res.Years == "" {
+		return res, false
+	}
+	holder := string(m[4])
+	for _, end := range []string{"*/", "-->", "*)"} {
+		if i := strings.Index(holder, end); i != -1 {
+			holder = holder[:i]
+		}
+	}
+	holder = allRightsReserved.ReplaceAllString(holder, "")
+	holder = strings.TrimPrefix(strings.TrimSpace(holder), "by ")
+	res.Holder = strings.TrimRight(strings.TrimSpace(holder), ".,;")
+	return res, true
+}
@@ -0,0 +1,59 @@
+package fileinfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func toLines(lines ...string) (res [][]byte) {
+	for _, l := range lines {
+		res = append(res, []byte(l))
+	}
+	return
+}
+
+func TestScanNoticesSPDX(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    [][]byte
+		Want  []LicenseTag
+	}{
+		{"none", toLines("package main", "", "func main(){}"), nil},
+		{"go", toLines("// SPDX-License-Identifier: MIT", "package main"), []LicenseTag{{1, "MIT"}}},
+		{"c block", toLines("/* SPDX-License-Identifier: GPL-2.0-only */", "int a;"), []LicenseTag{{1, "GPL-2.0-only"}}},
+		{"expression", toLines("# SPDX-License-Identifier: (Apache-2.0 OR MIT) AND BSD-3-Clause"), []LicenseTag{{1, "(Apache-2.0 OR MIT) AND BSD-3-Clause"}}},
+		{"html", toLines("<!-- SPDX-License-Identifier: LicenseRef-Internal -->"), []LicenseTag{{1, "LicenseRef-Internal"}}},
+		{"empty tag", toLines("// SPDX-License-Identifier:", "// SPDX-License-Identifier: MIT"), []LicenseTag{{2, "MIT"}}},
+		{"snippet", toLines("// SPDX-License-Identifier: MIT", "package main", "", "// SPDX-License-Identifier: GPL-3.0-or-later", "func a(){}"), []LicenseTag{{1, "MIT"}, {4, "GPL-3.0-or-later"}}},
+	}
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			got, _ := scanNotices(c.In)
+			assert.Equal(t, c.Want, got)
+		})
+	}
+}
+
+func TestScanNoticesCopyright(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    string
+		Want  []Copyright
+	}{
+		{"go", "// Copyright 2019 The Go Authors. All rights reserved.", []Copyright{{1, "2019", "The Go Authors"}}},
+		{"sign", "/* Copyright (c) 2010-2015, Example Inc. */", []Copyright{{1, "2010-2015", "Example Inc"}}},
+		{"symbol", "# © 2018 Jane Doe <jane@example.com>", []Copyright{{1, "2018", "Jane Doe <jane@example.com>"}}},
+		{"years list", "// Copyright 2012, 2014 by John", []Copyright{{1, "2012, 2014", "John"}}},
+		{"no year", "// Copyright (C) Example", []Copyright{{1, "", "Example"}}},
+		{"upper case", "# COPYRIGHT 2020 EXAMPLE INC", []Copyright{{1, "2020", "EXAMPLE INC"}}},
+		{"code", "copyright := getCopyright()", nil},
+		{"text", "// see the copyright notice", nil},
+	}
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			_, got := scanNotices(toLines(c.In))
+			assert.Equal(t, c.Want, got)
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package fileinfo
+
+import (
+	"regexp"
+	"strings"
+)
+
+// spdxHeaderLines is the number of lines at the top of the file checked for SPDX-License-Identifier
+const spdxHeaderLines = 30
+
+var spdxRegexp = regexp.MustCompile(`SPDX-License-Identifier:(.*)`)
+
+// spdxToken matches license ids, operators and parentheses in license expression
+var spdxToken = regexp.MustCompile(`^\(*[A-Za-z0-9][A-Za-z0-9.+:\-]*\)*$`)
+
+// detectSPDX returns the license expression from SPDX-License-Identifier tag in the file header or empty string if not found.
+func detectSPDX(lines [][]byte) string {
+	if len(lines) > spdxHeaderLines {
+		lines = lines[:spdxHeaderLines]
+	}
+	for _, l := range lines {
+		if expr := parseSPDX(l); expr != "" {
+			return expr
+		}
+	}
+	return ""
+}
+
+// parseSPDX returns the license expression from SPDX-License-Identifier tag in line or empty string if not found.
+func parseSPDX(line []byte) string {
+	m := spdxRegexp.FindSubmatch(line)
+	if m == nil {
+		return ""
+	}
+	var expr []string
+	// stop at comment terminators such as */ or -->
+	for _, t := range strings.Fields(string(m[1])) {
+		if !spdxToken.MatchString(t) {
+			break
+		}
+		expr = append(expr, t)
+	}
+	return strings.Join(expr, " ")
+}
@@ -0,0 +1,40 @@
+package fileinfo
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectSPDX(t *testing.T) {
+	cases := []struct {
+		Label string
+		In    []string
+		Want  string
+	}{
+		{"none", []string{"package main", "", "func main(){}"}, ""},
+		{"go", []string{"// SPDX-License-Identifier: MIT", "package main"}, "MIT"},
+		{"c block", []string{"/* SPDX-License-Identifier: GPL-2.0-only */", "int a;"}, "GPL-2.0-only"},
+		{"expression", []string{"# SPDX-License-Identifier: (Apache-2.0 OR MIT) AND BSD-3-Clause", "a = 1"}, "(Apache-2.0 OR MIT) AND BSD-3-Clause"},
+		{"html", []string{"<!-- SPDX-License-Identifier: LicenseRef-Internal -->"}, "LicenseRef-Internal"},
+		{"empty tag", []string{"// SPDX-License-Identifier:", "// SPDX-License-Identifier: MIT"}, "MIT"},
+	}
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			var lines [][]byte
+			for _, l := range c.In {
+				lines = append(lines, []byte(l))
+			}
+			assert.Equal(t, c.Want, detectSPDX(lines))
+		})
+	}
+}
+
+func TestDetectSPDXHeaderOnly(t *testing.T) {
+	var lines [][]byte
+	for i := 0; i < spdxHeaderLines; i++ {
+		lines = append(lines, []byte("code"))
+	}
+	lines = append(lines, []byte("// SPDX-License-Identifier: MIT"))
+	assert.Equal(t, "", detectSPDX(lines))
+}
@@ -28,14 +28,15 @@ func (r Result) String() string {
 	return strings.Join(out, "\n")
 }
 
-func Run(repoDir, commitHash, file string) (res Result, _ error) {
+// Run runs git blame for file at commit. Pass extraArgs to use additional options, for example -M -C.
+func Run(repoDir, commitHash, file string, extraArgs ...string) (res Result, _ error) {
 	args := []string{
 		"blame",
 		commitHash,
 		"--porcelain",
-		"--",
-		file,
 	}
+	args = append(args, extraArgs...)
+	args = append(args, "--", file)
 	cmd := exec.Command("git", args...)
 	cmd.Dir = repoDir
 	cmd.Stderr = os.Stderr
@@ -0,0 +1,171 @@
+package ripsrc
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo"
+)
+
+// codeAtHead runs Code and returns the last result for each file, which is the state of the repo at the last processed commit.
+func (s *Ripsrc) codeAtHead(ctx context.Context) (lastCommit string, res map[string]BlameResult, _ error) {
+	err := s.codeSnapshots(ctx, nil, func(commit Commit, files map[string]BlameResult) {
+		lastCommit = commit.SHA
+		res = files
+	})
+	if err != nil {
+		return "", nil, err
+	}
+	return lastCommit, res, nil
+}
+
+// codeSnapshots runs Code and calls cb with the state of all files at each commit in commits. If commits is empty, cb is called once after the last commit.
+// State is built from the blame tree of the commit, so it is exact for commits on branches and when processing starts from checkpoint. Results for files with the same blame as in the last returned result are reused, other files are processed again.
+func (s *Ripsrc) codeSnapshots(ctx context.Context, commits map[string]bool, cb func(commit Commit, files map[string]BlameResult)) error {
+	results := map[string]snapshotFile{}
+	var last Commit
+	var lastTree *repo.Tree
+	s.snapshot = func(commit Commit, rs []BlameResult, tree *repo.Tree) error {
+		// blame results do not include the old path of renamed files
+		for fp, f := range commit.Files {
+			if f.Status == GitFileCommitStatusRemoved {
+				delete(results, fp)
+			}
+		}
+		for _, r := range rs {
+			if r.Status == GitFileCommitStatusRemoved {
+				delete(results, r.Filename)
+				continue
+			}
+			results[r.Filename] = snapshotFile{blame: tree.Get(r.Filename), res: r}
+		}
+		last = commit
+		lastTree = tree
+		if commits[commit.SHA] {
+			return s.snapshotFiles(ctx, commit, tree, results, cb)
+		}
+		return nil
+	}
+	defer func() {
+		s.snapshot = nil
+	}()
+
+	resChan := make(chan CommitCode)
+	done := make(chan bool)
+	go func() {
+		for c := range resChan {
+			for range c.Blames {
+			}
+		}
+		done <- true
+	}()
+	err := s.CodeByCommit(ctx, resChan)
+	<-done
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 && lastTree != nil {
+		return s.snapshotFiles(ctx, last, lastTree, results, cb)
+	}
+	return nil
+}
+
+// snapshotFile is the last result returned for file with the blame it was created from
+type snapshotFile struct {
+	blame *incblame.Blame
+	res   BlameResult
+}
+
+// snapshotFiles calls cb with results for all files in tree. Results are reused from last returned if blame is the same. Submodules are skipped.
+func (s *Ripsrc) snapshotFiles(ctx context.Context, commit Commit, tree *repo.Tree, results map[string]snapshotFile, cb func(commit Commit, files map[string]BlameResult)) error {
+	// tree only has blame data, get modes from git
+	submodules, err := s.submodulePaths(ctx, commit.SHA)
+	if err != nil {
+		return err
+	}
+	files := map[string]BlameResult{}
+	var rerr error
+	tree.Range(func(filePath string, bl *incblame.Blame) {
+		if rerr != nil || filePath == "" {
+			return
+		}
+		if submodules[filePath] {
+			return
+		}
+		if f, ok := results[filePath]; ok && f.blame == bl {
+			files[filePath] = f.res
+			return
+		}
+		r := BlameResult{Commit: commit, Filename: filePath, Status: GitFileCommitStatusModified}
+		r, rerr = s.codeInfoBlame(filePath, bl, r)
+		if rerr != nil {
+			return
+		}
+		results[filePath] = snapshotFile{blame: bl, res: r}
+		files[filePath] = r
+	})
+	if rerr != nil {
+		return rerr
+	}
+	cb(commit, files)
+	return nil
+}
+
+// codeAtCommits calls cb with the state of the files at each of the passed refs, or at the last processed commit if refs is empty. Returns an error if some of the refs were not processed.
+func (s *Ripsrc) codeAtCommits(ctx context.Context, refs []string, cb func(commit Commit, files map[string]BlameResult)) error {
+	err := s.prepareGitExec(ctx)
+	if err != nil {
+		return err
+	}
+
+	var wanted map[string]bool
+	if len(refs) != 0 {
+		wanted = map[string]bool{}
+		for _, c := range refs {
+			sha, err := s.revParseCommit(ctx, c)
+			if err != nil {
+				return err
+			}
+			wanted[sha] = true
+		}
+	}
+
+	found := map[string]bool{}
+	err = s.codeSnapshots(ctx, wanted, func(commit Commit, files map[string]BlameResult) {
+		found[commit.SHA] = true
+		cb(commit, files)
+	})
+	if err != nil {
+		return err
+	}
+
+	var missing []string
+	for c := range wanted {
+		if !found[c] {
+			missing = append(missing, c)
+		}
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("commits were not processed: %v", strings.Join(missing, ","))
+	}
+	return nil
+}
+
+func (s *Ripsrc) revParseCommit(ctx context.Context, ref string) (string, error) {
+	r, err := gitexec.Exec(ctx, gitCommand, s.opts.RepoDir, []string{"rev-parse", "--verify", ref + "^{commit}"})
+	if err != nil {
+		return "", fmt.Errorf("invalid commit %v: %v", ref, err)
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
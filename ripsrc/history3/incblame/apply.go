@@ -77,7 +77,21 @@ func (f Blame) Eq(f2 *Blame) bool {
 	return true
 }
 
+// ApplyOpts controls attribution of lines changed in diff.
+type ApplyOpts struct {
+	// IgnoreWhitespace keeps the previous commit for added lines that replace a deleted line differing only in whitespace.
+	IgnoreWhitespace bool
+	// KeepPrevious keeps the previous commit for all replaced lines, pairing added and deleted lines of the same change by position when content does not match. Added lines without a deleted pair are attributed to commit. Used for commits that should be ignored in blame, such as reformatting.
+	KeepPrevious bool
+}
+
+// Apply applies diff to file blame, with added lines attributed to commit.
 func Apply(file Blame, diff Diff, commit string, fileForDebug string) Blame {
+	return ApplyWithOpts(file, diff, commit, fileForDebug, ApplyOpts{})
+}
+
+// ApplyWithOpts is the same as Apply, but allows keeping the previous commit for changed lines.
+func ApplyWithOpts(file Blame, diff Diff, commit string, fileForDebug string, opts ApplyOpts) Blame {
 	rerr := func(err error) {
 		panic(fmt.Errorf("commit:%v file:%v %v", commit, fileForDebug, err))
 	}
@@ -102,8 +116,26 @@ func Apply(file Blame, diff Diff, commit string, fileForDebug string) Blame {
 		res = append(res, file.Lines[i])
 	}
 
+	// deleted lines and number of added lines in the current change, used to keep previous commit
+	var deleted Lines
+	// deletedNorm has deleted lines without whitespace, normalized once per change instead of for each added line
+	var deletedNorm [][]byte
+	var deletedMatched []bool
+	added := 0
+	resetChange := func() {
+		deleted = nil
+		deletedNorm = nil
+		deletedMatched = nil
+		added = 0
+	}
+
 	addLine := func(data []byte) {
-		res = append(res, &Line{Line: data, Commit: commit})
+		c := commit
+		if opts.IgnoreWhitespace || opts.KeepPrevious {
+			c = previousCommit(data, deleted, deletedNorm, deletedMatched, added, opts.KeepPrevious, commit)
+			added++
+		}
+		res = append(res, &Line{Line: data, Commit: c})
 	}
 
 	sort.Slice(diff.Hunks, func(i, j int) bool {
@@ -129,6 +161,7 @@ func Apply(file Blame, diff Diff, commit string, fileForDebug string) Blame {
 
 		copyRange(oldFileIndex, j)
 		oldFileIndex = j
+		resetChange()
 
 		for scanner.Scan() {
 			b := scanner.Bytes()
@@ -141,7 +174,18 @@ func Apply(file Blame, diff Diff, commit string, fileForDebug string) Blame {
 			case ' ', '\t':
 				copyLine(oldFileIndex)
 				oldFileIndex++
+				resetChange()
 			case '-':
+				if added != 0 {
+					// deletion after additions starts a new change
+					resetChange()
+				}
+				if oldFileIndex < len(file.Lines) && (opts.IgnoreWhitespace || opts.KeepPrevious) {
+					l := file.Lines[oldFileIndex]
+					deleted = append(deleted, l)
+					deletedNorm = append(deletedNorm, withoutWhitespace(l.Line))
+					deletedMatched = append(deletedMatched, false)
+				}
 				oldFileIndex++
 			case '+':
 				addLine(copyBytes(data))
@@ -167,6 +211,30 @@ func Apply(file Blame, diff Diff, commit string, fileForDebug string) Blame {
 	return Blame{Lines: res, Commit: commit}
 }
 
+// previousCommit returns the commit of the deleted line replaced by added line data, or commit if not found.
+// Deleted lines differing only in whitespace are preferred, with keepPrevious the deleted line at the same position in the change is used otherwise. deletedNorm has deleted lines with whitespace removed.
+func previousCommit(data []byte, deleted Lines, deletedNorm [][]byte, matched []bool, addedIndex int, keepPrevious bool, commit string) string {
+	norm := withoutWhitespace(data)
+	for i, d := range deleted {
+		if matched[i] {
+			continue
+		}
+		if bytes.Equal(deletedNorm[i], norm) {
+			matched[i] = true
+			return d.Commit
+		}
+	}
+	if keepPrevious && addedIndex < len(deleted) && !matched[addedIndex] {
+		matched[addedIndex] = true
+		return deleted[addedIndex].Commit
+	}
+	return commit
+}
+
+func withoutWhitespace(b []byte) []byte {
+	return bytes.Join(bytes.Fields(b), nil)
+}
+
 func copyBytes(b []byte) []byte {
 	res := make([]byte, len(b))
 	copy(res, b)
@@ -0,0 +1,96 @@
+package incblame
+
+import (
+	"testing"
+)
+
+const whitespaceDiff1 = `diff --git a/main.go b/main.go
+new file mode 100644
+index 0000000..43f9419
+--- /dev/null
++++ b/main.go
+@@ -0,0 +1,5 @@
++package main
++
++func main() {
++  println("a")
++}
+`
+
+const whitespaceDiff2 = `diff --git a/main.go b/main.go
+index 43f9419..1671209 100644
+--- a/main.go
++++ b/main.go
+@@ -1,5 +1,6 @@
+ package main
+ 
+ func main() {
+-  println("a")
++	println("a")
++	println("b")
+ }
+`
+
+func TestApplyIgnoreWhitespace(t *testing.T) {
+	c1 := "c1"
+	c2 := "c2"
+	f1 := Apply(Blame{}, Parse([]byte(whitespaceDiff1)), c1, "")
+	f2 := ApplyWithOpts(f1, Parse([]byte(whitespaceDiff2)), c2, "", ApplyOpts{IgnoreWhitespace: true})
+
+	want := file(c2,
+		line(`package main`, c1),
+		line(``, c1),
+		line(`func main() {`, c1),
+		line(`	println("a")`, c1),
+		line(`	println("b")`, c2),
+		line(`}`, c1),
+	)
+	assertEqualFiles(t, f2, want)
+}
+
+func TestApplyWhitespaceDefault(t *testing.T) {
+	c1 := "c1"
+	c2 := "c2"
+	f1 := Apply(Blame{}, Parse([]byte(whitespaceDiff1)), c1, "")
+	f2 := Apply(f1, Parse([]byte(whitespaceDiff2)), c2, "")
+
+	want := file(c2,
+		line(`package main`, c1),
+		line(``, c1),
+		line(`func main() {`, c1),
+		line(`	println("a")`, c2),
+		line(`	println("b")`, c2),
+		line(`}`, c1),
+	)
+	assertEqualFiles(t, f2, want)
+}
+
+const keepPreviousDiff2 = `diff --git a/main.go b/main.go
+index 43f9419..1671209 100644
+--- a/main.go
++++ b/main.go
+@@ -3,3 +3,4 @@
+ func main() {
+-  println("a")
++	println('a')
++	println("b")
+ }
+`
+
+func TestApplyKeepPrevious(t *testing.T) {
+	c1 := "c1"
+	c2 := "c2"
+	f1 := Apply(Blame{}, Parse([]byte(whitespaceDiff1)), c1, "")
+	f2 := ApplyWithOpts(f1, Parse([]byte(keepPreviousDiff2)), c2, "", ApplyOpts{KeepPrevious: true})
+
+	// changed line keeps previous commit, added line without pair is attributed to commit
+	want := file(c2,
+		line(`package main`, c1),
+		line(``, c1),
+		line(`func main() {`, c1),
+		line(`	println('a')`, c1),
+		line(`	println("b")`, c2),
+		line(`}`, c1),
+	)
+	assertEqualFiles(t, f2, want)
+}
@@ -0,0 +1,86 @@
+package incblame
+
+import (
+	"bytes"
+)
+
+// binaryCheckBytes is the number of bytes checked for NUL to detect binary content, same as git.
+const binaryCheckBytes = 8000
+
+// bigFileThreshold is the size after which content is treated as binary, same as git core.bigFileThreshold default.
+const bigFileThreshold = 512 * 1024 * 1024
+
+// DiffContents returns diff between previous and current content of a file, in the same form as Parse returns for git log -p output. Hunks have no context lines.
+// Use empty pathPrev for added files and empty path for deleted files. Content is treated as binary when it contains NUL in the first 8000 bytes, same as git.
+func DiffContents(pathPrev, path string, prev, curr []byte) (res Diff) {
+	res.PathPrev = pathPrev
+	res.Path = path
+	if bytes.Equal(prev, curr) {
+		return
+	}
+	if IsBinaryContent(prev) || IsBinaryContent(curr) {
+		res.IsBinary = true
+		return
+	}
+	if path == "" {
+		return
+	}
+
+	a := splitLines(prev)
+	b := splitLines(curr)
+	ra, rb := diffLines(a, b)
+
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		if i < len(a) && j < len(b) && !ra[i] && !rb[j] {
+			i++
+			j++
+			continue
+		}
+		h := Hunk{}
+		del := HunkLocation{Op: OpDel, Offset: i + 1}
+		add := HunkLocation{Op: OpAdd, Offset: j + 1}
+		for ; i < len(a) && ra[i]; i++ {
+			h.Data = appendPatchLine(h.Data, '-', a[i])
+			del.Lines++
+		}
+		for ; j < len(b) && rb[j]; j++ {
+			h.Data = appendPatchLine(h.Data, '+', b[j])
+			add.Lines++
+		}
+		h.Locations = []HunkLocation{del, add}
+		res.Hunks = append(res.Hunks, h)
+	}
+	return
+}
+
+// IsBinaryContent returns true if git would show content as binary in diffs.
+func IsBinaryContent(data []byte) bool {
+	if len(data) > bigFileThreshold {
+		return true
+	}
+	if len(data) > binaryCheckBytes {
+		data = data[:binaryCheckBytes]
+	}
+	return bytes.IndexByte(data, 0) != -1
+}
+
+// splitLines splits content into lines keeping the trailing newline, so that the last line without newline differs from the same line with it, same as in git diff.
+func splitLines(data []byte) (res [][]byte) {
+	for len(data) > 0 {
+		i := bytes.IndexByte(data, '\n')
+		if i == -1 {
+			res = append(res, data)
+			break
+		}
+		res = append(res, data[:i+1])
+		data = data[i+1:]
+	}
+	return
+}
+
+func appendPatchLine(data []byte, op byte, line []byte) []byte {
+	data = append(data, op)
+	data = append(data, bytes.TrimSuffix(line, []byte("\n"))...)
+	return append(data, '\n')
+}
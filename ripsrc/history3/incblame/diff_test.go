@@ -0,0 +1,129 @@
+package incblame
+
+import (
+	"testing"
+)
+
+func TestDiffContentsAdded(t *testing.T) {
+	got := DiffContents("", "a.txt", nil, []byte("a\nb\n"))
+	want := Diff{
+		Path: "a.txt",
+		Hunks: []Hunk{
+			{
+				Locations: []HunkLocation{
+					{OpDel, 1, 0},
+					{OpAdd, 1, 2},
+				},
+				Data: []byte("+a\n+b\n"),
+			},
+		},
+	}
+	assertEqualDiffs(t, got, want)
+}
+
+func TestDiffContentsDeleted(t *testing.T) {
+	got := DiffContents("a.txt", "", []byte("a\nb\n"), nil)
+	want := Diff{
+		PathPrev: "a.txt",
+	}
+	assertEqualDiffs(t, got, want)
+}
+
+func TestDiffContentsRenameNoChanges(t *testing.T) {
+	got := DiffContents("a.txt", "b.txt", []byte("a\n"), []byte("a\n"))
+	want := Diff{
+		PathPrev: "a.txt",
+		Path:     "b.txt",
+	}
+	assertEqualDiffs(t, got, want)
+}
+
+func TestDiffContentsBinary(t *testing.T) {
+	got := DiffContents("a.bin", "a.bin", []byte("a\n"), []byte("a\x00\n"))
+	want := Diff{
+		PathPrev: "a.bin",
+		Path:     "a.bin",
+		IsBinary: true,
+	}
+	assertEqualDiffs(t, got, want)
+
+	got = DiffContents("a.bin", "", []byte("a\x00\n"), nil)
+	want = Diff{
+		PathPrev: "a.bin",
+		IsBinary: true,
+	}
+	assertEqualDiffs(t, got, want)
+}
+
+func TestDiffContentsNoNewline(t *testing.T) {
+	// same as in git, last line without newline is different from the same line with newline
+	got := DiffContents("a.txt", "a.txt", []byte("a\nb"), []byte("a\nb\nc\n"))
+	want := Diff{
+		PathPrev: "a.txt",
+		Path:     "a.txt",
+		Hunks: []Hunk{
+			{
+				Locations: []HunkLocation{
+					{OpDel, 2, 1},
+					{OpAdd, 2, 2},
+				},
+				Data: []byte("-b\n+b\n+c\n"),
+			},
+		},
+	}
+	assertEqualDiffs(t, got, want)
+}
+
+func TestDiffContentsIndentHeuristic(t *testing.T) {
+	prev := `func a() {
+}
+
+func c() {
+}
+`
+	curr := `func a() {
+}
+
+func b() {
+}
+
+func c() {
+}
+`
+	// git diff places the added blank line after the new function
+	got := DiffContents("a.go", "a.go", []byte(prev), []byte(curr))
+	want := Diff{
+		PathPrev: "a.go",
+		Path:     "a.go",
+		Hunks: []Hunk{
+			{
+				Locations: []HunkLocation{
+					{OpDel, 4, 0},
+					{OpAdd, 4, 3},
+				},
+				Data: []byte("+func b() {\n+}\n+\n"),
+			},
+		},
+	}
+	assertEqualDiffs(t, got, want)
+}
+
+func TestDiffContentsApply(t *testing.T) {
+	v1 := "package main\n\nimport \"fmt\"\n\nfunc main() {\n\tfmt.Println(1)\n}\n"
+	v2 := "package main\n\nfunc main() {\n\tprintln(1)\n\tprintln(2)\n}\n"
+
+	c1 := "c1"
+	c2 := "c2"
+	f1 := Apply(Blame{}, DiffContents("", "main.go", nil, []byte(v1)), c1, "")
+	f2 := Apply(f1, DiffContents("main.go", "main.go", []byte(v1), []byte(v2)), c2, "")
+
+	want := file(c2,
+		line(`package main`, c1),
+		line(``, c1),
+		line(`func main() {`, c1),
+		line(`	println(1)`, c2),
+		line(`	println(2)`, c2),
+		line(`}`, c1),
+	)
+	assertEqualFiles(t, f2, want)
+}
@@ -0,0 +1,682 @@
+package incblame
+
+// diffLines returns lines removed from a and added in b. Port of the default Myers diff from git (xdiff), including discarding of lines without matches, group sliding and indent heuristic, so that changes are attributed the same way as in git log -p output.
+func diffLines(a, b [][]byte) (removed, added []bool) {
+	ids := map[string]int{}
+	var count1, count2 []int
+	toIDs := func(lines [][]byte) []int {
+		res := make([]int, len(lines))
+		for i, l := range lines {
+			id, ok := ids[string(l)]
+			if !ok {
+				id = len(ids)
+				ids[string(l)] = id
+				count1 = append(count1, 0)
+				count2 = append(count2, 0)
+			}
+			res[i] = id
+		}
+		return res
+	}
+	ha1 := toIDs(a)
+	for _, id := range ha1 {
+		count1[id]++
+	}
+	ha2 := toIDs(b)
+	for _, id := range ha2 {
+		count2[id]++
+	}
+
+	// changed flags have sentinels before and after lines to simplify group sliding
+	ra := make([]bool, len(a)+2)
+	rb := make([]bool, len(b)+2)
+
+	// trim common lines at start and end
+	start := 0
+	for start < len(a) && start < len(b) && ha1[start] == ha2[start] {
+		start++
+	}
+	end := 0
+	for start+end < len(a) && start+end < len(b) && ha1[len(a)-1-end] == ha2[len(b)-1-end] {
+		end++
+	}
+
+	s := &lineDiff{}
+	s.ha1, s.rindex1 = discardLines(ha1, ra, start, len(a)-end, count2)
+	s.ha2, s.rindex2 = discardLines(ha2, rb, start, len(b)-end, count1)
+	s.ra = ra
+	s.rb = rb
+
+	ndiags := len(s.ha1) + len(s.ha2) + 3
+	s.kvdf = make([]int, ndiags)
+	s.kvdb = make([]int, ndiags)
+	s.kvOffset = len(s.ha2) + 1
+	s.mxcost = bogoSqrt(ndiags)
+	if s.mxcost < maxCostMin {
+		s.mxcost = maxCostMin
+	}
+	s.compare(0, len(s.ha1), 0, len(s.ha2), false)
+
+	compactChanges(ha1, ra, rb, a)
+	compactChanges(ha2, rb, ra, b)
+	return ra[1 : len(a)+1], rb[1 : len(b)+1]
+}
+
+const (
+	maxEqLimit     = 1024
+	simScanWindow  = 100
+	kpDisRun       = 4
+	maxCostMin     = 256
+	heurMinCost    = 256
+	snakeCnt       = 20
+	kHeur          = 4
+	lineMax        = int(^uint(0) >> 1)
+	discardNoMatch = 0
+	discardKeep    = 1
+	discardMulti   = 2
+)
+
+func bogoSqrt(n int) int {
+	i := 1
+	for ; n > 0; n >>= 2 {
+		i <<= 1
+	}
+	return i
+}
+
+// discardLines marks lines in [start, end) that have no match in the other file as changed, same for lines with many matches surrounded by such lines. Returns ids and indexes of lines left for comparison.
+func discardLines(ha []int, ch []bool, start, end int, otherCount []int) (res []int, rindex []int) {
+	mlim := bogoSqrt(len(ha))
+	if mlim > maxEqLimit {
+		mlim = maxEqLimit
+	}
+	dis := make([]byte, len(ha))
+	for i := start; i < end; i++ {
+		nm := otherCount[ha[i]]
+		switch {
+		case nm == 0:
+			dis[i] = discardNoMatch
+		case nm >= mlim:
+			dis[i] = discardMulti
+		default:
+			dis[i] = discardKeep
+		}
+	}
+	for i := start; i < end; i++ {
+		if dis[i] == discardKeep || (dis[i] == discardMulti && !cleanMultiMatch(dis, i, start, end-1)) {
+			res = append(res, ha[i])
+			rindex = append(rindex, i)
+		} else {
+			ch[i+1] = true
+		}
+	}
+	return
+}
+
+// cleanMultiMatch returns true if line with many matches should be discarded, because it is surrounded by lines without matches.
+func cleanMultiMatch(dis []byte, i, s, e int) bool {
+	if i-s > simScanWindow {
+		s = i - simScanWindow
+	}
+	if e-i > simScanWindow {
+		e = i + simScanWindow
+	}
+	rdis0, rpdis0 := 0, 1
+	for r := 1; i-r >= s; r++ {
+		if dis[i-r] == discardNoMatch {
+			rdis0++
+		} else if dis[i-r] == discardMulti {
+			rpdis0++
+		} else {
+			break
+		}
+	}
+	if rdis0 == 0 {
+		return false
+	}
+	rdis1, rpdis1 := 0, 1
+	for r := 1; i+r <= e; r++ {
+		if dis[i+r] == discardNoMatch {
+			rdis1++
+		} else if dis[i+r] == discardMulti {
+			rpdis1++
+		} else {
+			break
+		}
+	}
+	if rdis1 == 0 {
+		return false
+	}
+	rdis1 += rdis0
+	rpdis1 += rpdis0
+	return rpdis1*kpDisRun < rpdis1+rdis1
+}
+
+type lineDiff struct {
+	// ids of lines left after discarding and their indexes in files
+	ha1     []int
+	ha2     []int
+	rindex1 []int
+	rindex2 []int
+
+	ra []bool
+	rb []bool
+
+	// furthest reaching paths for forward and backward search by diagonal
+	kvdf     []int
+	kvdb     []int
+	kvOffset int
+	mxcost   int
+}
+
+func (s *lineDiff) compare(off1, lim1, off2, lim2 int, needMin bool) {
+	for off1 < lim1 && off2 < lim2 && s.ha1[off1] == s.ha2[off2] {
+		off1++
+		off2++
+	}
+	for off1 < lim1 && off2 < lim2 && s.ha1[lim1-1] == s.ha2[lim2-1] {
+		lim1--
+		lim2--
+	}
+	if off1 == lim1 {
+		for ; off2 < lim2; off2++ {
+			s.rb[s.rindex2[off2]+1] = true
+		}
+		return
+	}
+	if off2 == lim2 {
+		for ; off1 < lim1; off1++ {
+			s.ra[s.rindex1[off1]+1] = true
+		}
+		return
+	}
+	spl := s.split(off1, lim1, off2, lim2, needMin)
+	s.compare(off1, spl.i1, off2, spl.i2, spl.minLo)
+	s.compare(spl.i1, lim1, spl.i2, lim2, spl.minHi)
+}
+
+type diffSplit struct {
+	i1    int
+	i2    int
+	minLo bool
+	minHi bool
+}
+
+// split finds the middle snake of the shortest edit script and returns the point to split the problem at. For expensive diffs uses heuristics returning a good enough split point instead.
+// See Myers's 1986 paper: An O(ND) Difference Algorithm and Its Variations.
+func (s *lineDiff) split(off1, lim1, off2, lim2 int, needMin bool) (spl diffSplit) {
+	ha1, ha2 := s.ha1, s.ha2
+	kvdf := func(d int) *int { return &s.kvdf[d+s.kvOffset] }
+	kvdb := func(d int) *int { return &s.kvdb[d+s.kvOffset] }
+
+	dmin, dmax := off1-lim2, lim1-off2
+	fmid, bmid := off1-off2, lim1-lim2
+	odd := (fmid-bmid)&1 != 0
+	fmin, fmax := fmid, fmid
+	bmin, bmax := bmid, bmid
+
+	*kvdf(fmid) = off1
+	*kvdb(bmid) = lim1
+
+	for ec := 1; ; ec++ {
+		gotSnake := false
+
+		if fmin > dmin {
+			fmin--
+			*kvdf(fmin - 1) = -1
+		} else {
+			fmin++
+		}
+		if fmax < dmax {
+			fmax++
+			*kvdf(fmax + 1) = -1
+		} else {
+			fmax--
+		}
+		for d := fmax; d >= fmin; d -= 2 {
+			var i1 int
+			if *kvdf(d - 1) >= *kvdf(d + 1) {
+				i1 = *kvdf(d - 1) + 1
+			} else {
+				i1 = *kvdf(d + 1)
+			}
+			prev1 := i1
+			i2 := i1 - d
+			for i1 < lim1 && i2 < lim2 && ha1[i1] == ha2[i2] {
+				i1++
+				i2++
+			}
+			if i1-prev1 > snakeCnt {
+				gotSnake = true
+			}
+			*kvdf(d) = i1
+			if odd && bmin <= d && d <= bmax && *kvdb(d) <= i1 {
+				return diffSplit{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		if bmin > dmin {
+			bmin--
+			*kvdb(bmin - 1) = lineMax
+		} else {
+			bmin++
+		}
+		if bmax < dmax {
+			bmax++
+			*kvdb(bmax + 1) = lineMax
+		} else {
+			bmax--
+		}
+		for d := bmax; d >= bmin; d -= 2 {
+			var i1 int
+			if *kvdb(d - 1) < *kvdb(d + 1) {
+				i1 = *kvdb(d - 1)
+			} else {
+				i1 = *kvdb(d + 1) - 1
+			}
+			prev1 := i1
+			i2 := i1 - d
+			for i1 > off1 && i2 > off2 && ha1[i1-1] == ha2[i2-1] {
+				i1--
+				i2--
+			}
+			if prev1-i1 > snakeCnt {
+				gotSnake = true
+			}
+			*kvdb(d) = i1
+			if !odd && fmin <= d && d <= fmax && i1 <= *kvdf(d) {
+				return diffSplit{i1: i1, i2: i2, minLo: true, minHi: true}
+			}
+		}
+
+		if needMin {
+			continue
+		}
+
+		// heuristic for long diffs, use a split point with a long enough snake that made good progress
+		if gotSnake && ec > heurMinCost {
+			best := 0
+			for d := fmax; d >= fmin; d -= 2 {
+				dd := d - fmid
+				if dd < 0 {
+					dd = -dd
+				}
+				i1 := *kvdf(d)
+				i2 := i1 - d
+				v := (i1 - off1) + (i2 - off2) - dd
+				if v > kHeur*ec && v > best &&
+					off1+snakeCnt <= i1 && i1 < lim1 &&
+					off2+snakeCnt <= i2 && i2 < lim2 {
+					for k := 1; ha1[i1-k] == ha2[i2-k]; k++ {
+						if k == snakeCnt {
+							best = v
+							spl.i1 = i1
+							spl.i2 = i2
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minLo = true
+				spl.minHi = false
+				return
+			}
+
+			for d := bmax; d >= bmin; d -= 2 {
+				dd := d - bmid
+				if dd < 0 {
+					dd = -dd
+				}
+				i1 := *kvdb(d)
+				i2 := i1 - d
+				v := (lim1 - i1) + (lim2 - i2) - dd
+				if v > kHeur*ec && v > best &&
+					off1 < i1 && i1 <= lim1-snakeCnt &&
+					off2 < i2 && i2 <= lim2-snakeCnt {
+					for k := 0; ha1[i1+k] == ha2[i2+k]; k++ {
+						if k == snakeCnt-1 {
+							best = v
+							spl.i1 = i1
+							spl.i2 = i2
+							break
+						}
+					}
+				}
+			}
+			if best > 0 {
+				spl.minLo = false
+				spl.minHi = true
+				return
+			}
+		}
+
+		// too expensive, use the furthest reaching path
+		if ec >= s.mxcost {
+			fbest, fbest1 := -1, -1
+			for d := fmax; d >= fmin; d -= 2 {
+				i1 := *kvdf(d)
+				if i1 > lim1 {
+					i1 = lim1
+				}
+				i2 := i1 - d
+				if lim2 < i2 {
+					i1 = lim2 + d
+					i2 = lim2
+				}
+				if fbest < i1+i2 {
+					fbest = i1 + i2
+					fbest1 = i1
+				}
+			}
+			bbest, bbest1 := lineMax, lineMax
+			for d := bmax; d >= bmin; d -= 2 {
+				i1 := *kvdb(d)
+				if i1 < off1 {
+					i1 = off1
+				}
+				i2 := i1 - d
+				if i2 < off2 {
+					i1 = off2 + d
+					i2 = off2
+				}
+				if i1+i2 < bbest {
+					bbest = i1 + i2
+					bbest1 = i1
+				}
+			}
+			if (lim1+lim2)-bbest < fbest-(off1+off2) {
+				return diffSplit{i1: fbest1, i2: fbest - fbest1, minLo: true, minHi: false}
+			}
+			return diffSplit{i1: bbest1, i2: bbest - bbest1, minLo: false, minHi: true}
+		}
+	}
+}
+
+// lineGroup is a range of changed lines [start, end) using indexes with sentinel, so first line is at 1. Empty group is a position between unchanged lines.
+type lineGroup struct {
+	start int
+	end   int
+}
+
+func groupInit(ch []bool) (g lineGroup) {
+	g.start = 1
+	g.end = 1
+	for ch[g.end] {
+		g.end++
+	}
+	return
+}
+
+func groupNext(ch []bool, g *lineGroup) bool {
+	if g.end == len(ch)-1 {
+		return false
+	}
+	g.start = g.end + 1
+	g.end = g.start
+	for ch[g.end] {
+		g.end++
+	}
+	return true
+}
+
+func groupPrevious(ch []bool, g *lineGroup) bool {
+	if g.start == 1 {
+		return false
+	}
+	g.end = g.start - 1
+	g.start = g.end
+	for ch[g.start-1] {
+		g.start--
+	}
+	return true
+}
+
+func groupSlideDown(recs []int, ch []bool, g *lineGroup) bool {
+	if g.end < len(ch)-1 && recs[g.start-1] == recs[g.end-1] {
+		ch[g.start] = false
+		ch[g.end] = true
+		g.start++
+		g.end++
+		for ch[g.end] {
+			g.end++
+		}
+		return true
+	}
+	return false
+}
+
+func groupSlideUp(recs []int, ch []bool, g *lineGroup) bool {
+	if g.start > 1 && recs[g.start-2] == recs[g.end-2] {
+		g.start--
+		g.end--
+		ch[g.start] = true
+		ch[g.end] = false
+		for ch[g.start-1] {
+			g.start--
+		}
+		return true
+	}
+	return false
+}
+
+// maxIndentSliding limits the number of positions checked by indent heuristic for each group
+const maxIndentSliding = 100
+
+// compactChanges slides groups of changed lines in file to merge them where possible and to align them with changes in the other file. Remaining ambiguous groups are placed using indent heuristic. Port of xdl_change_compact from git.
+func compactChanges(recs []int, ch, other []bool, lines [][]byte) {
+	g := groupInit(ch)
+	og := groupInit(other)
+	mustMove := func(ok bool) {
+		if !ok {
+			panic("line diff groups are out of sync")
+		}
+	}
+
+	for {
+		if g.end != g.start {
+			var groupSize, earliestEnd, endMatchingOther int
+			for {
+				groupSize = g.end - g.start
+				endMatchingOther = -1
+				// shift the group backward as much as possible
+				for groupSlideUp(recs, ch, &g) {
+					mustMove(groupPrevious(other, &og))
+				}
+				earliestEnd = g.end
+				if og.end > og.start {
+					endMatchingOther = g.end
+				}
+				// now shift the group forward as far as possible
+				for groupSlideDown(recs, ch, &g) {
+					mustMove(groupNext(other, &og))
+					if og.end > og.start {
+						endMatchingOther = g.end
+					}
+				}
+				// repeat if group got merged with adjacent one
+				if groupSize == g.end-g.start {
+					break
+				}
+			}
+
+			if g.end == earliestEnd {
+				// no shifting was possible
+			} else if endMatchingOther != -1 {
+				// align with the group in the other file
+				for og.end == og.start {
+					mustMove(groupSlideUp(recs, ch, &g))
+					mustMove(groupPrevious(other, &og))
+				}
+			} else {
+				shift := earliestEnd
+				if g.end-groupSize-1 > shift {
+					shift = g.end - groupSize - 1
+				}
+				if g.end-maxIndentSliding > shift {
+					shift = g.end - maxIndentSliding
+				}
+				bestShift := -1
+				var best splitScore
+				for ; shift <= g.end; shift++ {
+					var score splitScore
+					score.add(measureSplit(lines, shift-1))
+					score.add(measureSplit(lines, shift-1-groupSize))
+					if bestShift == -1 || score.cmp(best) <= 0 {
+						best = score
+						bestShift = shift
+					}
+				}
+				for g.end > bestShift {
+					mustMove(groupSlideUp(recs, ch, &g))
+					mustMove(groupPrevious(other, &og))
+				}
+			}
+		}
+		if !groupNext(ch, &g) {
+			break
+		}
+		mustMove(groupNext(other, &og))
+	}
+}
+
+const (
+	maxIndent = 200
+	maxBlanks = 20
+
+	startOfFilePenalty              = 1
+	endOfFilePenalty                = 21
+	totalBlankWeight                = -30
+	postBlankWeight                 = 6
+	relativeIndentPenalty           = -4
+	relativeIndentWithBlankPenalty  = 10
+	relativeOutdentPenalty          = 24
+	relativeOutdentWithBlankPenalty = 17
+	relativeDedentPenalty           = 23
+	relativeDedentWithBlankPenalty  = 17
+	indentWeight                    = 60
+)
+
+// lineIndent returns indent of line with tabs expanded, or -1 if line has only whitespace.
+func lineIndent(line []byte) int {
+	res := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			res++
+		case '\t':
+			res += 8 - res%8
+		case '\n', '\r', '\f', '\v':
+		default:
+			return res
+		}
+		if res >= maxIndent {
+			return maxIndent
+		}
+	}
+	return -1
+}
+
+type splitMeasure struct {
+	endOfFile  bool
+	indent     int
+	preBlank   int
+	preIndent  int
+	postBlank  int
+	postIndent int
+}
+
+// measureSplit describes lines around split placed before line at index split.
+func measureSplit(lines [][]byte, split int) (m splitMeasure) {
+	if split >= len(lines) {
+		m.endOfFile = true
+		m.indent = -1
+	} else {
+		m.indent = lineIndent(lines[split])
+	}
+	m.preIndent = -1
+	for i := split - 1; i >= 0; i-- {
+		m.preIndent = lineIndent(lines[i])
+		if m.preIndent != -1 {
+			break
+		}
+		m.preBlank++
+		if m.preBlank == maxBlanks {
+			m.preIndent = 0
+			break
+		}
+	}
+	m.postIndent = -1
+	for i := split + 1; i < len(lines); i++ {
+		m.postIndent = lineIndent(lines[i])
+		if m.postIndent != -1 {
+			break
+		}
+		m.postBlank++
+		if m.postBlank == maxBlanks {
+			m.postIndent = 0
+			break
+		}
+	}
+	return
+}
+
+type splitScore struct {
+	effectiveIndent int
+	penalty         int
+}
+
+func (s *splitScore) add(m splitMeasure) {
+	if m.preIndent == -1 && m.preBlank == 0 {
+		s.penalty += startOfFilePenalty
+	}
+	if m.endOfFile {
+		s.penalty += endOfFilePenalty
+	}
+	postBlank := 0
+	if m.indent == -1 {
+		postBlank = 1 + m.postBlank
+	}
+	totalBlank := m.preBlank + postBlank
+	s.penalty += totalBlankWeight * totalBlank
+	s.penalty += postBlankWeight * postBlank
+
+	indent := m.indent
+	if indent == -1 {
+		indent = m.postIndent
+	}
+	anyBlanks := totalBlank != 0
+	s.effectiveIndent += indent
+
+	switch {
+	case indent == -1 || m.preIndent == -1 || indent == m.preIndent:
+	case indent > m.preIndent:
+		if anyBlanks {
+			s.penalty += relativeIndentWithBlankPenalty
+		} else {
+			s.penalty += relativeIndentPenalty
+		}
+	case m.postIndent != -1 && m.postIndent > indent:
+		if anyBlanks {
+			s.penalty += relativeOutdentWithBlankPenalty
+		} else {
+			s.penalty += relativeOutdentPenalty
+		}
+	default:
+		if anyBlanks {
+			s.penalty += relativeDedentWithBlankPenalty
+		} else {
+			s.penalty += relativeDedentPenalty
+		}
+	}
+}
+
+func (s splitScore) cmp(s2 splitScore) int {
+	cmpIndents := 0
+	if s.effectiveIndent > s2.effectiveIndent {
+		cmpIndents = 1
+	} else if s.effectiveIndent < s2.effectiveIndent {
+		cmpIndents = -1
+	}
+	return indentWeight*cmpIndents + (s.penalty - s2.penalty)
+}
@@ -0,0 +1,119 @@
+package incblame
+
+import (
+	"bytes"
+	"unicode"
+)
+
+// DefaultMoveMinChars is the minimum number of alphanumeric chars in a block of lines for it to be considered moved or copied. Same as the default for git blame -M.
+const DefaultMoveMinChars = 20
+
+// maxMoveCandidates limits the number of source positions checked for each line, to avoid slowdown on very common lines
+const maxMoveCandidates = 100
+
+type movePos struct {
+	source int
+	line   int
+}
+
+// MoveSources is an index of lines in files that moved or copied lines could come from. Build it once per commit with NewMoveSources and use for all changed files.
+type MoveSources struct {
+	sources []Blame
+	index   map[string][]movePos
+}
+
+// NewMoveSources indexes lines of sources. Sources are usually the blames of files changed in the commit taken from the parent commit. Binary files are skipped.
+func NewMoveSources(sources []Blame) *MoveSources {
+	s := &MoveSources{}
+	s.sources = sources
+	s.index = map[string][]movePos{}
+	for si, src := range sources {
+		if src.IsBinary {
+			continue
+		}
+		for li, l := range src.Lines {
+			k := string(bytes.TrimSpace(l.Line))
+			if k == "" || len(s.index[k]) >= maxMoveCandidates {
+				continue
+			}
+			s.index[k] = append(s.index[k], movePos{si, li})
+		}
+	}
+	return s
+}
+
+// Empty returns true if there are no lines to look for moves in.
+func (s *MoveSources) Empty() bool {
+	return len(s.index) == 0
+}
+
+// DetectMoves returns a copy of file blame where blocks of lines added in commit that also exist in one of the sources keep the commit from the source, similar to git blame -M -C.
+// Lines are compared ignoring leading and trailing whitespace. Blocks with less than minChars alphanumeric chars are not reassigned.
+func DetectMoves(file Blame, commit string, sources *MoveSources, minChars int) Blame {
+	if file.IsBinary || sources.Empty() {
+		return file
+	}
+	if minChars <= 0 {
+		minChars = DefaultMoveMinChars
+	}
+
+	added := false
+	for _, l := range file.Lines {
+		if l.Commit == commit {
+			added = true
+			break
+		}
+	}
+	if !added {
+		return file
+	}
+
+	res := Blame{Commit: file.Commit}
+	res.Lines = make(Lines, len(file.Lines))
+	copy(res.Lines, file.Lines)
+
+	for i := 0; i < len(res.Lines); {
+		if res.Lines[i].Commit != commit {
+			i++
+			continue
+		}
+		k := string(bytes.TrimSpace(res.Lines[i].Line))
+		var best movePos
+		bestLen := 0
+		for _, pos := range sources.index[k] {
+			src := sources.sources[pos.source].Lines
+			n := 0
+			for i+n < len(res.Lines) && pos.line+n < len(src) &&
+				res.Lines[i+n].Commit == commit &&
+				bytes.Equal(bytes.TrimSpace(res.Lines[i+n].Line), bytes.TrimSpace(src[pos.line+n].Line)) {
+				n++
+			}
+			if n > bestLen {
+				best = pos
+				bestLen = n
+			}
+		}
+		if bestLen == 0 || alnumChars(res.Lines[i:i+bestLen]) < minChars {
+			i++
+			continue
+		}
+		src := sources.sources[best.source].Lines
+		for j := 0; j < bestLen; j++ {
+			// keep the content of the new file, indentation could be different
+			res.Lines[i+j] = &Line{Line: res.Lines[i+j].Line, Commit: src[best.line+j].Commit}
+		}
+		i += bestLen
+	}
+	return res
+}
+
+func alnumChars(lines Lines) (res int) {
+	for _, l := range lines {
+		for _, r := range string(l.Line) {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				res++
+			}
+		}
+	}
+	return
+}
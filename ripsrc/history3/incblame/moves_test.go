@@ -0,0 +1,95 @@
+package incblame
+
+import (
+	"testing"
+)
+
+func TestDetectMovesBetweenFiles(t *testing.T) {
+	c1 := "c1"
+	c2 := "c2"
+	// function moved from a.go to b.go in c2 with different indentation
+	source := file(c1,
+		line(`package main`, c1),
+		line(``, c1),
+		line(`func moved() {`, c1),
+		line(`	doSomethingUseful()`, c1),
+		line(`}`, c1),
+	)
+	got := file(c2,
+		line(`package main`, c1),
+		line(``, c1),
+		line(`func added() {`, c2),
+		line(`}`, c2),
+		line(`	func moved() {`, c2),
+		line(`		doSomethingUseful()`, c2),
+		line(`	}`, c2),
+	)
+	got = DetectMoves(got, c2, NewMoveSources([]Blame{source}), 0)
+
+	want := file(c2,
+		line(`package main`, c1),
+		line(``, c1),
+		line(`func added() {`, c2),
+		line(`}`, c2),
+		line(`	func moved() {`, c1),
+		line(`		doSomethingUseful()`, c1),
+		line(`	}`, c1),
+	)
+	assertEqualFiles(t, got, want)
+}
+
+func TestDetectMovesKeepsOriginalCommitPerLine(t *testing.T) {
+	c1 := "c1"
+	c2 := "c2"
+	c3 := "c3"
+	source := file(c2,
+		line(`func moved() {`, c1),
+		line(`	doSomethingUseful()`, c2),
+		line(`}`, c1),
+	)
+	got := file(c3,
+		line(`func moved() {`, c3),
+		line(`	doSomethingUseful()`, c3),
+		line(`}`, c3),
+	)
+	got = DetectMoves(got, c3, NewMoveSources([]Blame{source}), 0)
+	want := file(c3,
+		line(`func moved() {`, c1),
+		line(`	doSomethingUseful()`, c2),
+		line(`}`, c1),
+	)
+	assertEqualFiles(t, got, want)
+}
+
+func TestDetectMovesMinChars(t *testing.T) {
+	c1 := "c1"
+	c2 := "c2"
+	source := file(c1,
+		line(`return nil`, c1),
+		line(`}`, c1),
+	)
+	got := file(c2,
+		line(`return nil`, c2),
+		line(`}`, c2),
+	)
+	got = DetectMoves(got, c2, NewMoveSources([]Blame{source}), 0)
+	want := file(c2,
+		line(`return nil`, c2),
+		line(`}`, c2),
+	)
+	assertEqualFiles(t, got, want)
+}
+
+func TestDetectMovesDoesNotMutateInput(t *testing.T) {
+	c1 := "c1"
+	c2 := "c2"
+	source := file(c1,
+		line(`func moved() { doSomethingUseful() }`, c1),
+	)
+	in := file(c2,
+		line(`func moved() { doSomethingUseful() }`, c2),
+	)
+	got := DetectMoves(in, c2, NewMoveSources([]Blame{source}), 0)
+	assertEqualFiles(t, got, file(c2, line(`func moved() { doSomethingUseful() }`, c1)))
+	assertEqualFiles(t, in, file(c2, line(`func moved() { doSomethingUseful() }`, c2)))
+}
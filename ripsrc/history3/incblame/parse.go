@@ -4,6 +4,7 @@ import (
 	"bufio"
 	"bytes"
 	"fmt"
+	"sort"
 )
 
 // Diff is change made to one file.
@@ -21,6 +22,44 @@ func (d Diff) PathOrPrev() string {
 	return d.PathPrev
 }
 
+// AddedLines returns the line numbers in the new file of lines added by diff, starting from 1. Uses the same positions as Apply.
+func (d Diff) AddedLines() (res []int) {
+	if d.IsBinary {
+		return nil
+	}
+	hunks := make([]Hunk, len(d.Hunks))
+	copy(hunks, d.Hunks)
+	sort.Slice(hunks, func(i, j int) bool {
+		return hunks[i].Locations[0].Offset < hunks[j].Locations[0].Offset
+	})
+	oldIndex := 0
+	newIndex := 0
+	for _, h := range hunks {
+		j := h.Locations[0].Offset - 1
+		if j == -1 {
+			j = 0
+		}
+		newIndex += j - oldIndex
+		oldIndex = j
+		for _, b := range bytes.Split(h.Data, []byte("\n")) {
+			if len(b) == 0 {
+				continue
+			}
+			switch b[0] {
+			case ' ', '\t':
+				oldIndex++
+				newIndex++
+			case '-':
+				oldIndex++
+			case '+':
+				newIndex++
+				res = append(res, newIndex)
+			}
+		}
+	}
+	return
+}
+
 // Hunk is a part of the diff describing change to a part of file.
 type Hunk struct {
 	Locations []HunkLocation
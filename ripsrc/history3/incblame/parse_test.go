@@ -316,3 +316,33 @@ Binary files /dev/null and b/main.go differ`
 	got := Parse([]byte(data))
 	assertEqualDiffs(t, got, want)
 }
+
+func TestAddedLines(t *testing.T) {
+	data := `` +
+		`diff --git a/main.go b/main.go
+index 43f9419..1671209 100644
+--- a/main.go
++++ b/main.go
+@@ -1,4 +1,4 @@
+ a
+-b
++B
++B2
+ c
+-d
+@@ -10,2 +10,3 @@
+ j
++k
+ l
+`
+	got := Parse([]byte(data)).AddedLines()
+	want := []int{2, 3, 11}
+	if len(got) != len(want) {
+		t.Fatalf("invalid added lines, got %v want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("invalid added lines, got %v want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package process
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo"
+)
+
+// CheckpointEvery defines how often intermediate checkpoints are written during processing. Checkpoint is written when either limit is reached. Zero value writes checkpoint only at the end.
+type CheckpointEvery struct {
+	// Commits is the number of commits processed since the last checkpoint.
+	Commits int
+	// Duration is the time since the last checkpoint.
+	Duration time.Duration
+}
+
+// IsZero returns true if intermediate checkpoints are disabled.
+func (s CheckpointEvery) IsZero() bool {
+	return s.Commits == 0 && s.Duration == 0
+}
+
+// ParseCheckpointEvery parses number of commits (for example 1000) or duration (for example 10m). Empty string disables intermediate checkpoints.
+func ParseCheckpointEvery(s string) (res CheckpointEvery, _ error) {
+	if s == "" {
+		return
+	}
+	if n, err := strconv.Atoi(s); err == nil {
+		if n < 0 {
+			return res, fmt.Errorf("invalid checkpoint interval %v, number of commits must be positive", s)
+		}
+		res.Commits = n
+		return
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return res, fmt.Errorf("invalid checkpoint interval %v, expected number of commits or duration", s)
+	}
+	res.Duration = d
+	return
+}
+
+// CheckpointsDir returns the directory with checkpoints, inside Opts.CheckpointsDir or RepoDir. Other data kept between runs is stored there as well.
+func (s *Process) CheckpointsDir() string {
+	return s.checkpointsDir
+}
+
+// LastCheckpointCommit returns the last processed commit stored in checkpoint, or empty string if there is no checkpoint. Pass it as CommitFromIncl to resume.
+func (s *Process) LastCheckpointCommit() (string, error) {
+	return repo.NewCheckpointReader(s.opts.Logger).LastCommit(s.checkpointsDir)
+}
+
+// ResumeHeads returns the commits processed again when resuming from checkpoint at commit: commit and heads of branches that were not merged when checkpoint was written. Returns only commit if checkpoint was written at another commit or could not be read, in which case Run returns the checkpoint error.
+func (s *Process) ResumeHeads(commit string) ([]string, error) {
+	header, err := repo.NewCheckpointReader(s.opts.Logger).Header(s.checkpointsDir)
+	if err != nil {
+		if repo.IsCheckpointInvalid(err) {
+			return []string{commit}, nil
+		}
+		return nil, err
+	}
+	if header.LastCommit != commit {
+		return []string{commit}, nil
+	}
+	return header.ResumeHeads(), nil
+}
+
+// CheckpointHeader describes checkpoint format version, last commit and checksums of data files.
+type CheckpointHeader = repo.CheckpointHeader
+
+// IsCheckpointInvalid returns true if checkpoint could not be used because it is missing, corrupted or in unsupported format. Processing needs to start from the beginning in this case.
+func IsCheckpointInvalid(err error) bool {
+	return repo.IsCheckpointInvalid(err)
+}
+
+// VerifyCheckpoint checks checkpoint header and checksums of data files without loading it. Use IsCheckpointInvalid on returned error to check if checkpoint needs to be rebuilt.
+func (s *Process) VerifyCheckpoint() (CheckpointHeader, error) {
+	return repo.NewCheckpointReader(s.opts.Logger).Verify(s.checkpointsDir)
+}
+
+// CheckpointStore keeps checkpoint bundles outside of local checkpoints dir.
+type CheckpointStore = repo.CheckpointStore
+
+// NewLocalCheckpointStore creates store keeping checkpoint bundle in file at loc.
+func NewLocalCheckpointStore(loc string) CheckpointStore {
+	return repo.NewLocalCheckpointStore(loc)
+}
+
+// FetchCheckpoint restores checkpoint from CheckpointStore into checkpoints dir, replacing local checkpoint. Returns false if store has no checkpoint. Call before Run and LastCheckpointCommit.
+func (s *Process) FetchCheckpoint() (bool, error) {
+	if s.opts.CheckpointStore == nil {
+		return false, nil
+	}
+	start := time.Now()
+	header, ok, err := repo.FetchCheckpoint(s.opts.CheckpointStore, s.checkpointsDir)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		s.opts.Logger.Info("no checkpoint in store")
+		return false, nil
+	}
+	s.opts.Logger.Info("fetched checkpoint from store", "commit", header.LastCommit, "duration", time.Since(start))
+	return true, nil
+}
+
+// maybeWriteCheckpoint writes intermediate checkpoint when CheckpointEvery limit is reached. Called between commits.
+func (s *Process) maybeWriteCheckpoint() error {
+	every := s.opts.CheckpointEvery
+	if every.IsZero() || s.commitsSinceCheckpoint == 0 {
+		return nil
+	}
+	// merge parts are not processed yet
+	if len(s.mergeParts) != 0 {
+		return nil
+	}
+	if (every.Commits > 0 && s.commitsSinceCheckpoint >= every.Commits) ||
+		(every.Duration > 0 && time.Since(s.lastCheckpoint) >= every.Duration) {
+		s.opts.Logger.Info("writing intermediate checkpoint", "commit", s.lastProcessedCommitHash, "commits_since_last", s.commitsSinceCheckpoint, "heads", len(s.heads))
+		return s.writeCheckpoint()
+	}
+	return nil
+}
+
+func (s *Process) writeCheckpoint() error {
+	start := time.Now()
+	writer := repo.NewCheckpointWriter(s.opts.Logger)
+	var heads []string
+	for h := range s.heads {
+		heads = append(heads, h)
+	}
+	err := writer.WriteHeads(s.repo, s.checkpointsDir, s.lastProcessedCommitHash, heads)
+	if err != nil {
+		return err
+	}
+	header, err := repo.NewCheckpointReader(s.opts.Logger).Header(s.checkpointsDir)
+	if err != nil {
+		return err
+	}
+	s.timing.CheckpointSize, s.timing.CheckpointRawSize = header.Size()
+	if s.opts.CheckpointStore != nil {
+		err := repo.PutCheckpoint(s.opts.CheckpointStore, s.checkpointsDir)
+		if err != nil {
+			return fmt.Errorf("could not store checkpoint: %v", err)
+		}
+	}
+	s.commitsSinceCheckpoint = 0
+	s.lastCheckpoint = time.Now()
+	s.lastCheckpointCommit = s.lastProcessedCommitHash
+	s.timing.CheckpointsCount++
+	s.timing.CheckpointsTime += time.Since(start)
+	return nil
+}
@@ -0,0 +1,140 @@
+package process
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/parser"
+)
+
+// DiffSourceType selects where diffs of processed commits come from.
+type DiffSourceType string
+
+const (
+	// DiffSourceGit parses git log -p output. Default.
+	DiffSourceGit DiffSourceType = ""
+	// DiffSourceNative reads trees and blobs from the repo directly and computes line diffs in process. Avoids parsing git text output, which is fragile for unusual file names, encodings and very long lines. Uses the same diff algorithm and rename detection as git, results are expected to be the same.
+	DiffSourceNative DiffSourceType = "native"
+)
+
+// ParseDiffSourceType returns diff source by name. Empty string and git return DiffSourceGit.
+func ParseDiffSourceType(s string) (DiffSourceType, error) {
+	switch DiffSourceType(s) {
+	case DiffSourceGit, "git":
+		return DiffSourceGit, nil
+	case DiffSourceNative:
+		return DiffSourceNative, nil
+	}
+	return "", fmt.Errorf("invalid diff source %v, expected git or native", s)
+}
+
+// diffSource returns commits with changes in processing order, oldest first, same as git log --date-order --reverse. Merge commits are returned once for each parent, with MergeDiffFrom set to the parent.
+type diffSource interface {
+	// Run sends commits to res and closes it when done.
+	Run(res chan parser.Commit) error
+}
+
+func (s *Process) newDiffSource() (diffSource, error) {
+	if s.opts.Patches != nil {
+		if s.opts.DiffSource != DiffSourceGit {
+			s.opts.Patches.Close()
+			return nil, fmt.Errorf("patches could only be used with git diff source, got %v", s.opts.DiffSource)
+		}
+		return gitDiffSource{r: s.opts.Patches}, nil
+	}
+	switch s.opts.DiffSource {
+	case DiffSourceGit:
+		r, err := s.gitLogPatches()
+		if err != nil {
+			return nil, err
+		}
+		return gitDiffSource{r: r}, nil
+	case DiffSourceNative:
+		return newNativeDiffSource(s.opts.RepoDir, s.gitCommand, s.logRangeArgs()), nil
+	}
+	return nil, fmt.Errorf("invalid diff source %v", s.opts.DiffSource)
+}
+
+// parseChange returns diff for change, parsing patch text when diff source returned git log output.
+func parseChange(ch parser.Change) incblame.Diff {
+	if ch.Parsed != nil {
+		return *ch.Parsed
+	}
+	return incblame.Parse(ch.Diff)
+}
+
+// gitDiffSource parses output of git log -p.
+type gitDiffSource struct {
+	r io.ReadCloser
+}
+
+func (s gitDiffSource) Run(res chan parser.Commit) error {
+	defer s.r.Close()
+	return parser.New(s.r).Run(res)
+}
+
+func (s *Process) gitLogPatches() (io.ReadCloser, error) {
+	// empty file at temp location to set an empty attributesFile
+	f, err := ioutil.TempFile("", "ripsrc")
+	if err != nil {
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"-c", "core.attributesFile=" + f.Name(),
+		"-c", "diff.renameLimit=10000",
+		"log",
+		"-p",
+		"-m",
+		"--date-order",
+		"--reverse",
+		"--no-abbrev-commit",
+		"--pretty=short",
+	}
+	args = append(args, s.logRangeArgs()...)
+
+	ctx := context.Background()
+	//if s.opts.DisableCache {
+
+	return gitexec.ExecPiped(ctx, s.gitCommand, s.opts.RepoDir, args)
+	//}
+	//return gitexec.ExecWithCache(ctx, s.gitCommand, s.opts.RepoDir, args)
+}
+
+// logRangeArgs returns git log arguments selecting commits to process. Empty means starting from HEAD.
+func (s *Process) logRangeArgs() (args []string) {
+	if s.opts.CommitFromIncl != "" {
+		if s.opts.AllBranches {
+			for _, c := range s.opts.WantedBranchRefs {
+				args = append(args, c)
+			}
+		}
+		if s.opts.CommitFromMakeNonIncl {
+			args = append(args, s.opts.CommitFromIncl+"..HEAD")
+		} else {
+			// exclude all parents instead of using <commit>^..HEAD, which for merge commit includes commits from other parents that were already processed, and fails for root commit
+			// other heads of branches open in checkpoint are processed again as well
+			heads := s.resumeHeads
+			if len(heads) == 0 {
+				heads = []string{s.opts.CommitFromIncl}
+			}
+			args = append(args, "HEAD")
+			for _, h := range heads {
+				args = append(args, "^"+h+"^@")
+			}
+		}
+	} else {
+		if s.opts.AllBranches {
+			args = append(args, "--all")
+		}
+	}
+	return
+}
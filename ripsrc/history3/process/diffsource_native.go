@@ -0,0 +1,307 @@
+package process
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/parser"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+)
+
+// nativeDiffSource reads commits, trees and blobs using go-git and computes diffs in process. Commit order is taken from git rev-list with the same arguments as git log, so that commits are processed in the same order for both sources.
+type nativeDiffSource struct {
+	repoDir    string
+	gitCommand string
+	rangeArgs  []string
+
+	repo *git.Repository
+	// blobs loaded for the current diff
+	blobs map[plumbing.Hash][]byte
+}
+
+func newNativeDiffSource(repoDir string, gitCommand string, rangeArgs []string) *nativeDiffSource {
+	s := &nativeDiffSource{}
+	s.repoDir = repoDir
+	s.gitCommand = gitCommand
+	s.rangeArgs = rangeArgs
+	return s
+}
+
+func (s *nativeDiffSource) Run(res chan parser.Commit) error {
+	defer close(res)
+
+	commits, err := s.revList()
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+	s.repo, err = git.PlainOpen(s.repoDir)
+	if err != nil {
+		return fmt.Errorf("could not open repo %v: %v", s.repoDir, err)
+	}
+
+	for _, hash := range commits {
+		commit, err := s.repo.CommitObject(plumbing.NewHash(hash))
+		if err != nil {
+			return fmt.Errorf("could not read commit %v: %v", hash, err)
+		}
+		if len(commit.ParentHashes) == 0 {
+			changes, err := s.diff(plumbing.ZeroHash, commit.TreeHash)
+			if err != nil {
+				return fmt.Errorf("could not diff commit %v: %v", hash, err)
+			}
+			res <- parser.Commit{Hash: hash, Changes: changes}
+			continue
+		}
+		for _, p := range commit.ParentHashes {
+			parent, err := s.repo.CommitObject(p)
+			if err != nil {
+				return fmt.Errorf("could not read parent %v of commit %v: %v", p, hash, err)
+			}
+			changes, err := s.diff(parent.TreeHash, commit.TreeHash)
+			if err != nil {
+				return fmt.Errorf("could not diff commit %v with parent %v: %v", hash, p, err)
+			}
+			c := parser.Commit{Hash: hash, Changes: changes}
+			if len(commit.ParentHashes) > 1 {
+				c.MergeDiffFrom = p.String()
+			}
+			res <- c
+		}
+	}
+	return nil
+}
+
+// revList returns commits to process in the same order as git log --date-order --reverse.
+func (s *nativeDiffSource) revList() (res []string, _ error) {
+	args := []string{"rev-list", "--date-order", "--reverse"}
+	if len(s.rangeArgs) == 0 {
+		args = append(args, "HEAD")
+	}
+	args = append(args, s.rangeArgs...)
+	r, err := gitexec.Exec(context.Background(), s.gitCommand, s.repoDir, args)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		res = append(res, scanner.Text())
+	}
+	return res, scanner.Err()
+}
+
+// treeFile is a file or submodule in tree
+type treeFile struct {
+	Path string
+	Mode filemode.FileMode
+	Hash plumbing.Hash
+}
+
+// fileChange is a change to one path. From is nil for added files, To is nil for deleted files. For renames paths are different.
+type fileChange struct {
+	From *treeFile
+	To   *treeFile
+}
+
+func (s fileChange) path() string {
+	if s.To != nil {
+		return s.To.Path
+	}
+	return s.From.Path
+}
+
+// diff returns changes between trees in the same form and order as git log -p, with renames detected.
+func (s *nativeDiffSource) diff(from, to plumbing.Hash) (res []parser.Change, _ error) {
+	var changes []fileChange
+	err := s.diffTrees("", from, to, &changes)
+	if err != nil {
+		return nil, err
+	}
+	s.blobs = map[plumbing.Hash][]byte{}
+	defer func() {
+		s.blobs = nil
+	}()
+	changes, err = s.detectRenames(changes)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changes[i].path() < changes[j].path()
+	})
+
+	add := func(from, to *treeFile) error {
+		var pathPrev, path string
+		var prev, curr []byte
+		var err error
+		if from != nil {
+			pathPrev = from.Path
+			prev, err = s.content(from)
+			if err != nil {
+				return err
+			}
+		}
+		if to != nil {
+			path = to.Path
+			curr, err = s.content(to)
+			if err != nil {
+				return err
+			}
+		}
+		diff := incblame.DiffContents(pathPrev, path, prev, curr)
+		res = append(res, parser.Change{Parsed: &diff})
+		return nil
+	}
+	for _, ch := range changes {
+		if ch.From != nil && ch.To != nil && fileType(ch.From.Mode) != fileType(ch.To.Mode) {
+			// type change is shown as deletion and addition
+			err := add(ch.From, nil)
+			if err != nil {
+				return nil, err
+			}
+			err = add(nil, ch.To)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		err := add(ch.From, ch.To)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
+
+// diffTrees appends changed files between trees to res. Pass plumbing.ZeroHash for missing tree.
+func (s *nativeDiffSource) diffTrees(prefix string, from, to plumbing.Hash, res *[]fileChange) error {
+	if from == to {
+		return nil
+	}
+	fromEntries, err := s.treeEntries(from)
+	if err != nil {
+		return err
+	}
+	toEntries, err := s.treeEntries(to)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for name := range fromEntries {
+		names = append(names, name)
+	}
+	for name := range toEntries {
+		if _, ok := fromEntries[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		a, aok := fromEntries[name]
+		b, bok := toEntries[name]
+		if aok && bok && a.Hash == b.Hash && a.Mode == b.Mode {
+			continue
+		}
+		path := prefix + name
+		aDir := aok && a.Mode == filemode.Dir
+		bDir := bok && b.Mode == filemode.Dir
+		if aDir || bDir {
+			fromTree := plumbing.ZeroHash
+			toTree := plumbing.ZeroHash
+			if aDir {
+				fromTree = a.Hash
+			} else if aok {
+				// file replaced with dir
+				*res = append(*res, fileChange{From: &treeFile{path, a.Mode, a.Hash}})
+			}
+			if bDir {
+				toTree = b.Hash
+			} else if bok {
+				// dir replaced with file
+				*res = append(*res, fileChange{To: &treeFile{path, b.Mode, b.Hash}})
+			}
+			err := s.diffTrees(path+"/", fromTree, toTree, res)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		ch := fileChange{}
+		if aok {
+			ch.From = &treeFile{path, a.Mode, a.Hash}
+		}
+		if bok {
+			ch.To = &treeFile{path, b.Mode, b.Hash}
+		}
+		*res = append(*res, ch)
+	}
+	return nil
+}
+
+func (s *nativeDiffSource) treeEntries(hash plumbing.Hash) (map[string]treeFile, error) {
+	res := map[string]treeFile{}
+	if hash == plumbing.ZeroHash {
+		return res, nil
+	}
+	tree, err := s.repo.TreeObject(hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not read tree %v: %v", hash, err)
+	}
+	for _, e := range tree.Entries {
+		res[e.Name] = treeFile{Path: e.Name, Mode: e.Mode, Hash: e.Hash}
+	}
+	return res, nil
+}
+
+// content returns file content. For submodules returns the same text as git diff.
+func (s *nativeDiffSource) content(f *treeFile) ([]byte, error) {
+	if f.Mode == filemode.Submodule {
+		return []byte("Subproject commit " + f.Hash.String() + "\n"), nil
+	}
+	if data, ok := s.blobs[f.Hash]; ok {
+		return data, nil
+	}
+	blob, err := s.repo.BlobObject(f.Hash)
+	if err != nil {
+		return nil, fmt.Errorf("could not read blob %v for file %v: %v", f.Hash, f.Path, err)
+	}
+	r, err := blob.Reader()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("could not read blob %v for file %v: %v", f.Hash, f.Path, err)
+	}
+	s.blobs[f.Hash] = data
+	return data, nil
+}
+
+// fileType returns mode without permissions, changes between types are not shown as modifications by git
+func fileType(mode filemode.FileMode) filemode.FileMode {
+	switch mode {
+	case filemode.Regular, filemode.Deprecated, filemode.Executable:
+		return filemode.Regular
+	}
+	return mode
+}
+
+func isRegular(mode filemode.FileMode) bool {
+	return fileType(mode) == filemode.Regular
+}
+
+func basename(path string) string {
+	return path[strings.LastIndex(path, "/")+1:]
+}
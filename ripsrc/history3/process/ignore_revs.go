@@ -0,0 +1,140 @@
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+)
+
+// DefaultIgnoreRevsFile is the file with commits to ignore used when blame.ignoreRevsFile is not set in git config.
+const DefaultIgnoreRevsFile = ".git-blame-ignore-revs"
+
+// loadIgnoreRevs adds commits from ignore revs file to s.ignoreRevs.
+func (s *Process) loadIgnoreRevs() error {
+	if s.opts.NoIgnoreRevsFile {
+		return nil
+	}
+	ctx := context.Background()
+	loc := s.opts.IgnoreRevsFile
+	required := loc != ""
+	if loc == "" {
+		loc = s.gitConfig(ctx, "blame.ignoreRevsFile")
+		required = loc != ""
+	}
+	if loc == "" {
+		loc = DefaultIgnoreRevsFile
+	}
+	data, err := s.readRepoFile(ctx, loc)
+	if err != nil {
+		if required {
+			return fmt.Errorf("could not read ignore revs file %v: %v", loc, err)
+		}
+		return nil
+	}
+	var skipInvalid func(n int, line string)
+	if !required {
+		// default file is not set up for ripsrc, so skip lines that git blame would not accept instead of failing
+		skipInvalid = func(n int, line string) {
+			s.opts.Logger.Info("skipping invalid line in ignore revs file", "file", loc, "line", n, "text", line)
+		}
+	}
+	revs, err := parseIgnoreRevs(bytes.NewReader(data), skipInvalid)
+	if err != nil {
+		return fmt.Errorf("invalid ignore revs file %v: %v", loc, err)
+	}
+	if len(revs) != 0 {
+		s.opts.Logger.Info("ignoring commits from ignore revs file", "file", loc, "count", len(revs))
+	}
+	for _, c := range revs {
+		s.ignoreRevs[c] = true
+	}
+	return nil
+}
+
+// gitConfig returns the value of git config key or empty string if not set.
+func (s *Process) gitConfig(ctx context.Context, key string) string {
+	r, err := gitexec.Exec(ctx, s.gitCommand, s.opts.RepoDir, []string{"config", "--get", key})
+	if err != nil {
+		return ""
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readRepoFile reads file from working tree, or from HEAD when it does not exist there (for example in bare repos).
+func (s *Process) readRepoFile(ctx context.Context, loc string) ([]byte, error) {
+	p := loc
+	if !filepath.IsAbs(p) {
+		p = filepath.Join(s.opts.RepoDir, p)
+	}
+	b, err := ioutil.ReadFile(p)
+	if err == nil {
+		return b, nil
+	}
+	if !os.IsNotExist(err) || filepath.IsAbs(loc) {
+		return nil, err
+	}
+	// ls-tree returns no output instead of error for missing files
+	r, err := gitexec.Exec(ctx, s.gitCommand, s.opts.RepoDir, []string{"ls-tree", "HEAD", "--", filepath.ToSlash(loc)})
+	if err != nil {
+		return nil, err
+	}
+	b, err = ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(bytes.TrimSpace(b)) == 0 {
+		return nil, os.ErrNotExist
+	}
+	r, err = gitexec.Exec(ctx, s.gitCommand, s.opts.RepoDir, []string{"cat-file", "blob", "HEAD:" + filepath.ToSlash(loc)})
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.ReadAll(r)
+}
+
+var fullCommitHash = regexp.MustCompile(`^[0-9a-f]{40}$`)
+
+// ParseIgnoreRevs parses file in git blame --ignore-revs-file format. Each line is a full commit hash, empty lines and text after # are skipped.
+func ParseIgnoreRevs(r io.Reader) (res []string, _ error) {
+	return parseIgnoreRevs(r, nil)
+}
+
+// parseIgnoreRevs parses ignore revs file. Invalid lines are passed to skipInvalid if set, otherwise an error is returned.
+func parseIgnoreRevs(r io.Reader, skipInvalid func(n int, line string)) (res []string, _ error) {
+	scanner := bufio.NewScanner(r)
+	n := 0
+	for scanner.Scan() {
+		n++
+		line := scanner.Text()
+		if i := strings.Index(line, "#"); i != -1 {
+			line = line[:i]
+		}
+		line = strings.ToLower(strings.TrimSpace(line))
+		if line == "" {
+			continue
+		}
+		if !fullCommitHash.MatchString(line) {
+			if skipInvalid != nil {
+				skipInvalid(n, line)
+				continue
+			}
+			return nil, fmt.Errorf("line %v: not a full commit hash: %v", n, line)
+		}
+		res = append(res, line)
+	}
+	return res, scanner.Err()
+}
@@ -0,0 +1,44 @@
+package process
+
+import (
+	"fmt"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/parser"
+)
+
+// detectMoves updates blame of changed files in res and repo to keep original commit for lines moved or copied from other files.
+func (s *Process) detectMoves(commit parser.Commit, res Result, changedInParent []string) error {
+	parent := commit.Parents[0]
+
+	paths := changedInParent
+	if s.opts.DetectCopiesAllFiles {
+		paths = s.repo.GetCommitMust(parent).Paths()
+	}
+
+	var sources []incblame.Blame
+	for _, fp := range paths {
+		bl := s.repo.GetFileOptional(parent, fp)
+		if bl == nil || bl.IsBinary {
+			continue
+		}
+		sources = append(sources, *bl)
+	}
+	index := incblame.NewMoveSources(sources)
+	if index.Empty() {
+		return nil
+	}
+
+	for fp, bl := range res.Files {
+		if bl.IsBinary || len(bl.Lines) == 0 {
+			continue
+		}
+		if s.repo.GetFileOptional(commit.Hash, fp) == nil {
+			return fmt.Errorf("changed file not found in repo commit: %v file: %v", commit.Hash, fp)
+		}
+		moved := incblame.DetectMoves(*bl, commit.Hash, index, incblame.DefaultMoveMinChars)
+		s.repo.SetFile(commit.Hash, fp, &moved)
+		res.Files[fp] = &moved
+	}
+	return nil
+}
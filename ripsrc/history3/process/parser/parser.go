@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"strings"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
 )
 
 type Parser struct {
@@ -50,9 +52,14 @@ func (c Commit) String() string {
 
 type Change struct {
 	Diff []byte
+	// Parsed is the diff computed without git log output, Diff is empty in this case.
+	Parsed *incblame.Diff
 }
 
 func (c Change) String() string {
+	if c.Parsed != nil {
+		return fmt.Sprintf("%+v", *c.Parsed)
+	}
 	return string(c.Diff)
 }
 
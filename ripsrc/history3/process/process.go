@@ -1,10 +1,8 @@
 package process
 
 import (
-	"context"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"sort"
@@ -17,7 +15,6 @@ import (
 
 	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo"
 
-	"github.com/pinpt/ripsrc/ripsrc/gitexec"
 	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
 	"github.com/pinpt/ripsrc/ripsrc/history3/process/parser"
 )
@@ -31,6 +28,11 @@ type Process struct {
 	childrenProcessed  map[string]int
 	maxLenOfStoredTree int
 
+	// heads are the processed commits without processed children, stored in checkpoint so that resume processes all commits that are not their ancestors
+	heads map[string]bool
+	// resumeHeads are the heads from checkpoint when starting from CommitFromIncl, processed again together with their descendants
+	resumeHeads []string
+
 	mergePartsCommit string
 	// map[parent_diffed]parser.Commit
 	mergeParts map[string]parser.Commit
@@ -43,6 +45,12 @@ type Process struct {
 	checkpointsDir string
 
 	lastProcessedCommitHash string
+
+	commitsSinceCheckpoint int
+	lastCheckpoint         time.Time
+	lastCheckpointCommit   string
+
+	ignoreRevs map[string]bool
 }
 
 type Opts struct {
@@ -73,11 +81,54 @@ type Opts struct {
 
 	// ParentsGraph is optional graph of commits. Pass to reuse, if not passed will be created.
 	ParentsGraph *parentsgraph.Graph
+
+	// DetectMoves set to true to keep the original commit for lines moved or copied from files changed in the same commit, similar to git blame -M -C. Only applies to regular (non-merge) commits.
+	DetectMoves bool
+
+	// DetectCopiesAllFiles set to true to also look for copied lines in all files of the parent commit, similar to git blame -C -C -C. Requires DetectMoves. Slow for large repos.
+	DetectCopiesAllFiles bool
+
+	// IgnoreWhitespace set to true to keep the previous commit for lines where only whitespace changed.
+	IgnoreWhitespace bool
+
+	// IgnoreRevs are full commit hashes that should not take ownership of changed lines, for example reformatting commits. Changed lines keep the previous commit, only new lines are attributed to these commits. Only applies to regular (non-merge) commits.
+	IgnoreRevs []string
+
+	// IgnoreRevsFile is the file with commits to add to IgnoreRevs, in git blame --ignore-revs-file format. Relative paths are read from the working tree, or from HEAD in bare repos.
+	// If empty, blame.ignoreRevsFile from git config is used, and then .git-blame-ignore-revs if it exists. Invalid lines in .git-blame-ignore-revs are logged and skipped, while invalid lines in a file set in options or git config return an error.
+	IgnoreRevsFile string
+
+	// NoIgnoreRevsFile set to true to skip reading ignore revs file. IgnoreRevs are still used.
+	NoIgnoreRevsFile bool
+
+	// MaxMemoryMB is the approximate memory budget for blame data. When exceeded, least recently used commits are moved to disk inside checkpoints dir. Default is 0, keeping all data in memory.
+	MaxMemoryMB int
+
+	// CheckpointEvery enables writing intermediate checkpoints during processing, so interrupted run could resume from the latest one using LastCheckpointCommit as CommitFromIncl. By default checkpoint is written only at the end. Checkpoint stores heads of branches that are not merged yet, resume processes them again together with all commits that are not their ancestors.
+	CheckpointEvery CheckpointEvery
+
+	// CheckpointStore receives checkpoint bundle after each checkpoint is written. Use FetchCheckpoint to restore checkpoint from store before Run. Optional.
+	CheckpointStore CheckpointStore
+
+	// DiffSource selects where diffs of processed commits come from. Default is DiffSourceGit, parsing git log -p output.
+	DiffSource DiffSourceType
+
+	// Patches is the git log -p output to parse instead of running git log, in the same format and for the same commits. Used when one git log pass is shared, see singlepass package. Requires DiffSourceGit. Process closes it. Optional.
+	Patches io.ReadCloser
+
+	// AddedLines set to true to return the lines added by diffs in Result.AddedLines.
+	AddedLines bool
 }
 
 type Result struct {
 	Commit string
 	Files  map[string]*incblame.Blame
+	// Ignored is true for commits in ignore revs. Lines changed in these commits keep the previous commit. Merge commits are processed as usual.
+	Ignored bool
+	// Tree has all files of the commit. Tree is not modified by later commits, so it could be used after processing continues.
+	Tree *repo.Tree
+	// AddedLines has the line numbers of lines added by diff for each file, starting from 1. For merges these are the lines added compared to all parents. Lines are included even if blame keeps the previous commit, for example in ignored commits. Only set when Opts.AddedLines is true.
+	AddedLines map[string][]int
 }
 
 func New(opts Opts) *Process {
@@ -97,17 +148,30 @@ func New(opts Opts) *Process {
 		s.checkpointsDir = filepath.Join(opts.RepoDir, "pp-git-cache")
 	}
 
+	s.ignoreRevs = map[string]bool{}
+	for _, c := range opts.IgnoreRevs {
+		s.ignoreRevs[c] = true
+	}
+
 	return s
 }
 
+func (s *Process) applyOpts(commit string) incblame.ApplyOpts {
+	return incblame.ApplyOpts{
+		IgnoreWhitespace: s.opts.IgnoreWhitespace,
+		KeepPrevious:     s.ignoreRevs[commit],
+	}
+}
+
 func (s *Process) Timing() Timing {
 	return *s.timing
 }
 
 func (s *Process) initCheckpoints() error {
 
+	var mem *repo.MemRepo
 	if s.opts.CommitFromIncl == "" {
-		s.repo = repo.New()
+		mem = repo.New()
 	} else {
 		expectedCommit := ""
 		if s.opts.NoStrictResume {
@@ -118,16 +182,34 @@ func (s *Process) initCheckpoints() error {
 		reader := repo.NewCheckpointReader(s.opts.Logger)
 		r, err := reader.Read(s.checkpointsDir, expectedCommit)
 		if err != nil {
+			if repo.IsCheckpointInvalid(err) {
+				// keep type so caller could rebuild from the beginning
+				return err
+			}
 			return fmt.Errorf("Could not read checkpoint: %v", err)
 		}
+		mem = r
+	}
+
+	if s.opts.MaxMemoryMB > 0 {
+		r, err := repo.NewDiskRepo(mem, repo.DiskRepoOpts{
+			Dir:            filepath.Join(s.checkpointsDir, "spill"),
+			MaxMemoryBytes: int64(s.opts.MaxMemoryMB) * 1024 * 1024,
+			Logger:         s.opts.Logger,
+		})
+		if err != nil {
+			return err
+		}
 		s.repo = r
+	} else {
+		s.repo = mem
 	}
 
 	s.unloader = repo.NewUnloader(s.repo)
 	return nil
 }
 
-func (s *Process) Run(resChan chan Result) error {
+func (s *Process) Run(resChan chan Result) (rerr error) {
 	defer func() {
 		close(resChan)
 	}()
@@ -147,16 +229,34 @@ func (s *Process) Run(resChan chan Result) error {
 	}
 
 	s.childrenProcessed = map[string]int{}
+	s.heads = map[string]bool{}
+	if s.opts.CommitFromIncl != "" {
+		s.resumeHeads = []string{s.opts.CommitFromIncl}
+		if !s.opts.CommitFromMakeNonIncl {
+			heads, err := s.ResumeHeads(s.opts.CommitFromIncl)
+			if err != nil {
+				return err
+			}
+			s.resumeHeads = heads
+		}
+		// commits processed before are the ancestors of checkpoint heads
+		for _, h := range s.resumeHeads {
+			s.heads[h] = true
+		}
+	}
+	s.lastCheckpoint = time.Now()
 
-	r, err := s.gitLogPatches()
+	err := s.loadIgnoreRevs()
 	if err != nil {
 		return err
 	}
 
-	defer r.Close()
+	src, err := s.newDiffSource()
+	if err != nil {
+		return err
+	}
 
 	commits := make(chan parser.Commit)
-	p := parser.New(r)
 
 	done := make(chan bool)
 
@@ -164,7 +264,7 @@ func (s *Process) Run(resChan chan Result) error {
 		defer func() {
 			done <- true
 		}()
-		err := p.Run(commits)
+		err := src.Run(commits)
 		if err != nil {
 			panic(err)
 		}
@@ -184,6 +284,13 @@ func (s *Process) Run(resChan chan Result) error {
 				drainAndExit()
 				return err
 			}
+			// repo keeps spilled commits on disk, remove them on all returns
+			defer func() {
+				err := s.repo.Close()
+				if err != nil && rerr == nil {
+					rerr = err
+				}
+			}()
 		}
 		i++
 		commit.Parents = s.graph.Parents[commit.Hash]
@@ -192,6 +299,11 @@ func (s *Process) Run(resChan chan Result) error {
 			drainAndExit()
 			return err
 		}
+		err = s.maybeWriteCheckpoint()
+		if err != nil {
+			drainAndExit()
+			return err
+		}
 	}
 
 	if len(s.mergeParts) > 0 {
@@ -205,11 +317,13 @@ func (s *Process) Run(resChan chan Result) error {
 		return nil
 	}
 
-	writer := repo.NewCheckpointWriter(s.opts.Logger)
-	err = writer.Write(s.repo, s.checkpointsDir, s.lastProcessedCommitHash)
-	if err != nil {
-		<-done
-		return err
+	// intermediate checkpoint could already be written for the last commit
+	if s.lastCheckpointCommit != s.lastProcessedCommitHash {
+		err = s.writeCheckpoint()
+		if err != nil {
+			<-done
+			return err
+		}
 	}
 
 	//fmt.Println("max len of stored tree", s.maxLenOfStoredTree)
@@ -220,21 +334,39 @@ func (s *Process) Run(resChan chan Result) error {
 
 func (s *Process) trimGraphAfterCommitProcessed(commit string) {
 	parents := s.graph.Parents[commit]
+	s.heads[commit] = true
 	for _, p := range parents {
 		s.childrenProcessed[p]++ // mark commit as processed
-		siblings := s.graph.Children[p]
-		if s.childrenProcessed[p] == len(siblings) {
-			// done with parent, can delete it
-			s.unloader.Unload(p)
+		if !s.heads[p] {
+			continue
+		}
+		delete(s.heads, p)
+		// parents of heads are kept, since heads are processed again when resuming from checkpoint
+		for _, gp := range s.graph.Parents[p] {
+			s.maybeUnload(gp)
 		}
 	}
-	//commitsInMemory := s.repo.CommitsInMemory()
-	commitsInMemory := len(s.repo)
+	s.commitsSinceCheckpoint++
+	commitsInMemory := s.repo.CommitsInMemory()
 	if commitsInMemory > s.maxLenOfStoredTree {
 		s.maxLenOfStoredTree = commitsInMemory
 	}
 }
 
+// maybeUnload deletes commit from repo when all its children are processed and none of them is a head.
+func (s *Process) maybeUnload(commit string) {
+	children := s.graph.Children[commit]
+	if s.childrenProcessed[commit] != len(children) {
+		return
+	}
+	for _, ch := range children {
+		if s.heads[ch] {
+			return
+		}
+	}
+	s.unloader.Unload(commit)
+}
+
 func (s *Process) processCommit(resChan chan Result, commit parser.Commit) error {
 	if len(s.mergeParts) > 0 {
 		// continuing with merge
@@ -261,16 +393,38 @@ func (s *Process) processCommit(resChan chan Result, commit parser.Commit) error
 	if err != nil {
 		return err
 	}
+	res.Tree = s.repo.GetCommitMust(commit.Hash)
 	s.trimGraphAfterCommitProcessed(commit.Hash)
 	resChan <- res
 	return nil
 }
 
+// addedInAllDiffs returns lines added in all diffs of merge, which are the lines not coming from any of the parents
+func addedInAllDiffs(diffs []*incblame.Diff) (res []int) {
+	counts := map[int]int{}
+	for _, diff := range diffs {
+		if diff == nil {
+			return nil
+		}
+		for _, l := range diff.AddedLines() {
+			counts[l]++
+		}
+	}
+	for l, c := range counts {
+		if c == len(diffs) {
+			res = append(res, l)
+		}
+	}
+	sort.Ints(res)
+	return
+}
+
 func (s *Process) processGotMergeParts(resChan chan Result) {
 	res, err := s.processMergeCommit(s.mergePartsCommit, s.mergeParts)
 	if err != nil {
 		panic(err)
 	}
+	res.Tree = s.repo.GetCommitMust(s.mergePartsCommit)
 	s.trimGraphAfterCommitProcessed(s.mergePartsCommit)
 	s.mergeParts = nil
 	resChan <- res
@@ -281,6 +435,10 @@ type Timing struct {
 	RegularCommitsTime  time.Duration
 	MergesCount         int
 	MergesTime          time.Duration
+	CheckpointsCount    int
+	CheckpointsTime     time.Duration
+	CheckpointSize      int64
+	CheckpointRawSize   int64
 	SlowestCommits      []CommitWithDuration
 }
 
@@ -326,6 +484,10 @@ func (s *Timing) OutputStats(wr io.Writer) {
 	fmt.Fprintln(wr, "time in regular commits", s.RegularCommitsTime)
 	fmt.Fprintln(wr, "merges", s.MergesCount)
 	fmt.Fprintln(wr, "time in merges commits", s.MergesTime)
+	fmt.Fprintln(wr, "checkpoints", s.CheckpointsCount)
+	fmt.Fprintln(wr, "time in checkpoints", s.CheckpointsTime)
+	fmt.Fprintln(wr, "checkpoint size", s.CheckpointSize)
+	fmt.Fprintln(wr, "checkpoint size uncompressed", s.CheckpointRawSize)
 	fmt.Fprintf(wr, "time in %v slowest commits %v\n", len(s.SlowestCommits), s.SlowestCommitsDur())
 	fmt.Fprintln(wr, "slowest commits")
 	for _, c := range s.SlowestCommits {
@@ -349,16 +511,41 @@ func (s *Process) processRegularCommit(commit parser.Commit) (res Result, rerr e
 		panic("not a regular commit")
 	}
 	// note that commit exists (important for empty commits)
-	s.repo.AddCommit(commit.Hash)
+	// start with files of parent, only changed files are updated below
+	if len(commit.Parents) == 0 {
+		s.repo.AddCommit(commit.Hash)
+	} else {
+		s.repo.ForkCommit(commit.Hash, commit.Parents[0])
+	}
 
 	//fmt.Println("processing regular commit", commit.Hash)
 	res.Commit = commit.Hash
+	res.Ignored = s.ignoreRevs[commit.Hash]
 	res.Files = map[string]*incblame.Blame{}
+	if s.opts.AddedLines {
+		res.AddedLines = map[string][]int{}
+	}
+
+	// files in parent changed in this commit, used as sources for move detection
+	var changedInParent []string
+
+	// files removed and changed in this commit, removed files are deleted from repo after processing all changes
+	removed := map[string]bool{}
+	changed := map[string]bool{}
 
 	for _, ch := range commit.Changes {
 
 		//fmt.Printf("%+v\n", string(ch.Diff))
-		diff := incblame.Parse(ch.Diff)
+		diff := parseChange(ch)
+		if diff.PathPrev != "" {
+			changedInParent = append(changedInParent, diff.PathPrev)
+		}
+		if s.opts.AddedLines && diff.Path != "" && !diff.IsBinary {
+			res.AddedLines[diff.Path] = diff.AddedLines()
+		}
+		if diff.Path != "" {
+			changed[diff.Path] = true
+		}
 
 		if diff.IsBinary {
 			// do not keep actual lines, but show in result
@@ -368,10 +555,11 @@ func (s *Process) processRegularCommit(commit parser.Commit) (res Result, rerr e
 				p := diff.PathPrev
 				res.Files[p] = bl
 				// removal
+				removed[p] = true
 			} else {
 				p := diff.Path
 				res.Files[p] = bl
-				s.repo[commit.Hash][p] = bl
+				s.repo.SetFile(commit.Hash, p, bl)
 			}
 			continue
 		}
@@ -380,19 +568,20 @@ func (s *Process) processRegularCommit(commit parser.Commit) (res Result, rerr e
 		if diff.Path == "" {
 			// file removed, no longer need to keep blame reference, but showcase the file in res.Files using PathPrev
 			res.Files[diff.PathPrev] = &incblame.Blame{Commit: commit.Hash}
+			removed[diff.PathPrev] = true
 			continue
 		}
 
 		// TODO: test renames here as well
 
 		if diff.Path == "" {
-			panic(fmt.Errorf("commit diff does not specify Path: %v diff: %v", commit.Hash, string(ch.Diff)))
+			panic(fmt.Errorf("commit diff does not specify Path: %v diff: %v", commit.Hash, ch.String()))
 		}
 
 		// this is a rename
 		if diff.PathPrev != "" && diff.PathPrev != diff.Path {
 			if len(commit.Parents) != 1 {
-				panic(fmt.Errorf("rename with more than 1 parent (merge) not supported: %v diff: %v", commit.Hash, string(ch.Diff)))
+				panic(fmt.Errorf("rename with more than 1 parent (merge) not supported: %v diff: %v", commit.Hash, ch.String()))
 			}
 			// rename with no patch
 			if len(diff.Hunks) == 0 {
@@ -403,7 +592,7 @@ func (s *Process) processRegularCommit(commit parser.Commit) (res Result, rerr e
 					return
 				}
 				if pb.IsBinary {
-					s.repo[commit.Hash][diff.Path] = pb
+					s.repo.SetFile(commit.Hash, diff.Path, pb)
 					res.Files[diff.Path] = pb
 					continue
 				}
@@ -447,33 +636,30 @@ func (s *Process) processRegularCommit(commit parser.Commit) (res Result, rerr e
 				}
 				blame = bl
 			} else {
-				blame = incblame.Apply(*parentBlame, diff, commit.Hash, diff.PathOrPrev())
+				blame = incblame.ApplyWithOpts(*parentBlame, diff, commit.Hash, diff.PathOrPrev(), s.applyOpts(commit.Hash))
 			}
 		}
-		s.repo[commit.Hash][diff.Path] = &blame
+		s.repo.SetFile(commit.Hash, diff.Path, &blame)
 		res.Files[diff.Path] = &blame
 	}
 
 	if len(commit.Parents) == 0 {
-		// no need to copy files from prev
+		// no parent files to remove or move from
 		return
 	}
 
-	// copy unchanged from prev
-	p := commit.Parents[0]
-	files := s.repo.GetCommitMust(p)
-	for fp := range files {
-		// was in the diff changes, nothing to do
-		if _, ok := res.Files[fp]; ok {
-			continue
+	for p := range removed {
+		if !changed[p] {
+			s.repo.DeleteFile(commit.Hash, p)
 		}
-		blame, err := s.repo.GetFileMust(p, fp)
+	}
+
+	if s.opts.DetectMoves {
+		err := s.detectMoves(commit, res, changedInParent)
 		if err != nil {
-			rerr = fmt.Errorf("could not get parent file for unchanged: %v err: %v", commit.Hash, err)
+			rerr = err
 			return
 		}
-		// copy reference
-		s.repo[commit.Hash][fp] = blame
 	}
 
 	return
@@ -492,16 +678,27 @@ func (s *Process) processMergeCommit(commitHash string, parts map[string]parser.
 		s.timing.MergesCount++
 	}()
 
-	// note that commit exists (important for empty commits)
-	s.repo.AddCommit(commitHash)
-
 	//fmt.Println("processing merge commit", commitHash)
 
 	parentHashes := s.graph.Parents[commitHash]
 	parentCount := len(parentHashes)
 
+	// note that commit exists (important for empty commits)
+	// start with files of the first parent, files that differ are updated below
+	if parentCount == 0 {
+		s.repo.AddCommit(commitHash)
+	} else {
+		s.repo.ForkCommit(commitHash, parentHashes[0])
+	}
+	// files set from diffs in this merge
+	added := map[string]bool{}
+
 	res.Commit = commitHash
+	res.Ignored = s.ignoreRevs[commitHash]
 	res.Files = map[string]*incblame.Blame{}
+	if s.opts.AddedLines {
+		res.AddedLines = map[string][]int{}
+	}
 
 	// parse and organize all diffs for access
 	diffs := map[string][]*incblame.Diff{}
@@ -513,7 +710,7 @@ func (s *Process) processMergeCommit(commitHash string, parts map[string]parser.
 
 	for parHash, part := range parts {
 		for _, ch := range part.Changes {
-			diff := incblame.Parse(ch.Diff)
+			diff := parseChange(ch)
 			key := ""
 			if diff.Path != "" {
 				key = diff.Path
@@ -596,7 +793,8 @@ EACHFILE:
 		// do not try to resolve the diffs for binary files in merge commits
 		if binaryDiffs != 0 || binParentsWithDiffs != 0 {
 			bl := incblame.BlameBinaryFile(commitHash)
-			s.repo[commitHash][k] = bl
+			s.repo.SetFile(commitHash, k, bl)
+			added[k] = true
 			res.Files[k] = bl
 			continue
 		}
@@ -627,12 +825,17 @@ EACHFILE:
 				pb := s.repo.GetFileOptional(parent, k)
 				if pb != nil {
 					// exacly the same as parent, no changes
-					s.repo[commitHash][k] = pb
+					s.repo.SetFile(commitHash, k, pb)
+					added[k] = true
 					continue EACHFILE
 				}
 			}
 		}
 
+		if s.opts.AddedLines {
+			res.AddedLines[k] = addedInAllDiffs(diffs)
+		}
+
 		parents := []incblame.Blame{}
 		for i, diff := range diffs {
 			if diff == nil {
@@ -670,7 +873,8 @@ EACHFILE:
 			diffs2 = append(diffs2, *ob)
 		}
 		blame := incblame.ApplyMerge(parents, diffs2, commitHash, k)
-		s.repo[commitHash][k] = &blame
+		s.repo.SetFile(commitHash, k, &blame)
+		added[k] = true
 
 		// only showing deletes and files changed in merge comparent to at least one parent
 		res.Files[k] = &blame
@@ -681,25 +885,15 @@ EACHFILE:
 	// get a list of all files in all parents
 	files = map[string]bool{}
 	for _, p := range parentHashes {
-		filesInCommit := s.repo.GetCommitMust(p)
-		for f := range filesInCommit {
+		s.repo.GetCommitMust(p).Range(func(f string, _ *incblame.Blame) {
 			files[f] = true
-		}
+		})
 	}
 
 	root := ""
 
 	for f := range files {
-		alreadyAddedAbove := false
-		{
-			bl := s.repo.GetFileOptional(commitHash, f)
-			if bl != nil {
-				alreadyAddedAbove = true
-			}
-
-		}
-
-		if alreadyAddedAbove {
+		if added[f] {
 			continue
 		}
 
@@ -713,8 +907,10 @@ EACHFILE:
 
 		// only one branch has the file
 		if len(candidates) == 1 {
-			// copy reference
-			s.repo[commitHash][f] = candidates[0]
+			// copy reference, unless already there from the first parent
+			if s.repo.GetFileOptional(commitHash, f) != candidates[0] {
+				s.repo.SetFile(commitHash, f, candidates[0])
+			}
 			continue
 		}
 
@@ -755,8 +951,9 @@ EACHFILE:
 				return
 			}
 		}
-		s.repo[commitHash][f] = res2
-
+		if s.repo.GetFileOptional(commitHash, f) != res2 {
+			s.repo.SetFile(commitHash, f, res2)
+		}
 	}
 
 	return
@@ -792,53 +989,3 @@ func (s *Process) RunGetAll() (_ []Result, err error) {
 	<-done
 	return res2, err
 }
-
-func (s *Process) gitLogPatches() (io.ReadCloser, error) {
-	// empty file at temp location to set an empty attributesFile
-	f, err := ioutil.TempFile("", "ripsrc")
-	if err != nil {
-		return nil, err
-	}
-	err = f.Close()
-	if err != nil {
-		return nil, err
-	}
-
-	args := []string{
-		"-c", "core.attributesFile=" + f.Name(),
-		"-c", "diff.renameLimit=10000",
-		"log",
-		"-p",
-		"-m",
-		"--date-order",
-		"--reverse",
-		"--no-abbrev-commit",
-		"--pretty=short",
-	}
-
-	if s.opts.CommitFromIncl != "" {
-		if s.opts.AllBranches {
-			for _, c := range s.opts.WantedBranchRefs {
-				args = append(args, c)
-			}
-		}
-		pf := ""
-		if s.opts.CommitFromMakeNonIncl {
-			pf = "..HEAD"
-		} else {
-			pf = "^..HEAD"
-		}
-		args = append(args, s.opts.CommitFromIncl+pf)
-	} else {
-		if s.opts.AllBranches {
-			args = append(args, "--all")
-		}
-	}
-
-	ctx := context.Background()
-	//if s.opts.DisableCache {
-
-	return gitexec.ExecPiped(ctx, s.gitCommand, s.opts.RepoDir, args)
-	//}
-	//return gitexec.ExecWithCache(ctx, s.gitCommand, s.opts.RepoDir, args)
-}
@@ -0,0 +1,267 @@
+package process
+
+import (
+	"sort"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+)
+
+// Rename detection follows git diffcore-rename, so that the native diff source returns the same renames as git log.
+const (
+	renameMaxScore      = 60000
+	renameMinScore      = 30000 // -M default of 50%
+	renameBasenameScore = 45000 // 75%, used for files with the same unique basename
+	renameLimit         = 10000 // same as diff.renameLimit passed to git log
+	renameCandidates    = 4
+)
+
+// detectRenames pairs deleted and added files into renames. Type changes and submodules are not considered.
+func (s *nativeDiffSource) detectRenames(changes []fileChange) (res []fileChange, _ error) {
+	var srcs, dsts []int
+	for i, ch := range changes {
+		if ch.To == nil && ch.From.Mode != filemode.Submodule {
+			srcs = append(srcs, i)
+		}
+		if ch.From == nil && ch.To.Mode != filemode.Submodule {
+			dsts = append(dsts, i)
+		}
+	}
+	if len(srcs) == 0 || len(dsts) == 0 {
+		return changes, nil
+	}
+
+	// source index in changes for each renamed destination
+	renamedFrom := map[int]int{}
+	used := map[int]bool{}
+
+	// exact renames, prefer source with the same basename
+	for _, d := range dsts {
+		dst := changes[d].To
+		best := -1
+		bestScore := -1
+		for _, sr := range srcs {
+			src := changes[sr].From
+			if src.Hash != dst.Hash || used[sr] {
+				continue
+			}
+			score := 0
+			if basename(src.Path) == basename(dst.Path) {
+				score = 1
+			}
+			if score > bestScore {
+				best = sr
+				bestScore = score
+			}
+		}
+		if best != -1 {
+			renamedFrom[d] = best
+			used[best] = true
+		}
+	}
+	srcs = filterOut(srcs, func(i int) bool { return used[i] })
+	dsts = filterOut(dsts, func(i int) bool { _, ok := renamedFrom[i]; return ok })
+
+	similarity := func(sr, d int) (int, error) {
+		src := changes[sr].From
+		dst := changes[d].To
+		if !isRegular(src.Mode) || !isRegular(dst.Mode) {
+			return 0, nil
+		}
+		a, err := s.content(src)
+		if err != nil {
+			return 0, err
+		}
+		b, err := s.content(dst)
+		if err != nil {
+			return 0, err
+		}
+		return similarityScore(a, b), nil
+	}
+
+	// files with the same basename, when it is unique in both sources and destinations
+	if len(srcs) > 0 && len(dsts) > 0 {
+		srcByName := uniqueBasenames(changes, srcs, true)
+		dstByName := uniqueBasenames(changes, dsts, false)
+		for _, sr := range srcs {
+			name := basename(changes[sr].From.Path)
+			si, ok := srcByName[name]
+			if !ok || si != sr {
+				continue
+			}
+			d, ok := dstByName[name]
+			if !ok {
+				continue
+			}
+			score, err := similarity(sr, d)
+			if err != nil {
+				return nil, err
+			}
+			if score >= renameBasenameScore {
+				renamedFrom[d] = sr
+				used[sr] = true
+			}
+		}
+		srcs = filterOut(srcs, func(i int) bool { return used[i] })
+		dsts = filterOut(dsts, func(i int) bool { _, ok := renamedFrom[i]; return ok })
+	}
+
+	// similarity matrix for the remaining files
+	if len(srcs) > 0 && len(dsts) > 0 && len(srcs)*len(dsts) <= renameLimit*renameLimit {
+		type candidate struct {
+			src, dst  int
+			score     int
+			nameScore int
+		}
+		better := func(a, b candidate) bool {
+			if a.score != b.score {
+				return a.score > b.score
+			}
+			return a.nameScore > b.nameScore
+		}
+		var all []candidate
+		for _, d := range dsts {
+			var top []candidate
+			for _, sr := range srcs {
+				score, err := similarity(sr, d)
+				if err != nil {
+					return nil, err
+				}
+				if score < renameMinScore {
+					continue
+				}
+				c := candidate{src: sr, dst: d, score: score}
+				if basename(changes[sr].From.Path) == basename(changes[d].To.Path) {
+					c.nameScore = 1
+				}
+				if len(top) < renameCandidates {
+					top = append(top, c)
+					continue
+				}
+				worst := 0
+				for i := 1; i < len(top); i++ {
+					if better(top[worst], top[i]) {
+						worst = i
+					}
+				}
+				if better(c, top[worst]) {
+					top[worst] = c
+				}
+			}
+			all = append(all, top...)
+		}
+		sort.SliceStable(all, func(i, j int) bool {
+			return better(all[i], all[j])
+		})
+		for _, c := range all {
+			if used[c.src] {
+				continue
+			}
+			if _, ok := renamedFrom[c.dst]; ok {
+				continue
+			}
+			renamedFrom[c.dst] = c.src
+			used[c.src] = true
+		}
+	}
+
+	for i, ch := range changes {
+		if used[i] {
+			continue
+		}
+		if sr, ok := renamedFrom[i]; ok {
+			ch.From = changes[sr].From
+		}
+		res = append(res, ch)
+	}
+	return res, nil
+}
+
+func filterOut(ind []int, skip func(i int) bool) (res []int) {
+	for _, i := range ind {
+		if !skip(i) {
+			res = append(res, i)
+		}
+	}
+	return
+}
+
+// uniqueBasenames returns change index by basename for basenames used only once
+func uniqueBasenames(changes []fileChange, ind []int, from bool) map[string]int {
+	res := map[string]int{}
+	dup := map[string]bool{}
+	for _, i := range ind {
+		var name string
+		if from {
+			name = basename(changes[i].From.Path)
+		} else {
+			name = basename(changes[i].To.Path)
+		}
+		if _, ok := res[name]; ok {
+			dup[name] = true
+		}
+		res[name] = i
+	}
+	for name := range dup {
+		delete(res, name)
+	}
+	return res
+}
+
+// similarityScore returns content similarity from 0 to renameMaxScore, calculated the same way as git estimate_similarity.
+func similarityScore(a, b []byte) int {
+	maxSize, minSize := len(a), len(b)
+	if maxSize < minSize {
+		maxSize, minSize = minSize, maxSize
+	}
+	if maxSize == 0 {
+		return 0
+	}
+	// too different in size to reach minimum score
+	if maxSize*(renameMaxScore-renameMinScore) < (maxSize-minSize)*renameMaxScore {
+		return 0
+	}
+	ha := spanHashes(a)
+	hb := spanHashes(b)
+	copied := 0
+	for h, ca := range ha {
+		cb := hb[h]
+		if cb < ca {
+			copied += cb
+		} else {
+			copied += ca
+		}
+	}
+	return int(int64(copied) * renameMaxScore / int64(maxSize))
+}
+
+const spanHashBase = 107927
+
+// spanHashes counts bytes in chunks ending with newline or 64 bytes long, grouped by chunk hash. For text \r before \n is ignored.
+func spanHashes(data []byte) map[uint32]int {
+	res := map[uint32]int{}
+	isText := !incblame.IsBinaryContent(data)
+	var accum1, accum2 uint32
+	n := 0
+	for i := 0; i < len(data); i++ {
+		c := uint32(data[i])
+		old1 := accum1
+		if isText && c == '\r' && i+1 < len(data) && data[i+1] == '\n' {
+			continue
+		}
+		accum1 = (accum1 << 7) ^ (accum2 >> 25)
+		accum2 = (accum2 << 7) ^ (old1 >> 25)
+		accum1 += c
+		n++
+		if n < 64 && c != '\n' {
+			continue
+		}
+		res[(accum1+accum2*0x61)%spanHashBase] += n
+		accum1, accum2 = 0, 0
+		n = 0
+	}
+	if n > 0 {
+		res[(accum1+accum2*0x61)%spanHashBase] += n
+	}
+	return res
+}
@@ -0,0 +1,140 @@
+package repo
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// bundleDirName is the temp dir used when extracting bundle, separate from writer tmp dir
+const bundleDirName = "tmp-bundle"
+
+// WriteBundle writes the latest checkpoint in dir to w as a single tar archive compressed with zstd. Used to move checkpoints between machines, see CheckpointStore.
+func WriteBundle(w io.Writer, dir string) error {
+	loc := checkpointLoc(dir)
+	if _, err := readHeader(loc); err != nil {
+		return err
+	}
+	infos, err := ioutil.ReadDir(loc)
+	if err != nil {
+		return err
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name() < infos[j].Name()
+	})
+
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	tw := tar.NewWriter(zw)
+	for _, info := range infos {
+		if !info.Mode().IsRegular() || strings.HasSuffix(info.Name(), ".tmp") {
+			continue
+		}
+		err := writeBundleFile(tw, loc, info)
+		if err != nil {
+			zw.Close()
+			return err
+		}
+	}
+	err = tw.Close()
+	if err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+func writeBundleFile(tw *tar.Writer, dir string, info os.FileInfo) error {
+	f, err := os.Open(filepath.Join(dir, info.Name()))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = tw.WriteHeader(&tar.Header{
+		Name:    info.Name(),
+		Mode:    0644,
+		Size:    info.Size(),
+		ModTime: info.ModTime(),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, f)
+	return err
+}
+
+// ReadBundle extracts bundle written by WriteBundle into dir, replacing existing checkpoint. Bundle is verified before replacing, returns ErrCheckpointCorrupted or ErrCheckpointFormat if it could not be used, keeping existing checkpoint.
+func ReadBundle(r io.Reader, dir string) (CheckpointHeader, error) {
+	tmpDir := filepath.Join(dir, bundleDirName)
+	err := os.RemoveAll(tmpDir)
+	if err != nil {
+		return CheckpointHeader{}, err
+	}
+	err = os.MkdirAll(tmpDir, 0777)
+	if err != nil {
+		return CheckpointHeader{}, err
+	}
+	header, err := readBundle(r, tmpDir)
+	if err != nil {
+		os.RemoveAll(tmpDir)
+		return header, err
+	}
+	err = replaceCheckpoint(filepath.Join(dir, checkpointDirName), filepath.Join(dir, checkpointPrevDirName), tmpDir)
+	return header, err
+}
+
+func readBundle(r io.Reader, dir string) (CheckpointHeader, error) {
+	corrupted := func(err error) error {
+		return ErrCheckpointCorrupted{CheckpointDir: dir, File: "bundle", Err: err}
+	}
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return CheckpointHeader{}, corrupted(err)
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return CheckpointHeader{}, corrupted(err)
+		}
+		if h.Typeflag != tar.TypeReg || h.Name != filepath.Base(h.Name) || h.Name == ".." {
+			return CheckpointHeader{}, corrupted(fmt.Errorf("unexpected entry in bundle %v", h.Name))
+		}
+		err = writeFileFrom(filepath.Join(dir, h.Name), tr)
+		if err != nil {
+			return CheckpointHeader{}, corrupted(err)
+		}
+	}
+	return verifyCheckpoint(dir)
+}
+
+func writeFileFrom(loc string, r io.Reader) error {
+	f, err := os.Create(loc)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, r)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
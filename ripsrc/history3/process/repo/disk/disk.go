@@ -32,3 +32,36 @@ type LineData struct {
 	Pointer uint64 `msg:"p"`
 	Data    []byte `msg:"d"`
 }
+
+// Types below are used since checkpoint format version 3. They are encoded as arrays instead of maps. Commits are stored once in commits file and referenced by position, blames, lines and line data are also referenced by position in their files, starting from 1.
+
+//msgp:tuple Commit Row CompactBlame CompactLine CompactLineData
+
+type Commit struct {
+	Hash string `msg:"h"`
+	// InRepo is true for commits stored in repo, false for commits only referenced by lines.
+	InRepo bool `msg:"r"`
+}
+
+// Row is the file changed in commit compared to the previous commit in repo file. Blame is 0 for files deleted.
+type Row struct {
+	Commit uint32 `msg:"c"`
+	Path   string `msg:"p"`
+	Blame  uint64 `msg:"b"`
+}
+
+type CompactBlame struct {
+	Commit uint32 `msg:"c"`
+	// Lines are positions in lines file, each stored as difference from the previous one. Lines added in the same commit are mostly consecutive, so differences are small.
+	Lines    []int64 `msg:"l"`
+	IsBinary bool    `msg:"ib"`
+}
+
+type CompactLine struct {
+	Commit   uint32 `msg:"c"`
+	LineData uint64 `msg:"ld"`
+}
+
+type CompactLineData struct {
+	Data []byte `msg:"d"`
+}
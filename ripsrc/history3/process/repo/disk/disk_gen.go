@@ -220,6 +220,390 @@ func (z *Blame) Msgsize() (s int) {
 	return
 }
 
+// DecodeMsg implements msgp.Decodable
+func (z *Commit) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 2 {
+		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
+		return
+	}
+	z.Hash, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "Hash")
+		return
+	}
+	z.InRepo, err = dc.ReadBool()
+	if err != nil {
+		err = msgp.WrapError(err, "InRepo")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z Commit) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 2
+	err = en.Append(0x92)
+	if err != nil {
+		return
+	}
+	err = en.WriteString(z.Hash)
+	if err != nil {
+		err = msgp.WrapError(err, "Hash")
+		return
+	}
+	err = en.WriteBool(z.InRepo)
+	if err != nil {
+		err = msgp.WrapError(err, "InRepo")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z Commit) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 2
+	o = append(o, 0x92)
+	o = msgp.AppendString(o, z.Hash)
+	o = msgp.AppendBool(o, z.InRepo)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *Commit) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 2 {
+		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
+		return
+	}
+	z.Hash, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Hash")
+		return
+	}
+	z.InRepo, bts, err = msgp.ReadBoolBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "InRepo")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z Commit) Msgsize() (s int) {
+	s = 1 + msgp.StringPrefixSize + len(z.Hash) + msgp.BoolSize
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *CompactBlame) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.Commit, err = dc.ReadUint32()
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	var zb0002 uint32
+	zb0002, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err, "Lines")
+		return
+	}
+	if cap(z.Lines) >= int(zb0002) {
+		z.Lines = (z.Lines)[:zb0002]
+	} else {
+		z.Lines = make([]int64, zb0002)
+	}
+	for za0001 := range z.Lines {
+		z.Lines[za0001], err = dc.ReadInt64()
+		if err != nil {
+			err = msgp.WrapError(err, "Lines", za0001)
+			return
+		}
+	}
+	z.IsBinary, err = dc.ReadBool()
+	if err != nil {
+		err = msgp.WrapError(err, "IsBinary")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *CompactBlame) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 3
+	err = en.Append(0x93)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint32(z.Commit)
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Lines)))
+	if err != nil {
+		err = msgp.WrapError(err, "Lines")
+		return
+	}
+	for za0001 := range z.Lines {
+		err = en.WriteInt64(z.Lines[za0001])
+		if err != nil {
+			err = msgp.WrapError(err, "Lines", za0001)
+			return
+		}
+	}
+	err = en.WriteBool(z.IsBinary)
+	if err != nil {
+		err = msgp.WrapError(err, "IsBinary")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *CompactBlame) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 3
+	o = append(o, 0x93)
+	o = msgp.AppendUint32(o, z.Commit)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Lines)))
+	for za0001 := range z.Lines {
+		o = msgp.AppendInt64(o, z.Lines[za0001])
+	}
+	o = msgp.AppendBool(o, z.IsBinary)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *CompactBlame) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.Commit, bts, err = msgp.ReadUint32Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	var zb0002 uint32
+	zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Lines")
+		return
+	}
+	if cap(z.Lines) >= int(zb0002) {
+		z.Lines = (z.Lines)[:zb0002]
+	} else {
+		z.Lines = make([]int64, zb0002)
+	}
+	for za0001 := range z.Lines {
+		z.Lines[za0001], bts, err = msgp.ReadInt64Bytes(bts)
+		if err != nil {
+			err = msgp.WrapError(err, "Lines", za0001)
+			return
+		}
+	}
+	z.IsBinary, bts, err = msgp.ReadBoolBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "IsBinary")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *CompactBlame) Msgsize() (s int) {
+	s = 1 + msgp.Uint32Size + msgp.ArrayHeaderSize + (len(z.Lines) * (msgp.Int64Size)) + msgp.BoolSize
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *CompactLine) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 2 {
+		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
+		return
+	}
+	z.Commit, err = dc.ReadUint32()
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	z.LineData, err = dc.ReadUint64()
+	if err != nil {
+		err = msgp.WrapError(err, "LineData")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z CompactLine) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 2
+	err = en.Append(0x92)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint32(z.Commit)
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	err = en.WriteUint64(z.LineData)
+	if err != nil {
+		err = msgp.WrapError(err, "LineData")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z CompactLine) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 2
+	o = append(o, 0x92)
+	o = msgp.AppendUint32(o, z.Commit)
+	o = msgp.AppendUint64(o, z.LineData)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *CompactLine) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 2 {
+		err = msgp.ArrayError{Wanted: 2, Got: zb0001}
+		return
+	}
+	z.Commit, bts, err = msgp.ReadUint32Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	z.LineData, bts, err = msgp.ReadUint64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "LineData")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z CompactLine) Msgsize() (s int) {
+	s = 1 + msgp.Uint32Size + msgp.Uint64Size
+	return
+}
+
+// DecodeMsg implements msgp.Decodable
+func (z *CompactLineData) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 1 {
+		err = msgp.ArrayError{Wanted: 1, Got: zb0001}
+		return
+	}
+	z.Data, err = dc.ReadBytes(z.Data)
+	if err != nil {
+		err = msgp.WrapError(err, "Data")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *CompactLineData) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 1
+	err = en.Append(0x91)
+	if err != nil {
+		return
+	}
+	err = en.WriteBytes(z.Data)
+	if err != nil {
+		err = msgp.WrapError(err, "Data")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *CompactLineData) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 1
+	o = append(o, 0x91)
+	o = msgp.AppendBytes(o, z.Data)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *CompactLineData) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 1 {
+		err = msgp.ArrayError{Wanted: 1, Got: zb0001}
+		return
+	}
+	z.Data, bts, err = msgp.ReadBytesBytes(bts, z.Data)
+	if err != nil {
+		err = msgp.WrapError(err, "Data")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *CompactLineData) Msgsize() (s int) {
+	s = 1 + msgp.BytesPrefixSize + len(z.Data)
+	return
+}
+
 // DecodeMsg implements msgp.Decodable
 func (z *Data) DecodeMsg(dc *msgp.Reader) (err error) {
 	var field []byte
@@ -1284,3 +1668,106 @@ func (z *LineData) Msgsize() (s int) {
 	s = 1 + 2 + msgp.Uint64Size + 2 + msgp.BytesPrefixSize + len(z.Data)
 	return
 }
+
+// DecodeMsg implements msgp.Decodable
+func (z *Row) DecodeMsg(dc *msgp.Reader) (err error) {
+	var zb0001 uint32
+	zb0001, err = dc.ReadArrayHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.Commit, err = dc.ReadUint32()
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	z.Path, err = dc.ReadString()
+	if err != nil {
+		err = msgp.WrapError(err, "Path")
+		return
+	}
+	z.Blame, err = dc.ReadUint64()
+	if err != nil {
+		err = msgp.WrapError(err, "Blame")
+		return
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z Row) EncodeMsg(en *msgp.Writer) (err error) {
+	// array header, size 3
+	err = en.Append(0x93)
+	if err != nil {
+		return
+	}
+	err = en.WriteUint32(z.Commit)
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	err = en.WriteString(z.Path)
+	if err != nil {
+		err = msgp.WrapError(err, "Path")
+		return
+	}
+	err = en.WriteUint64(z.Blame)
+	if err != nil {
+		err = msgp.WrapError(err, "Blame")
+		return
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z Row) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// array header, size 3
+	o = append(o, 0x93)
+	o = msgp.AppendUint32(o, z.Commit)
+	o = msgp.AppendString(o, z.Path)
+	o = msgp.AppendUint64(o, z.Blame)
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *Row) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadArrayHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	if zb0001 != 3 {
+		err = msgp.ArrayError{Wanted: 3, Got: zb0001}
+		return
+	}
+	z.Commit, bts, err = msgp.ReadUint32Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Commit")
+		return
+	}
+	z.Path, bts, err = msgp.ReadStringBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Path")
+		return
+	}
+	z.Blame, bts, err = msgp.ReadUint64Bytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err, "Blame")
+		return
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z Row) Msgsize() (s int) {
+	s = 1 + msgp.Uint32Size + msgp.StringPrefixSize + len(z.Path) + msgp.Uint64Size
+	return
+}
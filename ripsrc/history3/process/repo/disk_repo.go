@@ -0,0 +1,623 @@
+package repo
+
+import (
+	"container/list"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/cespare/xxhash"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo/disk"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+type DiskRepoOpts struct {
+	// Dir is the directory to store commits moved out of memory. Removed on Close.
+	Dir string
+
+	// MaxMemoryBytes is the approximate limit for blame data kept in memory.
+	MaxMemoryBytes int64
+
+	Logger logger.Logger
+}
+
+// DiskRepo keeps recently used commits in memory and moves least recently used commits to disk when memory budget is exceeded. Commits on disk are loaded back on access.
+//
+// Memory use is estimated from unique blames referenced by commits in memory. Lines shared between blames are counted for each blame, so the estimate is higher than actual use.
+//
+// Blames are written to disk once, addressed by hash of their content, and shared by all commits on disk referencing them. A commit on disk only stores paths with blame hashes. Blames loaded from disk are shared with blames of the same content already in memory. Space of blames and commits no longer referenced is reclaimed by compacting the file when it is mostly unused.
+type DiskRepo struct {
+	opts DiskRepoOpts
+
+	mem *MemRepo
+
+	// lru has commits in memory, front is most recently used
+	lru     *list.List
+	lruElem map[string]*list.Element
+
+	// refs is the number of references to blame from commits in memory
+	refs map[*incblame.Blame]int
+	size int64
+
+	// keys has content hash of blames in memory that were written to or loaded from disk, so they are not encoded again
+	keys map[*incblame.Blame]uint64
+	// loaded has blames in memory by content hash, used to share blames loaded from disk
+	loaded map[uint64]*incblame.Blame
+
+	f *os.File
+	// offset is the end of data in file
+	offset int64
+	// live is the size of data in file still referenced
+	live int64
+	// onDisk has location of commits written to disk. Commits not modified since writing are also kept in memory.
+	onDisk map[string]diskLoc
+	// blobs has location of blames written to disk by content hash
+	blobs map[uint64]*diskBlob
+
+	spills      int
+	blobWrites  int
+	loads       int
+	compactions int
+}
+
+type diskLoc struct {
+	offset int64
+	size   int
+}
+
+type diskBlob struct {
+	loc diskLoc
+	// refs is the number of references from commits on disk
+	refs int
+}
+
+// compactMinBytes is the minimum unused space in file before it is compacted
+var compactMinBytes int64 = 64 * 1024 * 1024
+
+// NewDiskRepo creates repo with initial data from mem. Data in mem is moved to disk if it does not fit into memory budget.
+func NewDiskRepo(mem *MemRepo, opts DiskRepoOpts) (*DiskRepo, error) {
+	if opts.Logger == nil {
+		opts.Logger = logger.NewDefaultLogger(os.Stdout)
+	}
+	s := &DiskRepo{}
+	s.opts = opts
+	s.mem = New()
+	s.lru = list.New()
+	s.lruElem = map[string]*list.Element{}
+	s.refs = map[*incblame.Blame]int{}
+	s.keys = map[*incblame.Blame]uint64{}
+	s.loaded = map[uint64]*incblame.Blame{}
+	s.onDisk = map[string]diskLoc{}
+	s.blobs = map[uint64]*diskBlob{}
+
+	err := os.RemoveAll(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(opts.Dir, 0777)
+	if err != nil {
+		return nil, err
+	}
+	s.f, err = os.Create(s.fileLoc())
+	if err != nil {
+		return nil, err
+	}
+
+	for c, files := range mem.commits {
+		s.mem.commits[c] = files
+		files.Range(s.incRefFile)
+		s.touch(c)
+		s.evict(c)
+	}
+	return s, nil
+}
+
+func (s *DiskRepo) AddCommit(commitHash string) {
+	s.Delete(commitHash)
+	s.mem.AddCommit(commitHash)
+	s.touch(commitHash)
+}
+
+func (s *DiskRepo) ForkCommit(commitHash string, parentHash string) {
+	s.load(parentHash)
+	s.Delete(commitHash)
+	s.mem.ForkCommit(commitHash, parentHash)
+	s.mem.commits[commitHash].Range(s.incRefFile)
+	s.touch(commitHash)
+	s.evict(commitHash)
+}
+
+func (s *DiskRepo) SetFile(commitHash string, filePath string, blame *incblame.Blame) {
+	s.load(commitHash)
+	// modified, copy on disk is no longer valid
+	s.removeFromDisk(commitHash)
+	if old := s.mem.GetFileOptional(commitHash, filePath); old != nil {
+		s.decRef(old)
+	}
+	s.mem.SetFile(commitHash, filePath, blame)
+	s.incRef(blame)
+	s.evict(commitHash)
+}
+
+func (s *DiskRepo) DeleteFile(commitHash string, filePath string) {
+	s.load(commitHash)
+	old := s.mem.GetFileOptional(commitHash, filePath)
+	if old == nil {
+		return
+	}
+	s.removeFromDisk(commitHash)
+	s.decRef(old)
+	s.mem.DeleteFile(commitHash, filePath)
+}
+
+func (s *DiskRepo) GetCommitMust(commitHash string) *Tree {
+	s.load(commitHash)
+	return s.mem.GetCommitMust(commitHash)
+}
+
+func (s *DiskRepo) GetFileOptional(commitHash string, filePath string) *incblame.Blame {
+	return s.GetCommitMust(commitHash).Get(filePath)
+}
+
+func (s *DiskRepo) GetFileMust(commitHash string, filePath string) (*incblame.Blame, error) {
+	res := s.GetCommitMust(commitHash).Get(filePath)
+	if res == nil {
+		return nil, fmt.Errorf("file is missing in commit. commit: %v file: %v", commitHash, filePath)
+	}
+	return res, nil
+}
+
+func (s *DiskRepo) Commits() (res []string) {
+	for c := range s.mem.commits {
+		res = append(res, c)
+	}
+	for c := range s.onDisk {
+		if _, ok := s.mem.commits[c]; ok {
+			continue
+		}
+		res = append(res, c)
+	}
+	return
+}
+
+// RangeCommits calls cb with files of each commit. Commits on disk are decoded without loading them into memory, so the order of commits in memory and memory use are not changed. Blames decoded from disk are shared between commits in the same call. Panics if commit does not exist.
+func (s *DiskRepo) RangeCommits(commits []string, cb func(commitHash string, files map[string]*incblame.Blame)) {
+	read := map[uint64]*incblame.Blame{}
+	for _, c := range commits {
+		if files, ok := s.mem.commits[c]; ok {
+			cb(c, files.Map())
+			continue
+		}
+		if _, ok := s.onDisk[c]; !ok {
+			panic(fmt.Errorf("commit not found: %v", c))
+		}
+		files, _, err := s.readCommit(c, read)
+		if err != nil {
+			panic(fmt.Errorf("could not read commit from disk: %v err: %v", c, err))
+		}
+		cb(c, files)
+	}
+}
+
+func (s *DiskRepo) CommitsInMemory() int {
+	return s.mem.CommitsInMemory()
+}
+
+// MemoryBytes returns the estimated memory used by commits in memory.
+func (s *DiskRepo) MemoryBytes() int64 {
+	return s.size
+}
+
+func (s *DiskRepo) Delete(commitHash string) {
+	s.removeFromDisk(commitHash)
+	s.unloadFromMemory(commitHash)
+}
+
+func (s *DiskRepo) Close() error {
+	s.opts.Logger.Info("disk repo stats", "written", s.spills, "blames_written", s.blobWrites, "loaded", s.loads, "compactions", s.compactions, "disk_mb", s.offset/1024/1024)
+	err := s.f.Close()
+	if err != nil {
+		return err
+	}
+	return os.RemoveAll(s.opts.Dir)
+}
+
+func (s *DiskRepo) Debug() string {
+	all := map[string]*Tree{}
+	for _, c := range s.Commits() {
+		if files, ok := s.mem.commits[c]; ok {
+			all[c] = files
+			continue
+		}
+		files, _, err := s.readCommit(c, map[uint64]*incblame.Blame{})
+		if err != nil {
+			panic(err)
+		}
+		all[c] = NewTreeFromFiles(nil, files)
+	}
+	return debugTrees(all)
+}
+
+func (s *DiskRepo) touch(commitHash string) {
+	if e, ok := s.lruElem[commitHash]; ok {
+		s.lru.MoveToFront(e)
+		return
+	}
+	s.lruElem[commitHash] = s.lru.PushFront(commitHash)
+}
+
+// approximate memory used by structs and slices, line data is added separately
+const blameOverhead = 64
+const lineOverhead = 64
+
+func blameSize(bl *incblame.Blame) int64 {
+	res := int64(blameOverhead)
+	for _, l := range bl.Lines {
+		res += lineOverhead + int64(len(l.Line))
+	}
+	return res
+}
+
+func (s *DiskRepo) incRef(bl *incblame.Blame) {
+	s.refs[bl]++
+	if s.refs[bl] == 1 {
+		s.size += blameSize(bl)
+	}
+}
+
+func (s *DiskRepo) incRefFile(filePath string, bl *incblame.Blame) {
+	s.incRef(bl)
+}
+
+func (s *DiskRepo) decRefFile(filePath string, bl *incblame.Blame) {
+	s.decRef(bl)
+}
+
+func (s *DiskRepo) decRef(bl *incblame.Blame) {
+	s.refs[bl]--
+	if s.refs[bl] == 0 {
+		delete(s.refs, bl)
+		s.size -= blameSize(bl)
+		if k, ok := s.keys[bl]; ok {
+			delete(s.keys, bl)
+			if s.loaded[k] == bl {
+				delete(s.loaded, k)
+			}
+		}
+	}
+}
+
+// load loads commit from disk if it is not in memory. Panics if commit does not exist, same as MemRepo.
+func (s *DiskRepo) load(commitHash string) {
+	if _, ok := s.mem.commits[commitHash]; ok {
+		s.touch(commitHash)
+		return
+	}
+	if _, ok := s.onDisk[commitHash]; !ok {
+		panic(fmt.Errorf("commit not found: %v", commitHash))
+	}
+	files, keys, err := s.readCommit(commitHash, map[uint64]*incblame.Blame{})
+	if err != nil {
+		panic(fmt.Errorf("could not load commit from disk: %v err: %v", commitHash, err))
+	}
+	s.loads++
+	for fp, bl := range files {
+		s.setKey(bl, keys[fp])
+	}
+	tree := NewTreeFromFiles(nil, files)
+	s.mem.commits[commitHash] = tree
+	tree.Range(s.incRefFile)
+	s.touch(commitHash)
+	s.evict(commitHash)
+}
+
+// evict moves least recently used commits to disk until memory is within budget. Pinned commit is kept in memory.
+func (s *DiskRepo) evict(pinned string) {
+	for s.size > s.opts.MaxMemoryBytes {
+		e := s.lru.Back()
+		if e == nil {
+			return
+		}
+		c := e.Value.(string)
+		if c == pinned {
+			return
+		}
+		err := s.spill(c)
+		if err != nil {
+			panic(fmt.Errorf("could not write commit to disk: %v err: %v", c, err))
+		}
+	}
+}
+
+func (s *DiskRepo) fileLoc() string {
+	return filepath.Join(s.opts.Dir, "commits")
+}
+
+// spill writes commit to disk if it was modified since last written and removes it from memory. Only blames not yet on disk are written.
+func (s *DiskRepo) spill(commitHash string) error {
+	if _, ok := s.onDisk[commitHash]; !ok {
+		data := &disk.Data{}
+		var err error
+		s.mem.commits[commitHash].Range(func(filePath string, bl *incblame.Blame) {
+			if err != nil {
+				return
+			}
+			var k uint64
+			k, err = s.writeBlob(bl)
+			data.Data = append(data.Data, sDataRow{Path: filePath, BlamePointer: k})
+		})
+		if err != nil {
+			return err
+		}
+		loc, err := s.write(data)
+		if err != nil {
+			return err
+		}
+		s.onDisk[commitHash] = loc
+		s.spills++
+	}
+	s.unloadFromMemory(commitHash)
+	return s.maybeCompact()
+}
+
+// writeBlob writes blame to disk unless blame with the same content is already there, and adds reference to it. Returns content hash of blame.
+func (s *DiskRepo) writeBlob(bl *incblame.Blame) (uint64, error) {
+	k, ok := s.keys[bl]
+	if ok {
+		if b, ok := s.blobs[k]; ok {
+			b.refs++
+			return k, nil
+		}
+	}
+	data, err := encodeBlame(bl).MarshalMsg(nil)
+	if err != nil {
+		return 0, err
+	}
+	k = xxhash.Sum64(data)
+	s.setKey(bl, k)
+	if b, ok := s.blobs[k]; ok {
+		b.refs++
+		return k, nil
+	}
+	loc, err := s.writeBytes(data)
+	if err != nil {
+		return 0, err
+	}
+	s.blobs[k] = &diskBlob{loc: loc, refs: 1}
+	s.blobWrites++
+	return k, nil
+}
+
+// setKey records content hash of blame in memory, so it is not encoded again and blames loaded later could share it.
+func (s *DiskRepo) setKey(bl *incblame.Blame, k uint64) {
+	s.keys[bl] = k
+	if _, ok := s.loaded[k]; !ok {
+		s.loaded[k] = bl
+	}
+}
+
+func (s *DiskRepo) write(data *disk.Data) (diskLoc, error) {
+	b, err := data.MarshalMsg(nil)
+	if err != nil {
+		return diskLoc{}, err
+	}
+	return s.writeBytes(b)
+}
+
+func (s *DiskRepo) writeBytes(b []byte) (diskLoc, error) {
+	_, err := s.f.WriteAt(b, s.offset)
+	if err != nil {
+		return diskLoc{}, err
+	}
+	loc := diskLoc{offset: s.offset, size: len(b)}
+	s.offset += int64(len(b))
+	s.live += int64(len(b))
+	return loc, nil
+}
+
+func (s *DiskRepo) readBytes(loc diskLoc) ([]byte, error) {
+	b := make([]byte, loc.size)
+	_, err := s.f.ReadAt(b, loc.offset)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+func (s *DiskRepo) readData(loc diskLoc) (*disk.Data, error) {
+	b, err := s.readBytes(loc)
+	if err != nil {
+		return nil, err
+	}
+	data := &disk.Data{}
+	_, err = data.UnmarshalMsg(b)
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// removeFromDisk removes commit from disk if it was written and releases references to its blames.
+func (s *DiskRepo) removeFromDisk(commitHash string) {
+	loc, ok := s.onDisk[commitHash]
+	if !ok {
+		return
+	}
+	data, err := s.readData(loc)
+	if err != nil {
+		panic(fmt.Errorf("could not read commit from disk: %v err: %v", commitHash, err))
+	}
+	for _, r := range data.Data {
+		b := s.blobs[r.BlamePointer]
+		b.refs--
+		if b.refs == 0 {
+			delete(s.blobs, r.BlamePointer)
+			s.live -= int64(b.loc.size)
+		}
+	}
+	delete(s.onDisk, commitHash)
+	s.live -= int64(loc.size)
+}
+
+// maybeCompact rewrites file with only referenced data when most of it is unused.
+func (s *DiskRepo) maybeCompact() error {
+	unused := s.offset - s.live
+	if unused < compactMinBytes || unused < s.live {
+		return nil
+	}
+	loc := s.fileLoc()
+	f, err := os.Create(loc + ".compact")
+	if err != nil {
+		return err
+	}
+	offset := int64(0)
+	move := func(l diskLoc) (diskLoc, error) {
+		b, err := s.readBytes(l)
+		if err != nil {
+			return l, err
+		}
+		_, err = f.WriteAt(b, offset)
+		if err != nil {
+			return l, err
+		}
+		res := diskLoc{offset: offset, size: l.size}
+		offset += int64(l.size)
+		return res, nil
+	}
+	for _, b := range s.blobs {
+		b.loc, err = move(b.loc)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	for c, l := range s.onDisk {
+		s.onDisk[c], err = move(l)
+		if err != nil {
+			f.Close()
+			return err
+		}
+	}
+	err = s.f.Close()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = os.Rename(loc+".compact", loc)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	s.f = f
+	s.offset = offset
+	s.live = offset
+	s.compactions++
+	return nil
+}
+
+func (s *DiskRepo) unloadFromMemory(commitHash string) {
+	files, ok := s.mem.commits[commitHash]
+	if !ok {
+		return
+	}
+	files.Range(s.decRefFile)
+	s.mem.Delete(commitHash)
+	s.lru.Remove(s.lruElem[commitHash])
+	delete(s.lruElem, commitHash)
+}
+
+// readCommit reads files of commit from disk with content hashes of their blames. Blames already in memory or in read are reused, blames decoded from disk are added to read.
+func (s *DiskRepo) readCommit(commitHash string, read map[uint64]*incblame.Blame) (files map[string]*incblame.Blame, keys map[string]uint64, _ error) {
+	data, err := s.readData(s.onDisk[commitHash])
+	if err != nil {
+		return nil, nil, err
+	}
+	files = map[string]*incblame.Blame{}
+	keys = map[string]uint64{}
+	for _, r := range data.Data {
+		k := r.BlamePointer
+		bl := s.loaded[k]
+		if bl == nil {
+			bl = read[k]
+		}
+		if bl == nil {
+			b, ok := s.blobs[k]
+			if !ok {
+				return nil, nil, fmt.Errorf("blame not found: %v", k)
+			}
+			data, err := s.readData(b.loc)
+			if err != nil {
+				return nil, nil, err
+			}
+			bl, err = decodeBlame(data, s.mem.interner)
+			if err != nil {
+				return nil, nil, err
+			}
+			read[k] = bl
+		}
+		files[r.Path] = bl
+		keys[r.Path] = k
+	}
+	return files, keys, nil
+}
+
+// encodeBlame converts blame to disk format. Pointers are local to blame.
+func encodeBlame(file *incblame.Blame) *disk.Data {
+	res := &disk.Data{}
+	linePointers := map[*incblame.Line]uint64{}
+	lineData := map[uint64]bool{}
+	bl := sBlame{}
+	bl.Pointer = 1
+	bl.Commit = file.Commit
+	bl.IsBinary = file.IsBinary
+	bl.LinePointers = make([]uint64, 0, len(file.Lines))
+	for _, l := range file.Lines {
+		lp, ok := linePointers[l]
+		if !ok {
+			lp = uint64(len(linePointers) + 1)
+			linePointers[l] = lp
+			dp := xxhash.Sum64(l.Line)
+			if !lineData[dp] {
+				lineData[dp] = true
+				res.LineData = append(res.LineData, sLineData{Pointer: dp, Data: l.Line})
+			}
+			res.Lines = append(res.Lines, sLine{Pointer: lp, Commit: l.Commit, LineDataPointer: dp})
+		}
+		bl.LinePointers = append(bl.LinePointers, lp)
+	}
+	res.Blames = append(res.Blames, bl)
+	return res
+}
+
+// decodeBlame converts blame from disk format, line data is interned.
+func decodeBlame(data *disk.Data, interner *Interner) (*incblame.Blame, error) {
+	if len(data.Blames) != 1 {
+		return nil, fmt.Errorf("expected one blame, got %v", len(data.Blames))
+	}
+	lineData := map[uint64][]byte{}
+	for _, ld := range data.LineData {
+		lineData[ld.Pointer] = interner.Intern(ld.Data)
+	}
+	lines := map[uint64]*incblame.Line{}
+	for _, l := range data.Lines {
+		v, ok := lineData[l.LineDataPointer]
+		if !ok {
+			return nil, fmt.Errorf("line data not found: %v", l.LineDataPointer)
+		}
+		lines[l.Pointer] = &incblame.Line{Commit: l.Commit, Line: v}
+	}
+	b := data.Blames[0]
+	bl := &incblame.Blame{}
+	bl.Commit = b.Commit
+	bl.IsBinary = b.IsBinary
+	for _, lp := range b.LinePointers {
+		l, ok := lines[lp]
+		if !ok {
+			return nil, fmt.Errorf("line not found: %v", lp)
+		}
+		bl.Lines = append(bl.Lines, l)
+	}
+	return bl, nil
+}
@@ -0,0 +1,246 @@
+package repo
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+func TestDiskRepoSpillAndLoad(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+
+	// budget fits only a few blames, so most commits are moved to disk
+	repo, err := NewDiskRepo(New(), DiskRepoOpts{
+		Dir:            filepath.Join(dir, "spill"),
+		MaxMemoryBytes: 3 * blameSize(randomBlameLineLen(10, 100)),
+		Logger:         logger.NewDefaultLogger(os.Stdout),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := New()
+	var commits []string
+	for i := 0; i < 20; i++ {
+		ch := randomString(32)
+		commits = append(commits, ch)
+		repo.AddCommit(ch)
+		want.AddCommit(ch)
+		shared := randomBlameLineLen(10, 100)
+		for _, fp := range []string{"a", "b"} {
+			repo.SetFile(ch, fp, shared)
+			want.SetFile(ch, fp, shared)
+		}
+		bl := randomBlameLineLen(10, 100)
+		repo.SetFile(ch, "c", bl)
+		want.SetFile(ch, "c", bl)
+	}
+
+	if repo.CommitsInMemory() >= len(commits) {
+		t.Fatalf("expected commits to be moved to disk, in memory %v", repo.CommitsInMemory())
+	}
+	if repo.MemoryBytes() > repo.opts.MaxMemoryBytes {
+		t.Fatalf("memory budget exceeded, got %v", repo.MemoryBytes())
+	}
+
+	got := repo.Commits()
+	sort.Strings(got)
+	sort.Strings(commits)
+	if len(got) != len(commits) {
+		t.Fatalf("invalid commit count, got %v want %v", len(got), len(commits))
+	}
+
+	for _, ch := range commits {
+		files := repo.GetCommitMust(ch)
+		if files.Len() != 3 {
+			t.Fatalf("invalid file count in %v: %v", ch, files.Len())
+		}
+		for fp, bl := range want.GetCommitMust(ch).Map() {
+			got, err := repo.GetFileMust(ch, fp)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Eq(bl) {
+				t.Fatalf("blame does not match after loading from disk, commit %v file %v", ch, fp)
+			}
+		}
+	}
+
+	repo.Delete(commits[0])
+	if len(repo.Commits()) != len(commits)-1 {
+		t.Fatal("commit not deleted")
+	}
+
+	err = repo.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "spill")); !os.IsNotExist(err) {
+		t.Fatal("spill dir not removed on close")
+	}
+}
+
+func TestDiskRepoModifyAfterLoad(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+
+	repo, err := NewDiskRepo(New(), DiskRepoOpts{
+		Dir:    filepath.Join(dir, "spill"),
+		Logger: logger.NewDefaultLogger(os.Stdout),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	bl1 := &incblame.Blame{Commit: "c1", Lines: []*incblame.Line{{Commit: "c1", Line: []byte("a")}}}
+	repo.AddCommit("c1")
+	repo.SetFile("c1", "f", bl1)
+	repo.AddCommit("c2")
+	repo.SetFile("c2", "f", bl1)
+
+	// zero budget keeps only the last used commit in memory
+	if repo.CommitsInMemory() != 1 {
+		t.Fatalf("expected 1 commit in memory, got %v", repo.CommitsInMemory())
+	}
+
+	bl2 := &incblame.Blame{Commit: "c1", Lines: []*incblame.Line{{Commit: "c1", Line: []byte("b")}}}
+	repo.SetFile("c1", "f", bl2)
+	repo.GetCommitMust("c2")
+	if !repo.GetFileOptional("c1", "f").Eq(bl2) {
+		t.Fatal("modification lost after moving commit to disk")
+	}
+}
+
+func TestDiskRepoBlameWrittenOnce(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+
+	repo, err := NewDiskRepo(New(), DiskRepoOpts{
+		Dir:    filepath.Join(dir, "spill"),
+		Logger: logger.NewDefaultLogger(os.Stdout),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	shared := randomBlameLineLen(10, 100)
+	repo.AddCommit("c1")
+	repo.SetFile("c1", "a", shared)
+	repo.SetFile("c1", "b", shared)
+	for _, c := range []string{"c2", "c3", "c4"} {
+		repo.ForkCommit(c, "c1")
+		repo.SetFile(c, "c", randomBlameLineLen(10, 100))
+	}
+	// zero budget moves all other commits to disk
+	repo.GetCommitMust("c1")
+	repo.GetCommitMust("c4")
+
+	// shared blame and 3 blames for c
+	if repo.blobWrites != 4 {
+		t.Fatalf("expected each blame to be written once, got %v", repo.blobWrites)
+	}
+
+	// blames loaded from disk are shared with blames in memory
+	a1 := repo.GetFileOptional("c4", "a")
+	a2 := repo.GetFileOptional("c2", "a")
+	if a1 != a2 || repo.GetFileOptional("c2", "b") != a2 {
+		t.Fatal("blame loaded from disk is not shared")
+	}
+}
+
+func TestDiskRepoCompact(t *testing.T) {
+	defer func(v int64) { compactMinBytes = v }(compactMinBytes)
+	compactMinBytes = 0
+
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+
+	repo, err := NewDiskRepo(New(), DiskRepoOpts{
+		Dir:    filepath.Join(dir, "spill"),
+		Logger: logger.NewDefaultLogger(os.Stdout),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	want := map[string]*incblame.Blame{}
+	prev := ""
+	for i := 0; i < 20; i++ {
+		ch := randomString(32)
+		bl := randomBlameLineLen(10, 100)
+		repo.AddCommit(ch)
+		repo.SetFile(ch, "a", bl)
+		want[ch] = bl
+		if prev != "" && i%4 != 0 {
+			repo.Delete(prev)
+			delete(want, prev)
+		}
+		prev = ch
+	}
+	if repo.compactions == 0 {
+		t.Fatal("expected file to be compacted")
+	}
+	if repo.offset > 2*repo.live {
+		t.Fatalf("unused space after compaction, size %v live %v", repo.offset, repo.live)
+	}
+	for ch, bl := range want {
+		if !repo.GetFileOptional(ch, "a").Eq(bl) {
+			t.Fatalf("blame does not match after compaction, commit %v", ch)
+		}
+	}
+}
+
+func TestDiskRepoWriteCheckpointWithoutLoading(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+
+	repo, err := NewDiskRepo(New(), DiskRepoOpts{
+		Dir:    filepath.Join(dir, "spill"),
+		Logger: logger.NewDefaultLogger(os.Stdout),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer repo.Close()
+
+	shared := randomBlameLineLen(10, 100)
+	repo.AddCommit("c1")
+	repo.SetFile("c1", "a", shared)
+	for _, c := range []string{"c2", "c3", "c4"} {
+		repo.ForkCommit(c, "c1")
+		repo.SetFile(c, "b", randomBlameLineLen(10, 100))
+	}
+	// zero budget keeps only the last used commit in memory
+	if repo.CommitsInMemory() != 1 {
+		t.Fatalf("expected 1 commit in memory, got %v", repo.CommitsInMemory())
+	}
+	want := repo.Debug()
+	loads := repo.loads
+
+	err = testWriter(t).Write(repo, filepath.Join(dir, "checkpoint"), "c4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.loads != loads || repo.CommitsInMemory() != 1 || repo.lru.Front().Value != "c4" {
+		t.Fatalf("commits loaded into memory when writing checkpoint, loads %v in memory %v", repo.loads-loads, repo.CommitsInMemory())
+	}
+
+	got, err := testReader(t).Read(filepath.Join(dir, "checkpoint"), "c4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Debug() != want {
+		t.Fatalf("wanted repo %v\ngot repo %v", want, got.Debug())
+	}
+	// blame decoded from disk for each commit is written to checkpoint once
+	if got.GetFileOptional("c2", "a") != got.GetFileOptional("c3", "a") {
+		t.Fatal("shared blame written to checkpoint more than once")
+	}
+}
@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// CheckpointFormatVersion is the version of checkpoint format written by CheckpointWriter. Increase when changing data files or disk structs.
+//
+// Version 1 had no header, only checkpoint-version file with the last commit. It is read without integrity checks and replaced by the current version on the next write.
+// Version 2 added header with checksums.
+// Version 3 stores commit hashes once in commits file, only files changed compared to the previous commit in repo file and uses zstd instead of gzip.
+// Version 4 adds heads of open branches to header.
+const CheckpointFormatVersion = 4
+
+const checkpointHeaderFile = "header.json"
+
+// checkpointVersionFileV1 has the last commit in version 1 checkpoints
+const checkpointVersionFileV1 = "checkpoint-version"
+
+// checkpointDataFiles returns the data files in checkpoint of format version
+func checkpointDataFiles(version int) []string {
+	res := []string{"repo", "blames", "lines", "line-data"}
+	if version >= 3 {
+		res = append(res, "commits")
+	}
+	return res
+}
+
+// CheckpointHeader describes checkpoint, stored in header.json in checkpoint dir.
+type CheckpointHeader struct {
+	FormatVersion int `json:"format_version"`
+	// LastCommit is the last processed commit when checkpoint was written.
+	LastCommit string `json:"last_commit"`
+	// Heads are the processed commits without processed children when checkpoint was written, set when there is more than one, for example when branches are not merged yet. All commits in checkpoint are ancestors of heads. Not set before version 4.
+	Heads []string `json:"heads,omitempty"`
+	// Created is the time checkpoint was written. Not set for version 1.
+	Created time.Time `json:"created"`
+	// Files has the size and checksum of each data file. Not set for version 1.
+	Files map[string]CheckpointFile `json:"files"`
+}
+
+// CheckpointFile is the size and checksum of data file in checkpoint.
+type CheckpointFile struct {
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	// RawSize is the size before compression. Not set before version 3.
+	RawSize int64 `json:"raw_size,omitempty"`
+}
+
+// ErrCheckpointMissing is returned when there is no checkpoint in dir.
+type ErrCheckpointMissing struct {
+	CheckpointDir string
+}
+
+func (s ErrCheckpointMissing) Error() string {
+	return fmt.Sprintf("ripsrc: checkpoint not found in %v", s.CheckpointDir)
+}
+
+// ErrCheckpointFormat is returned when checkpoint was written in a format version that could not be read, for example by a newer version of ripsrc.
+type ErrCheckpointFormat struct {
+	CheckpointDir string
+	Version       int
+	Supported     int
+}
+
+func (s ErrCheckpointFormat) Error() string {
+	return fmt.Sprintf("ripsrc: checkpoint format version %v is not supported, max supported version %v, dir %v", s.Version, s.Supported, s.CheckpointDir)
+}
+
+// ErrCheckpointCorrupted is returned when checkpoint file is missing, truncated, could not be decoded or does not match checksum in header.
+type ErrCheckpointCorrupted struct {
+	CheckpointDir string
+	File          string
+	Err           error
+}
+
+func (s ErrCheckpointCorrupted) Error() string {
+	return fmt.Sprintf("ripsrc: checkpoint file %v is corrupted: %v, dir %v", s.File, s.Err, s.CheckpointDir)
+}
+
+// IsCheckpointInvalid returns true if checkpoint could not be used because it is missing, corrupted or in unsupported format. Processing needs to start from the beginning in this case.
+func IsCheckpointInvalid(err error) bool {
+	switch err.(type) {
+	case ErrCheckpointMissing, ErrCheckpointFormat, ErrCheckpointCorrupted:
+		return true
+	}
+	return false
+}
+
+// readHeader reads header from checkpoint location returned by checkpointLoc. Version 1 checkpoints return header with LastCommit only.
+func readHeader(dir string) (res CheckpointHeader, _ error) {
+	b, err := ioutil.ReadFile(filepath.Join(dir, checkpointHeaderFile))
+	if os.IsNotExist(err) {
+		b, err := ioutil.ReadFile(filepath.Join(dir, checkpointVersionFileV1))
+		if err == nil {
+			res.FormatVersion = 1
+			res.LastCommit = string(b)
+			return res, nil
+		}
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			return res, ErrCheckpointMissing{CheckpointDir: dir}
+		}
+		return res, ErrCheckpointCorrupted{CheckpointDir: dir, File: checkpointHeaderFile, Err: os.ErrNotExist}
+	}
+	if err != nil {
+		return res, err
+	}
+	err = json.Unmarshal(b, &res)
+	if err != nil {
+		return res, ErrCheckpointCorrupted{CheckpointDir: dir, File: checkpointHeaderFile, Err: err}
+	}
+	if res.FormatVersion < 1 || res.FormatVersion > CheckpointFormatVersion {
+		return res, ErrCheckpointFormat{CheckpointDir: dir, Version: res.FormatVersion, Supported: CheckpointFormatVersion}
+	}
+	if res.LastCommit == "" {
+		return res, ErrCheckpointCorrupted{CheckpointDir: dir, File: checkpointHeaderFile, Err: fmt.Errorf("last commit is not set")}
+	}
+	for _, f := range checkpointDataFiles(res.FormatVersion) {
+		if _, ok := res.Files[f]; !ok {
+			return res, ErrCheckpointCorrupted{CheckpointDir: dir, File: checkpointHeaderFile, Err: fmt.Errorf("no checksum for file %v", f)}
+		}
+	}
+	return res, nil
+}
+
+// ResumeHeads returns the commits to process again when resuming from checkpoint, Heads or LastCommit if not set.
+func (s CheckpointHeader) ResumeHeads() []string {
+	if len(s.Heads) != 0 {
+		return s.Heads
+	}
+	return []string{s.LastCommit}
+}
+
+// Size returns the total size of data files and the size before compression.
+func (s CheckpointHeader) Size() (size int64, rawSize int64) {
+	for _, f := range s.Files {
+		size += f.Size
+		rawSize += f.RawSize
+	}
+	return
+}
+
+// fileInfo returns expected size and checksum of data file, nil for version 1 checkpoints.
+func (s CheckpointHeader) fileInfo(kind string) *CheckpointFile {
+	if s.FormatVersion < 2 {
+		return nil
+	}
+	res := s.Files[kind]
+	return &res
+}
+
+func writeHeader(dir string, header CheckpointHeader) error {
+	b, err := json.MarshalIndent(header, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(dir, checkpointHeaderFile), b)
+}
+
+// verifyFile checks size and checksum of data file without decoding it.
+func verifyFile(dir string, kind string, want CheckpointFile) error {
+	corrupted := func(err error) error {
+		return ErrCheckpointCorrupted{CheckpointDir: dir, File: kind, Err: err}
+	}
+	f, err := os.Open(filepath.Join(dir, kind))
+	if err != nil {
+		return corrupted(err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, f)
+	if err != nil {
+		return corrupted(err)
+	}
+	if size != want.Size {
+		return corrupted(fmt.Errorf("size %v does not match %v in header", size, want.Size))
+	}
+	sum := hex.EncodeToString(h.Sum(nil))
+	if sum != want.SHA256 {
+		return corrupted(fmt.Errorf("checksum %v does not match %v in header", sum, want.SHA256))
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"bytes"
+
+	"github.com/cespare/xxhash"
+)
+
+// DefaultInternMaxLines is the default number of unique lines kept by Interner.
+const DefaultInternMaxLines = 1 << 20
+
+// Interner deduplicates line data, so equal lines added in different commits and files share memory.
+//
+// Interned data is also copied out of the diff buffer, so the buffer is not kept alive by a single line referencing it.
+//
+// Only the hash and reference are kept for each line. When the number of lines exceeds the limit the table is reset, data interned before is still shared by existing blames.
+type Interner struct {
+	max  int
+	data map[uint64][]byte
+}
+
+// NewInterner creates interner keeping up to maxLines unique lines. Uses DefaultInternMaxLines if maxLines is 0.
+func NewInterner(maxLines int) *Interner {
+	if maxLines == 0 {
+		maxLines = DefaultInternMaxLines
+	}
+	s := &Interner{}
+	s.max = maxLines
+	s.data = map[uint64][]byte{}
+	return s
+}
+
+// Intern returns shared copy of data.
+func (s *Interner) Intern(data []byte) []byte {
+	h := xxhash.Sum64(data)
+	if v, ok := s.data[h]; ok {
+		if bytes.Equal(v, data) {
+			return v
+		}
+		// hash collision, keep the original
+		return data
+	}
+	if len(s.data) >= s.max {
+		s.data = map[uint64][]byte{}
+	}
+	v := make([]byte, len(data))
+	copy(v, data)
+	s.data[h] = v
+	return v
+}
+
+// Len returns the number of lines in table.
+func (s *Interner) Len() int {
+	return len(s.data)
+}
@@ -2,17 +2,28 @@ package repo
 
 import (
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
 	"os"
 	"path/filepath"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/tinylib/msgp/msgp"
 )
 
 type msgWriter struct {
-	loc string
-	f   *os.File
-	gw  *gzip.Writer
-	wr  *msgp.Writer
+	loc  string
+	f    *os.File
+	hash hash.Hash
+	size int64
+	// rawSize is the size before compression
+	rawSize int64
+	zw      *zstd.Encoder
+	wr      *msgp.Writer
 }
 
 func newMsgWriter(dir string, kind string) (*msgWriter, error) {
@@ -27,8 +38,13 @@ func newMsgWriter(dir string, kind string) (*msgWriter, error) {
 		return nil, err
 	}
 	s.f = f
-	s.gw = gzip.NewWriter(f)
-	s.wr = msgp.NewWriter(s.gw)
+	s.hash = sha256.New()
+	s.zw, err = zstd.NewWriter(io.MultiWriter(f, s.hash, (*countWriter)(&s.size)))
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.wr = msgp.NewWriter(io.MultiWriter(s.zw, (*countWriter)(&s.rawSize)))
 	return s, nil
 }
 
@@ -36,53 +52,133 @@ func (s *msgWriter) Write(obj msgp.Encodable) error {
 	return obj.EncodeMsg(s.wr)
 }
 
-func (s *msgWriter) Finish() error {
+// Finish completes the file and returns its size and checksum for header.
+func (s *msgWriter) Finish() (res CheckpointFile, _ error) {
 	err := s.wr.Flush()
 	if err != nil {
-		return err
+		return res, err
 	}
-	err = s.gw.Flush()
+	// close writes the end of zstd frame with checksum, used to detect truncated files
+	err = s.zw.Close()
 	if err != nil {
-		return err
+		return res, err
+	}
+	// sync before rename, so file is complete after crash
+	err = s.f.Sync()
+	if err != nil {
+		return res, err
 	}
 	err = s.f.Close()
 	if err != nil {
-		return err
+		return res, err
 	}
-	return os.Rename(s.loc+".tmp", s.loc)
+	res.Size = s.size
+	res.RawSize = s.rawSize
+	res.SHA256 = hex.EncodeToString(s.hash.Sum(nil))
+	return res, os.Rename(s.loc+".tmp", s.loc)
+}
+
+type countWriter int64
+
+func (s *countWriter) Write(b []byte) (int, error) {
+	*s += countWriter(len(b))
+	return len(b), nil
 }
 
 type msgReader struct {
-	loc string
-	f   *os.File
-	gr  *gzip.Reader
-	r   *msgp.Reader
+	dir  string
+	kind string
+	f    *os.File
+	// want is the expected size and checksum, nil for version 1 checkpoints
+	want *CheckpointFile
+	hash hash.Hash
+	size int64
+	raw  io.Reader
+	// close releases decompressor, safe to call more than once
+	close func()
+	r     *msgp.Reader
 }
 
-func newMsgReader(dir string, kind string) (*msgReader, error) {
+// newMsgReader opens data file in checkpoint dir. Files are compressed with gzip before format version 3 and with zstd since. Pass want from header to verify size and checksum on Finish.
+func newMsgReader(dir string, kind string, version int, want *CheckpointFile) (*msgReader, error) {
 	s := &msgReader{}
-	s.loc = filepath.Join(dir, kind)
-	f, err := os.Open(s.loc)
+	s.dir = dir
+	s.kind = kind
+	s.want = want
+	f, err := os.Open(filepath.Join(dir, kind))
 	if err != nil {
-		return nil, err
+		return nil, s.corrupted(err)
 	}
 	s.f = f
-	s.gr, err = gzip.NewReader(f)
-	if err != nil {
-		return nil, err
+	s.hash = sha256.New()
+	s.raw = io.TeeReader(f, io.MultiWriter(s.hash, (*countWriter)(&s.size)))
+	var r io.Reader
+	if version < 3 {
+		gr, err := gzip.NewReader(s.raw)
+		if err != nil {
+			f.Close()
+			return nil, s.corrupted(err)
+		}
+		r = gr
+		s.close = func() {}
+	} else {
+		zr, err := zstd.NewReader(s.raw)
+		if err != nil {
+			f.Close()
+			return nil, s.corrupted(err)
+		}
+		r = zr
+		s.close = zr.Close
 	}
-	s.r = msgp.NewReader(s.gr)
+	s.r = msgp.NewReader(r)
 	return s, nil
 }
 
+func (s *msgReader) corrupted(err error) error {
+	return ErrCheckpointCorrupted{CheckpointDir: s.dir, File: s.kind, Err: err}
+}
+
+// Read decodes the next object. Returns io.EOF at the end of file and ErrCheckpointCorrupted if data could not be decoded.
 func (s *msgReader) Read(obj msgp.Decodable) error {
-	return obj.DecodeMsg(s.r)
+	err := obj.DecodeMsg(s.r)
+	if err == nil {
+		return nil
+	}
+	if msgp.Cause(err) == io.EOF {
+		return io.EOF
+	}
+	if s.want == nil && msgIsEOF(err) {
+		// version 1 checkpoints did not write gzip footer
+		return io.EOF
+	}
+	return s.corrupted(err)
 }
 
+// Close closes the file without verifying it. Used when reading failed.
+func (s *msgReader) Close() {
+	s.close()
+	s.f.Close()
+}
+
+// Finish closes the file and verifies size and checksum.
 func (s *msgReader) Finish() error {
-	err := s.f.Close()
+	// stop decompressor before reading the rest of the file, zstd decoder reads in background
+	s.close()
+	defer s.f.Close()
+	if s.want == nil {
+		return nil
+	}
+	// hash the rest of the file not consumed by decompressor
+	_, err := io.Copy(ioutil.Discard, s.raw)
 	if err != nil {
-		return err
+		return s.corrupted(err)
+	}
+	if s.size != s.want.Size {
+		return s.corrupted(fmt.Errorf("size %v does not match %v in header", s.size, s.want.Size))
+	}
+	sum := hex.EncodeToString(s.hash.Sum(nil))
+	if sum != s.want.SHA256 {
+		return s.corrupted(fmt.Errorf("checksum %v does not match %v in header", sum, s.want.SHA256))
 	}
 	return nil
 }
@@ -2,7 +2,8 @@ package repo
 
 import (
 	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
 	"path/filepath"
 	"time"
 
@@ -31,18 +32,75 @@ func NewCheckpointReader(logger logger.Logger) *CheckpointReader {
 	return s
 }
 
-func (s *CheckpointReader) Read(dir string, expectedCommit string) (Repo, error) {
-	dir = filepath.Join(dir, checkpointDirName)
+// checkpointLoc returns the directory of the latest complete checkpoint. Writer keeps the previous checkpoint until the new one is in place, it is used if writing was interrupted.
+func checkpointLoc(dir string) string {
+	loc := filepath.Join(dir, checkpointDirName)
+	if _, err := os.Stat(loc); os.IsNotExist(err) {
+		prev := filepath.Join(dir, checkpointPrevDirName)
+		if _, err := os.Stat(prev); err == nil {
+			return prev
+		}
+	}
+	return loc
+}
 
-	if expectedCommit != "" {
-		// no expected commit validation requested
-		b, err := ioutil.ReadFile(filepath.Join(dir, checkpointVersionFile))
+// Header returns header of the latest checkpoint in dir. Returns ErrCheckpointMissing if there is no checkpoint.
+func (s *CheckpointReader) Header(dir string) (CheckpointHeader, error) {
+	return readHeader(checkpointLoc(dir))
+}
+
+// LastCommit returns the last processed commit stored in checkpoint, or empty string if there is no checkpoint in dir.
+func (s *CheckpointReader) LastCommit(dir string) (string, error) {
+	header, err := s.Header(dir)
+	if err != nil {
+		if _, ok := err.(ErrCheckpointMissing); ok {
+			return "", nil
+		}
+		return "", err
+	}
+	return header.LastCommit, nil
+}
+
+// Verify checks header and checksums of data files without decoding them. Returns ErrCheckpointMissing, ErrCheckpointFormat or ErrCheckpointCorrupted if checkpoint could not be used. Version 1 checkpoints have no checksums, only header is checked.
+func (s *CheckpointReader) Verify(dir string) (CheckpointHeader, error) {
+	return verifyCheckpoint(checkpointLoc(dir))
+}
+
+// verifyCheckpoint checks checkpoint in loc, see Verify.
+func verifyCheckpoint(dir string) (CheckpointHeader, error) {
+	header, err := readHeader(dir)
+	if err != nil {
+		return header, err
+	}
+	if header.FormatVersion < 2 {
+		return header, nil
+	}
+	for _, kind := range checkpointDataFiles(header.FormatVersion) {
+		err := verifyFile(dir, kind, header.Files[kind])
 		if err != nil {
-			return nil, fmt.Errorf("failed reading checkpoint version file, err: %v", err)
+			return header, err
 		}
-		checkpointCommit := string(b)
-		if checkpointCommit != expectedCommit {
-			return nil, ErrCheckpointNotExpected{CheckpointDir: dir, WantCommit: expectedCommit, HaveCommit: checkpointCommit}
+	}
+	return header, nil
+}
+
+// Read reads checkpoint from dir. If expectedCommit is set, returns ErrCheckpointNotExpected when checkpoint was written for another commit.
+// Returns ErrCheckpointMissing, ErrCheckpointFormat or ErrCheckpointCorrupted if checkpoint could not be used, see IsCheckpointInvalid. Version 1 checkpoints are read without integrity checks.
+func (s *CheckpointReader) Read(dir string, expectedCommit string) (*MemRepo, error) {
+	dir = checkpointLoc(dir)
+
+	header, err := readHeader(dir)
+	if err != nil {
+		return nil, err
+	}
+	if header.FormatVersion < CheckpointFormatVersion {
+		s.logger.Info("reading checkpoint in old format, it will be replaced on the next write", "version", header.FormatVersion)
+	}
+
+	if expectedCommit != "" {
+		// no expected commit validation requested
+		if header.LastCommit != expectedCommit {
+			return nil, ErrCheckpointNotExpected{CheckpointDir: dir, WantCommit: expectedCommit, HaveCommit: header.LastCommit}
 		}
 	}
 
@@ -52,71 +110,237 @@ func (s *CheckpointReader) Read(dir string, expectedCommit string) (Repo, error)
 		s.logger.Info("finished reading checkpoint", "duration", time.Since(start))
 	}()
 
+	if header.FormatVersion < 3 {
+		return s.readV2(dir, header)
+	}
+	return s.readV3(dir, header)
+}
+
+func corruptedf(dir string, kind string, format string, args ...interface{}) error {
+	return ErrCheckpointCorrupted{CheckpointDir: dir, File: kind, Err: fmt.Errorf(format, args...)}
+}
+
+// readV3 reads checkpoint in format version 3.
+func (s *CheckpointReader) readV3(dir string, header CheckpointHeader) (*MemRepo, error) {
 	repo := New()
 
-	repoR, err := newMsgReader(dir, "repo")
+	var commits []disk.Commit
+	err := readFile(dir, "commits", header, func(r *msgReader) error {
+		for {
+			obj := disk.Commit{}
+			err := r.Read(&obj)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			commits = append(commits, obj)
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
-	blamesR, err := newMsgReader(dir, "blames")
+	s.logger.Info("loaded commits", "count", len(commits))
+	commit := func(kind string, i uint32) (string, error) {
+		if int(i) >= len(commits) {
+			return "", corruptedf(dir, kind, "commit not found: %v", i)
+		}
+		return commits[i].Hash, nil
+	}
+
+	var lineData [][]byte
+	err = readFile(dir, "line-data", header, func(r *msgReader) error {
+		for {
+			obj := disk.CompactLineData{}
+			err := r.Read(&obj)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			lineData = append(lineData, obj.Data)
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
-	linesR, err := newMsgReader(dir, "lines")
+	s.logger.Info("loaded line data", "count", len(lineData))
+
+	var lines []*incblame.Line
+	err = readFile(dir, "lines", header, func(r *msgReader) error {
+		for {
+			obj := disk.CompactLine{}
+			err := r.Read(&obj)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if obj.LineData == 0 || obj.LineData > uint64(len(lineData)) {
+				return corruptedf(dir, "lines", "line data not found: %v", obj.LineData)
+			}
+			line := &incblame.Line{}
+			line.Commit, err = commit("lines", obj.Commit)
+			if err != nil {
+				return err
+			}
+			line.Line = lineData[obj.LineData-1]
+			lines = append(lines, line)
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
-	lineDataR, err := newMsgReader(dir, "line-data")
+	s.logger.Info("loaded lines", "count", len(lines))
+
+	var blames []*incblame.Blame
+	err = readFile(dir, "blames", header, func(r *msgReader) error {
+		for {
+			obj := disk.CompactBlame{}
+			err := r.Read(&obj)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			bl := &incblame.Blame{}
+			bl.Commit, err = commit("blames", obj.Commit)
+			if err != nil {
+				return err
+			}
+			bl.IsBinary = obj.IsBinary
+			if len(obj.Lines) != 0 {
+				bl.Lines = make([]*incblame.Line, 0, len(obj.Lines))
+			}
+			lp := int64(0)
+			for _, d := range obj.Lines {
+				lp += d
+				if lp <= 0 || lp > int64(len(lines)) {
+					return corruptedf(dir, "blames", "line not found: %v", lp)
+				}
+				bl.Lines = append(bl.Lines, lines[lp-1])
+			}
+			blames = append(blames, bl)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("loaded unique blames", "count", len(blames))
+
+	// rows have files changed compared to the previous commit in repo, commits in repo are first in commits file
+	tree := NewTree()
+	next := 0
+	finish := func(until int) {
+		for ; next < until; next++ {
+			if commits[next].InRepo {
+				repo.commits[commits[next].Hash] = tree
+			}
+		}
+	}
+	i := 0
+	err = readFile(dir, "repo", header, func(r *msgReader) error {
+		for {
+			obj := disk.Row{}
+			err := r.Read(&obj)
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			c := int(obj.Commit)
+			if c >= len(commits) || !commits[c].InRepo || c < next {
+				return corruptedf(dir, "repo", "unexpected commit: %v", c)
+			}
+			finish(c)
+			if obj.Blame == 0 {
+				tree = tree.Delete(obj.Path)
+			} else {
+				if obj.Blame > uint64(len(blames)) {
+					return corruptedf(dir, "repo", "blame not found: %v", obj.Blame)
+				}
+				tree = tree.Set(obj.Path, blames[obj.Blame-1])
+			}
+			i++
+		}
+	})
 	if err != nil {
 		return nil, err
 	}
+	finish(len(commits))
+	s.logger.Info("loaded changed files", "count", i, "commits", len(repo.commits))
+
+	return repo, nil
+}
+
+// readV2 reads checkpoint in format version 1 or 2.
+func (s *CheckpointReader) readV2(dir string, header CheckpointHeader) (*MemRepo, error) {
+	corrupted := func(kind string, format string, args ...interface{}) error {
+		return corruptedf(dir, kind, format, args...)
+	}
+
+	repo := New()
+
 	lineData := map[uint64][]byte{}
-	{
+	err := readFile(dir, "line-data", header, func(r *msgReader) error {
 		for {
 			obj := &disk.LineData{}
-			err := lineDataR.Read(obj)
+			err := r.Read(obj)
+			if err == io.EOF {
+				return nil
+			}
 			if err != nil {
-				if msgIsEOF(err) {
-					break
-				}
-				return nil, err
+				return err
 			}
 			lineData[obj.Pointer] = obj.Data
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 	s.logger.Info("loaded line data", "count", len(lineData))
+
 	lines := map[uint64]*incblame.Line{}
-	{
+	err = readFile(dir, "lines", header, func(r *msgReader) error {
 		for {
 			obj := &disk.Line{}
-			err := linesR.Read(obj)
+			err := r.Read(obj)
+			if err == io.EOF {
+				return nil
+			}
 			if err != nil {
-				if msgIsEOF(err) {
-					break
-				}
-				return nil, err
+				return err
 			}
 			line := &incblame.Line{}
 			line.Commit = obj.Commit
 			v, ok := lineData[obj.LineDataPointer]
 			if !ok {
-				panic("line data")
+				return corrupted("lines", "line data not found: %v", obj.LineDataPointer)
 			}
 			line.Line = v
 			lines[obj.Pointer] = line
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 	s.logger.Info("loaded lines", "count", len(lines))
+
 	blames := map[uint64]*incblame.Blame{}
-	{
+	err = readFile(dir, "blames", header, func(r *msgReader) error {
 		for {
 			obj := &disk.Blame{}
-			err := blamesR.Read(obj)
+			err := r.Read(obj)
+			if err == io.EOF {
+				return nil
+			}
 			if err != nil {
-				if msgIsEOF(err) {
-					break
-				}
-				return nil, err
+				return err
 			}
 			bl := &incblame.Blame{}
 			bl.Commit = obj.Commit
@@ -124,56 +348,78 @@ func (s *CheckpointReader) Read(dir string, expectedCommit string) (Repo, error)
 			for _, lp := range obj.LinePointers {
 				line, ok := lines[lp]
 				if !ok {
-					panic("line")
+					return corrupted("blames", "line not found: %v", lp)
 				}
 				bl.Lines = append(bl.Lines, line)
 			}
 			blames[obj.Pointer] = bl
 		}
+	})
+	if err != nil {
+		return nil, err
 	}
 	s.logger.Info("loaded unique blames", "count", len(blames))
-	{
-		i := 0
+
+	// rows are written grouped by commit, build tree for each commit based on the previous one to share unchanged files
+	commit := ""
+	var files map[string]*incblame.Blame
+	var prev *Tree
+	flush := func() {
+		if commit == "" {
+			return
+		}
+		prev = NewTreeFromFiles(prev, files)
+		repo.commits[commit] = prev
+	}
+	i := 0
+	err = readFile(dir, "repo", header, func(r *msgReader) error {
 		for {
 			obj := &disk.DataRow{}
-			err := repoR.Read(obj)
+			err := r.Read(obj)
+			if err == io.EOF {
+				return nil
+			}
 			if err != nil {
-				if msgIsEOF(err) {
-					break
-				}
-				return nil, err
+				return err
 			}
 			bl, ok := blames[obj.BlamePointer]
 			if !ok {
-				panic("blame")
+				return corrupted("repo", "blame not found: %v", obj.BlamePointer)
 			}
-			if _, ok := repo[obj.Commit]; !ok {
-				repo[obj.Commit] = map[string]*incblame.Blame{}
+			if obj.Commit != commit {
+				flush()
+				commit = obj.Commit
+				files = map[string]*incblame.Blame{}
+				if t, ok := repo.commits[commit]; ok {
+					// rows for commit are not contiguous
+					files = t.Map()
+				}
 			}
-			repo[obj.Commit][obj.Path] = bl
+			files[obj.Path] = bl
 			i++
 		}
-		s.logger.Info("loaded blames", "count", i)
-	}
-
-	err = repoR.Finish()
-	if err != nil {
-		return nil, err
-	}
-	err = blamesR.Finish()
+	})
 	if err != nil {
 		return nil, err
 	}
-	err = linesR.Finish()
+	flush()
+	s.logger.Info("loaded blames", "count", i)
+
+	return repo, nil
+}
+
+// readFile opens data file, calls read to decode objects and verifies file against header.
+func readFile(dir string, kind string, header CheckpointHeader, read func(r *msgReader) error) error {
+	r, err := newMsgReader(dir, kind, header.FormatVersion, header.fileInfo(kind))
 	if err != nil {
-		return nil, err
+		return err
 	}
-	err = lineDataR.Finish()
+	err = read(r)
 	if err != nil {
-		return nil, err
+		r.Close()
+		return err
 	}
-
-	return repo, nil
+	return r.Finish()
 }
 
 func msgIsEOF(err error) bool {
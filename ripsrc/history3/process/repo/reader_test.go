@@ -1,10 +1,13 @@
 package repo
 
 import (
+	"io/ioutil"
 	"os"
-	"reflect"
+	"path/filepath"
+	"strconv"
 	"testing"
 
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
 	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
 
 	"github.com/stretchr/testify/assert"
@@ -25,7 +28,7 @@ func TestReaderBasic1(t *testing.T) {
 		repo.AddCommit(ch)
 		for i := 0; i < 2; i++ {
 			fp := randomString(1)
-			repo[ch][fp] = randomBlameLineLen(1, 2)
+			repo.SetFile(ch, fp, randomBlameLineLen(1, 2))
 		}
 	}
 
@@ -39,7 +42,7 @@ func TestReaderBasic1(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	if !reflect.DeepEqual(repo, repo2) {
+	if repo.Debug() != repo2.Debug() {
 		t.Fatalf("wanted repo %v\ngot repo %v", repo.Debug(), repo2.Debug())
 	}
 }
@@ -49,7 +52,7 @@ func TestReaderValidateCommit(t *testing.T) {
 	defer os.RemoveAll(dir)
 	repo := New()
 	repo.AddCommit("c1")
-	repo["c1"]["p1"] = randomBlameLineLen(1, 1)
+	repo.SetFile("c1", "p1", randomBlameLineLen(1, 1))
 
 	err := testWriter(t).Write(repo, dir, "c1")
 	if err != nil {
@@ -68,3 +71,233 @@ func TestReaderValidateCommit(t *testing.T) {
 	assert.Equal(t, "c1", err2.HaveCommit)
 	t.Log("error msg: " + err.Error())
 }
+
+func TestReaderInterruptedWrite(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+	repo := New()
+	repo.AddCommit("c1")
+	repo.SetFile("c1", "p1", randomBlameLineLen(1, 1))
+
+	err := testWriter(t).Write(repo, dir, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// crash after moving the previous checkpoint, before new one is in place
+	err = os.Rename(filepath.Join(dir, checkpointDirName), filepath.Join(dir, checkpointPrevDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := testReader(t).LastCommit(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "c1", last)
+	repo2, err := testReader(t).Read(dir, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, repo.Debug(), repo2.Debug())
+
+	// next write replaces previous checkpoint
+	err = testWriter(t).Write(repo, dir, "c2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, checkpointPrevDirName)); !os.IsNotExist(err) {
+		t.Fatal("previous checkpoint not removed")
+	}
+	last, err = testReader(t).LastCommit(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "c2", last)
+}
+
+func writeTestCheckpoint(t *testing.T, dir string) *MemRepo {
+	t.Helper()
+	repo := New()
+	repo.AddCommit("c1")
+	repo.SetFile("c1", "p1", randomBlameLineLen(3, 10))
+	repo.SetFile("c1", "p2", randomBlameLineLen(3, 10))
+	err := testWriter(t).Write(repo, dir, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return repo
+}
+
+func TestReaderMissing(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+	_, err := testReader(t).Read(dir, "c1")
+	if _, ok := err.(ErrCheckpointMissing); !ok {
+		t.Fatalf("expected ErrCheckpointMissing, got %v", err)
+	}
+	last, err := testReader(t).LastCommit(dir)
+	if err != nil || last != "" {
+		t.Fatalf("expected no last commit, got %v %v", last, err)
+	}
+}
+
+func TestReaderCorrupted(t *testing.T) {
+	cases := []struct {
+		Label string
+		File  string
+		Edit  func(b []byte) []byte
+	}{
+		{"truncated", "blames", func(b []byte) []byte {
+			return b[:len(b)/2]
+		}},
+		{"modified", "line-data", func(b []byte) []byte {
+			b[len(b)-1] ^= 0xff
+			return b
+		}},
+		{"empty", "repo", func(b []byte) []byte {
+			return nil
+		}},
+		{"header", checkpointHeaderFile, func(b []byte) []byte {
+			return b[:len(b)-2]
+		}},
+	}
+	for _, c := range cases {
+		t.Run(c.Label, func(t *testing.T) {
+			dir := tempDir()
+			defer os.RemoveAll(dir)
+			writeTestCheckpoint(t, dir)
+
+			loc := filepath.Join(dir, checkpointDirName, c.File)
+			b, err := ioutil.ReadFile(loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ioutil.WriteFile(loc, c.Edit(b), 0777)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = testReader(t).Verify(dir)
+			assertCorrupted(t, err, c.File)
+			_, err = testReader(t).Read(dir, "c1")
+			assertCorrupted(t, err, c.File)
+		})
+	}
+}
+
+func assertCorrupted(t *testing.T, err error, file string) {
+	t.Helper()
+	err2, ok := err.(ErrCheckpointCorrupted)
+	if !ok {
+		t.Fatalf("expected ErrCheckpointCorrupted, got %v", err)
+	}
+	assert.Equal(t, file, err2.File)
+	if !IsCheckpointInvalid(err) {
+		t.Fatal("expected invalid checkpoint")
+	}
+}
+
+func TestReaderUnsupportedFormat(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+	writeTestCheckpoint(t, dir)
+
+	loc := filepath.Join(dir, checkpointDirName)
+	header, err := readHeader(loc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	header.FormatVersion = CheckpointFormatVersion + 1
+	err = writeHeader(loc, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = testReader(t).Read(dir, "c1")
+	err2, ok := err.(ErrCheckpointFormat)
+	if !ok {
+		t.Fatalf("expected ErrCheckpointFormat, got %v", err)
+	}
+	assert.Equal(t, CheckpointFormatVersion+1, err2.Version)
+}
+
+// oldFormatRepo returns repo stored in testdata/v1 and testdata/v2, written by previous versions of CheckpointWriter
+func oldFormatRepo() *MemRepo {
+	repo := New()
+	l1 := &incblame.Line{Commit: "c1", Line: []byte("package main")}
+	l2 := &incblame.Line{Commit: "c1", Line: []byte("")}
+	l3 := &incblame.Line{Commit: "c2", Line: []byte("func main() {}")}
+	repo.AddCommit("c1")
+	repo.SetFile("c1", "main.go", &incblame.Blame{Commit: "c1", Lines: []*incblame.Line{l1, l2}})
+	repo.SetFile("c1", "README", &incblame.Blame{Commit: "c1", Lines: []*incblame.Line{l2}})
+	repo.AddCommit("c2")
+	repo.SetFile("c2", "main.go", &incblame.Blame{Commit: "c2", Lines: []*incblame.Line{l1, l2, l3}})
+	repo.SetFile("c2", "logo.png", &incblame.Blame{Commit: "c2", IsBinary: true})
+	return repo
+}
+
+func TestReaderOldFormats(t *testing.T) {
+	for _, version := range []int{1, 2} {
+		t.Run(strconv.Itoa(version), func(t *testing.T) {
+			dir := tempDir()
+			defer os.RemoveAll(dir)
+			copyTestdata(t, filepath.Join("testdata", "v"+strconv.Itoa(version), checkpointDirName), filepath.Join(dir, checkpointDirName))
+
+			header, err := testReader(t).Verify(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, version, header.FormatVersion)
+			assert.Equal(t, "c2", header.LastCommit)
+
+			want := oldFormatRepo()
+			repo, err := testReader(t).Read(dir, "c2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want.Debug() != repo.Debug() {
+				t.Fatalf("wanted repo %v\ngot repo %v", want.Debug(), repo.Debug())
+			}
+
+			// next write replaces it with the current version
+			err = testWriter(t).Write(repo, dir, "c2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			header, err = testReader(t).Verify(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, CheckpointFormatVersion, header.FormatVersion)
+			repo, err = testReader(t).Read(dir, "c2")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want.Debug() != repo.Debug() {
+				t.Fatalf("wanted repo %v\ngot repo %v", want.Debug(), repo.Debug())
+			}
+		})
+	}
+}
+
+func copyTestdata(t *testing.T, from, to string) {
+	t.Helper()
+	err := os.MkdirAll(to, 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := ioutil.ReadDir(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, info := range infos {
+		b, err := ioutil.ReadFile(filepath.Join(from, info.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(to, info.Name()), b, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
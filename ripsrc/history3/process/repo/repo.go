@@ -9,20 +9,58 @@ import (
 	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
 )
 
-type Repo map[string]map[string]*incblame.Blame
+// Repo stores blame data for each file by commit.
+type Repo interface {
+	// AddCommit notes that commit exists (important for empty commits).
+	AddCommit(commitHash string)
+	// ForkCommit adds commit with the same files as parent. Files are shared with parent, so this does not depend on the number of files. Panics if parent does not exist.
+	ForkCommit(commitHash string, parentHash string)
+	// SetFile sets blame for file in commit. Commit must be added before.
+	SetFile(commitHash string, filePath string, blame *incblame.Blame)
+	// DeleteFile removes file from commit. Commit must be added before.
+	DeleteFile(commitHash string, filePath string)
+	// GetCommitMust returns all files in commit. Panics if commit does not exist. Returned tree is not affected by later changes to commit.
+	GetCommitMust(commitHash string) *Tree
+	// GetFileOptional returns blame for file in commit or nil if file does not exist. Panics if commit does not exist.
+	GetFileOptional(commitHash string, filePath string) *incblame.Blame
+	// GetFileMust returns blame for file in commit or error if file does not exist. Panics if commit does not exist.
+	GetFileMust(commitHash string, filePath string) (*incblame.Blame, error)
+	// Commits returns all stored commits.
+	Commits() []string
+	// CommitsInMemory returns the number of commits kept in memory.
+	CommitsInMemory() int
+	// Delete removes commit.
+	Delete(commitHash string)
+	// Close releases resources used by repo. Repo should not be used after.
+	Close() error
+	Debug() string
+}
+
+// MemRepo keeps all data in memory. Files are stored in Tree for each commit, sharing unchanged files and tree nodes with other commits. Line data of lines added in commit is interned.
+type MemRepo struct {
+	commits  map[string]*Tree
+	interner *Interner
+}
 
-func New() Repo {
-	return Repo{}
+func New() *MemRepo {
+	s := &MemRepo{}
+	s.commits = map[string]*Tree{}
+	s.interner = NewInterner(0)
+	return s
 }
 
-func (s Repo) Debug() string {
+func (s *MemRepo) Debug() string {
+	return debugTrees(s.commits)
+}
+
+func debugTrees(commits map[string]*Tree) string {
 	res := []string{}
 	type KV struct {
 		K string
-		V map[string]*incblame.Blame
+		V *Tree
 	}
 	var arr []KV
-	for k, v := range s {
+	for k, v := range commits {
 		arr = append(arr, KV{k, v})
 	}
 	sort.Slice(arr, func(i, j int) bool {
@@ -38,23 +76,12 @@ func (s Repo) Debug() string {
 	for _, v := range arr {
 		commit := v.K
 		line("commit:" + commit)
-		type KV struct {
-			K string
-			V *incblame.Blame
-		}
-		var arr []KV
-		for k, v := range v.V {
-			arr = append(arr, KV{k, v})
-		}
-		sort.Slice(arr, func(i, j int) bool {
-			a := arr[i]
-			b := arr[j]
-			return a.K < b.K
-		})
-		for _, v := range arr {
+		paths := v.V.Paths()
+		sort.Strings(paths)
+		for _, fp := range paths {
 			line("commit:" + commit)
-			line("file:" + v.K)
-			line(v.V.String())
+			line("file:" + fp)
+			line(v.V.Get(fp).String())
 			line("")
 		}
 	}
@@ -62,37 +89,77 @@ func (s Repo) Debug() string {
 	return strings.Join(res, "")
 }
 
-func (s Repo) CommitsInMemory() int {
-	return len(s)
+func (s *MemRepo) CommitsInMemory() int {
+	return len(s.commits)
+}
+
+func (s *MemRepo) Commits() (res []string) {
+	for c := range s.commits {
+		res = append(res, c)
+	}
+	return
+}
+
+func (s *MemRepo) AddCommit(commitHash string) {
+	s.commits[commitHash] = NewTree()
+}
+
+func (s *MemRepo) ForkCommit(commitHash string, parentHash string) {
+	s.commits[commitHash] = s.GetCommitMust(parentHash)
+}
+
+func (s *MemRepo) SetFile(commitHash string, filePath string, blame *incblame.Blame) {
+	c, ok := s.commits[commitHash]
+	if !ok {
+		panic(fmt.Errorf("commit not found: %v when setting file: %v", commitHash, filePath))
+	}
+	for _, l := range blame.Lines {
+		if l.Commit == commitHash {
+			l.Line = s.interner.Intern(l.Line)
+		}
+	}
+	s.commits[commitHash] = c.Set(filePath, blame)
+}
+
+func (s *MemRepo) DeleteFile(commitHash string, filePath string) {
+	c, ok := s.commits[commitHash]
+	if !ok {
+		panic(fmt.Errorf("commit not found: %v when deleting file: %v", commitHash, filePath))
+	}
+	s.commits[commitHash] = c.Delete(filePath)
 }
 
-func (s Repo) AddCommit(commitHash string) {
-	s[commitHash] = map[string]*incblame.Blame{}
+func (s *MemRepo) Delete(commitHash string) {
+	delete(s.commits, commitHash)
 }
 
-func (s Repo) GetCommitMust(commitHash string) map[string]*incblame.Blame {
-	res, ok := s[commitHash]
+func (s *MemRepo) Close() error {
+	return nil
+}
+
+func (s *MemRepo) GetCommitMust(commitHash string) *Tree {
+	res, ok := s.commits[commitHash]
 	if !ok {
 		panic(fmt.Errorf("commit not found: %v", commitHash))
 	}
 	return res
 }
 
-func (s Repo) GetFileOptional(commitHash string, filePath string) *incblame.Blame {
-	c, ok := s[commitHash]
+func (s *MemRepo) GetFileOptional(commitHash string, filePath string) *incblame.Blame {
+	c, ok := s.commits[commitHash]
 	if !ok {
 		panic(fmt.Errorf("commit not found: %v when looking for file: %v", commitHash, filePath))
 	}
-	return c[filePath]
+	return c.Get(filePath)
 }
 
-func (s Repo) GetFileMust(commitHash string, filePath string) (*incblame.Blame, error) {
-	c, ok := s[commitHash]
+func (s *MemRepo) GetFileMust(commitHash string, filePath string) (*incblame.Blame, error) {
+	c, ok := s.commits[commitHash]
 	if !ok {
 		panic(fmt.Errorf("commit not found: %v when looking for file: %v", commitHash, filePath))
 	}
-	res, ok := c[filePath]
-	if !ok {
+	res := c.Get(filePath)
+	if res == nil {
 		return nil, fmt.Errorf("file is missing in commit. commit: %v file: %v", commitHash, filePath)
 	}
 	return res, nil
@@ -118,6 +185,6 @@ func (s *Unloader) Unload(commitHash string) {
 	if s.toUnload.Len() > maxCommitsInCheckpoint {
 		last := s.toUnload.Back()
 		s.toUnload.Remove(last)
-		delete(s.repo, last.Value.(string))
+		s.repo.Delete(last.Value.(string))
 	}
 }
@@ -0,0 +1,79 @@
+package repo
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// CheckpointStore keeps checkpoint bundles outside of local checkpoints dir, for example in object storage when workers are ephemeral. Store holds a single bundle for one repo, see WriteBundle for the format.
+type CheckpointStore interface {
+	// Get returns the stored bundle. Returns error satisfying os.IsNotExist if there is no bundle.
+	Get() (io.ReadCloser, error)
+	// Put stores bundle read from r, replacing the previous one. Previous bundle should stay available if Put fails.
+	Put(r io.Reader) error
+}
+
+// LocalCheckpointStore stores bundle in a file on local filesystem.
+type LocalCheckpointStore struct {
+	loc string
+}
+
+// NewLocalCheckpointStore creates store keeping bundle in file at loc. Parent dirs are created on Put.
+func NewLocalCheckpointStore(loc string) *LocalCheckpointStore {
+	s := &LocalCheckpointStore{}
+	s.loc = loc
+	return s
+}
+
+func (s *LocalCheckpointStore) Get() (io.ReadCloser, error) {
+	return os.Open(s.loc)
+}
+
+func (s *LocalCheckpointStore) Put(r io.Reader) error {
+	err := os.MkdirAll(filepath.Dir(s.loc), 0777)
+	if err != nil {
+		return err
+	}
+	err = writeFileFrom(s.loc+".tmp", r)
+	if err != nil {
+		return err
+	}
+	return os.Rename(s.loc+".tmp", s.loc)
+}
+
+// FetchCheckpoint gets bundle from store and extracts it into checkpoints dir, replacing local checkpoint. Returns false if store has no bundle.
+func FetchCheckpoint(store CheckpointStore, dir string) (header CheckpointHeader, ok bool, _ error) {
+	r, err := store.Get()
+	if os.IsNotExist(err) {
+		return header, false, nil
+	}
+	if err != nil {
+		return header, false, err
+	}
+	defer r.Close()
+	header, err = ReadBundle(r, dir)
+	if err != nil {
+		return header, false, err
+	}
+	return header, true, nil
+}
+
+// PutCheckpoint writes the latest checkpoint in dir to store as bundle.
+func PutCheckpoint(store CheckpointStore, dir string) error {
+	pr, pw := io.Pipe()
+	done := make(chan error)
+	go func() {
+		err := WriteBundle(pw, dir)
+		pw.CloseWithError(err)
+		done <- err
+	}()
+	err := store.Put(pr)
+	// unblock writer if Put returned without reading everything
+	pr.Close()
+	werr := <-done
+	if err != nil {
+		return err
+	}
+	return werr
+}
@@ -0,0 +1,88 @@
+package repo
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStoreRoundTrip(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+	store := NewLocalCheckpointStore(filepath.Join(dir, "store", "repo.tar.zst"))
+
+	_, ok, err := FetchCheckpoint(store, dst)
+	if err != nil || ok {
+		t.Fatalf("expected no checkpoint in empty store, got %v %v", ok, err)
+	}
+
+	repo := writeTestCheckpoint(t, src)
+	err = PutCheckpoint(store, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header, ok, err := FetchCheckpoint(store, dst)
+	if err != nil || !ok {
+		t.Fatalf("expected checkpoint in store, got %v %v", ok, err)
+	}
+	assert.Equal(t, "c1", header.LastCommit)
+
+	repo2, err := testReader(t).Read(dst, "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Debug() != repo2.Debug() {
+		t.Fatalf("wanted repo %v\ngot repo %v", repo.Debug(), repo2.Debug())
+	}
+}
+
+func TestBundleCorrupted(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	dst := filepath.Join(dir, "dst")
+
+	writeTestCheckpoint(t, src)
+	buf := bytes.NewBuffer(nil)
+	err := WriteBundle(buf, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+
+	// existing checkpoint is kept
+	writeTestCheckpoint(t, dst)
+	_, err = ReadBundle(bytes.NewReader(b[:len(b)/2]), dst)
+	if !IsCheckpointInvalid(err) {
+		t.Fatalf("expected invalid checkpoint, got %v", err)
+	}
+	_, err = testReader(t).Verify(dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dst, bundleDirName)); !os.IsNotExist(err) {
+		t.Fatal("expected temp dir to be removed")
+	}
+
+	_, err = ReadBundle(bytes.NewReader([]byte("not a bundle")), dst)
+	if !IsCheckpointInvalid(err) {
+		t.Fatalf("expected invalid checkpoint, got %v", err)
+	}
+
+	_, err = ReadBundle(bytes.NewReader(b), dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	infos, err := ioutil.ReadDir(filepath.Join(dst, checkpointDirName))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Len(t, infos, len(checkpointDataFiles(CheckpointFormatVersion))+1)
+}
@@ -0,0 +1,347 @@
+package repo
+
+import (
+	"math/bits"
+
+	"github.com/cespare/xxhash"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+)
+
+// Tree is a persistent map of file path to blame, used to store files of a commit.
+//
+// Set and Delete return a new tree and do not modify the original. Unchanged parts are shared between the original and the new tree, so a commit based on its parent only needs memory for changed files.
+//
+// Implemented as hash array mapped trie, with 32 children per node selected by 5 bits of path hash.
+type Tree struct {
+	root *treeNode
+	size int
+}
+
+const treeBits = 5
+const treeMask = 1<<treeBits - 1
+
+type treeNode struct {
+	// bitmap has bits set for children present, children are ordered by bit
+	bitmap   uint32
+	children []treeEntry
+}
+
+// treeEntry is either a subtree node or a leaf
+type treeEntry struct {
+	node *treeNode
+	leaf *treeLeaf
+}
+
+type treeLeaf struct {
+	hash uint64
+	// files with the same path hash, almost always one
+	files []treeFile
+}
+
+type treeFile struct {
+	path  string
+	blame *incblame.Blame
+}
+
+// NewTree returns an empty tree.
+func NewTree() *Tree {
+	return &Tree{}
+}
+
+// NewTreeFromFiles returns tree with files, sharing unchanged data with base. Base could be nil.
+func NewTreeFromFiles(base *Tree, files map[string]*incblame.Blame) *Tree {
+	res := base
+	if res == nil {
+		res = NewTree()
+	}
+	var removed []string
+	res.Range(func(filePath string, blame *incblame.Blame) {
+		if _, ok := files[filePath]; !ok {
+			removed = append(removed, filePath)
+		}
+	})
+	for _, fp := range removed {
+		res = res.Delete(fp)
+	}
+	for fp, bl := range files {
+		if res.Get(fp) != bl {
+			res = res.Set(fp, bl)
+		}
+	}
+	return res
+}
+
+// Len returns the number of files in tree.
+func (t *Tree) Len() int {
+	return t.size
+}
+
+// Get returns blame for file or nil if file does not exist.
+func (t *Tree) Get(filePath string) *incblame.Blame {
+	h := xxhash.Sum64String(filePath)
+	n := t.root
+	shift := uint(0)
+	for n != nil {
+		bit, pos := n.index(h, shift)
+		if n.bitmap&bit == 0 {
+			return nil
+		}
+		e := n.children[pos]
+		if e.node != nil {
+			n = e.node
+			shift += treeBits
+			continue
+		}
+		if e.leaf.hash != h {
+			return nil
+		}
+		for _, f := range e.leaf.files {
+			if f.path == filePath {
+				return f.blame
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// Set returns a new tree with file set to blame.
+func (t *Tree) Set(filePath string, blame *incblame.Blame) *Tree {
+	root, added := t.root.set(xxhash.Sum64String(filePath), 0, filePath, blame)
+	res := &Tree{root: root, size: t.size}
+	if added {
+		res.size++
+	}
+	return res
+}
+
+// Delete returns a new tree without file. Returns the same tree if file does not exist.
+func (t *Tree) Delete(filePath string) *Tree {
+	root, removed := t.root.delete(xxhash.Sum64String(filePath), 0, filePath)
+	if !removed {
+		return t
+	}
+	return &Tree{root: root, size: t.size - 1}
+}
+
+// Range calls cb for each file in tree. Order is not defined.
+func (t *Tree) Range(cb func(filePath string, blame *incblame.Blame)) {
+	t.root.rang(cb)
+}
+
+// Paths returns all file paths in tree.
+func (t *Tree) Paths() (res []string) {
+	res = make([]string, 0, t.size)
+	t.Range(func(filePath string, blame *incblame.Blame) {
+		res = append(res, filePath)
+	})
+	return
+}
+
+// Map returns files in tree as a map. Allocates a new map on each call.
+func (t *Tree) Map() map[string]*incblame.Blame {
+	res := make(map[string]*incblame.Blame, t.size)
+	t.Range(func(filePath string, blame *incblame.Blame) {
+		res[filePath] = blame
+	})
+	return res
+}
+
+// Diff calls cb for each file that is different in other. Blame is nil for files only in other and otherBlame is nil for files not in other. Subtrees shared between trees are skipped, so diff of a commit with its parent only visits changed files.
+func (t *Tree) Diff(other *Tree, cb func(filePath string, blame, otherBlame *incblame.Blame)) {
+	diffNodes(t.root, other.root, cb)
+}
+
+func diffNodes(a, b *treeNode, cb func(filePath string, blame, otherBlame *incblame.Blame)) {
+	if a == b {
+		return
+	}
+	var bitmap uint32
+	if a != nil {
+		bitmap |= a.bitmap
+	}
+	if b != nil {
+		bitmap |= b.bitmap
+	}
+	for bitmap != 0 {
+		bit := bitmap & -bitmap
+		bitmap &^= bit
+		ea := a.entry(bit)
+		eb := b.entry(bit)
+		if ea.node != nil && eb.node != nil {
+			diffNodes(ea.node, eb.node, cb)
+			continue
+		}
+		if ea.leaf != nil && ea.leaf == eb.leaf {
+			continue
+		}
+		fa := ea.files()
+		fb := eb.files()
+		for fp, bl := range fa {
+			if fb[fp] != bl {
+				cb(fp, bl, fb[fp])
+			}
+		}
+		for fp, bl := range fb {
+			if _, ok := fa[fp]; !ok {
+				cb(fp, nil, bl)
+			}
+		}
+	}
+}
+
+// entry returns child for bit or empty entry if there is none
+func (n *treeNode) entry(bit uint32) treeEntry {
+	if n == nil || n.bitmap&bit == 0 {
+		return treeEntry{}
+	}
+	return n.children[bits.OnesCount32(n.bitmap&(bit-1))]
+}
+
+// files returns all files in entry
+func (e treeEntry) files() map[string]*incblame.Blame {
+	res := map[string]*incblame.Blame{}
+	if e.node != nil {
+		e.node.rang(func(filePath string, blame *incblame.Blame) {
+			res[filePath] = blame
+		})
+	}
+	if e.leaf != nil {
+		for _, f := range e.leaf.files {
+			res[f.path] = f.blame
+		}
+	}
+	return res
+}
+
+// index returns bit for hash at shift and position of child in children
+func (n *treeNode) index(h uint64, shift uint) (bit uint32, pos int) {
+	bit = 1 << ((h >> shift) & treeMask)
+	pos = bits.OnesCount32(n.bitmap & (bit - 1))
+	return
+}
+
+func (n *treeNode) rang(cb func(filePath string, blame *incblame.Blame)) {
+	if n == nil {
+		return
+	}
+	for _, e := range n.children {
+		if e.node != nil {
+			e.node.rang(cb)
+			continue
+		}
+		for _, f := range e.leaf.files {
+			cb(f.path, f.blame)
+		}
+	}
+}
+
+// withEntry returns a copy of node with entry at pos replaced
+func (n *treeNode) withEntry(pos int, e treeEntry) *treeNode {
+	res := &treeNode{bitmap: n.bitmap}
+	res.children = make([]treeEntry, len(n.children))
+	copy(res.children, n.children)
+	res.children[pos] = e
+	return res
+}
+
+func (n *treeNode) set(h uint64, shift uint, filePath string, blame *incblame.Blame) (_ *treeNode, added bool) {
+	if n == nil {
+		n = &treeNode{}
+	}
+	bit, pos := n.index(h, shift)
+	if n.bitmap&bit == 0 {
+		res := &treeNode{bitmap: n.bitmap | bit}
+		res.children = make([]treeEntry, len(n.children)+1)
+		copy(res.children, n.children[:pos])
+		res.children[pos] = treeEntry{leaf: &treeLeaf{hash: h, files: []treeFile{{filePath, blame}}}}
+		copy(res.children[pos+1:], n.children[pos:])
+		return res, true
+	}
+	e := n.children[pos]
+	if e.node != nil {
+		child, added := e.node.set(h, shift+treeBits, filePath, blame)
+		return n.withEntry(pos, treeEntry{node: child}), added
+	}
+	if e.leaf.hash == h {
+		leaf := &treeLeaf{hash: h}
+		leaf.files = make([]treeFile, 0, len(e.leaf.files)+1)
+		added := true
+		for _, f := range e.leaf.files {
+			if f.path == filePath {
+				f.blame = blame
+				added = false
+			}
+			leaf.files = append(leaf.files, f)
+		}
+		if added {
+			leaf.files = append(leaf.files, treeFile{filePath, blame})
+		}
+		return n.withEntry(pos, treeEntry{leaf: leaf}), added
+	}
+	// different hash at the same position, move existing leaf one level down
+	// hashes are different, so they diverge before running out of bits
+	sub := &treeNode{}
+	subBit, _ := sub.index(e.leaf.hash, shift+treeBits)
+	sub.bitmap = subBit
+	sub.children = []treeEntry{e}
+	sub, _ = sub.set(h, shift+treeBits, filePath, blame)
+	return n.withEntry(pos, treeEntry{node: sub}), true
+}
+
+func (n *treeNode) delete(h uint64, shift uint, filePath string) (_ *treeNode, removed bool) {
+	if n == nil {
+		return nil, false
+	}
+	bit, pos := n.index(h, shift)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	e := n.children[pos]
+	var repl treeEntry
+	if e.node != nil {
+		child, removed := e.node.delete(h, shift+treeBits, filePath)
+		if !removed {
+			return n, false
+		}
+		switch {
+		case child == nil:
+		case len(child.children) == 1 && child.children[0].leaf != nil:
+			// collapse node with a single leaf to keep tree shallow
+			repl = child.children[0]
+		default:
+			repl = treeEntry{node: child}
+		}
+	} else {
+		if e.leaf.hash != h {
+			return n, false
+		}
+		found := false
+		leaf := &treeLeaf{hash: h}
+		for _, f := range e.leaf.files {
+			if f.path == filePath {
+				found = true
+				continue
+			}
+			leaf.files = append(leaf.files, f)
+		}
+		if !found {
+			return n, false
+		}
+		if len(leaf.files) != 0 {
+			repl = treeEntry{leaf: leaf}
+		}
+	}
+	if repl.node != nil || repl.leaf != nil {
+		return n.withEntry(pos, repl), true
+	}
+	if len(n.children) == 1 {
+		return nil, true
+	}
+	res := &treeNode{bitmap: n.bitmap &^ bit}
+	res.children = make([]treeEntry, 0, len(n.children)-1)
+	res.children = append(res.children, n.children[:pos]...)
+	res.children = append(res.children, n.children[pos+1:]...)
+	return res, true
+}
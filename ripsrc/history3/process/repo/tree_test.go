@@ -0,0 +1,146 @@
+package repo
+
+import (
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+)
+
+func assertTree(t *testing.T, want map[string]*incblame.Blame, got *Tree) {
+	t.Helper()
+	if got.Len() != len(want) {
+		t.Fatalf("invalid len, got %v want %v", got.Len(), len(want))
+	}
+	n := 0
+	got.Range(func(filePath string, blame *incblame.Blame) {
+		n++
+		if want[filePath] != blame {
+			t.Fatalf("invalid blame for %v", filePath)
+		}
+	})
+	if n != len(want) {
+		t.Fatalf("invalid range count, got %v want %v", n, len(want))
+	}
+	for fp, bl := range want {
+		if got.Get(fp) != bl {
+			t.Fatalf("invalid get for %v", fp)
+		}
+	}
+}
+
+func TestTreeRandomOps(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	tree := NewTree()
+	want := map[string]*incblame.Blame{}
+	for i := 0; i < 20000; i++ {
+		fp := "dir/file" + strconv.Itoa(rnd.Intn(3000))
+		if rnd.Intn(3) == 0 {
+			tree = tree.Delete(fp)
+			delete(want, fp)
+			continue
+		}
+		bl := &incblame.Blame{Commit: strconv.Itoa(i)}
+		tree = tree.Set(fp, bl)
+		want[fp] = bl
+	}
+	assertTree(t, want, tree)
+	if tree.Get("missing") != nil {
+		t.Fatal("expected nil for missing file")
+	}
+}
+
+func TestTreePersistent(t *testing.T) {
+	bl1 := &incblame.Blame{Commit: "c1"}
+	bl2 := &incblame.Blame{Commit: "c2"}
+
+	t1 := NewTree()
+	for i := 0; i < 100; i++ {
+		t1 = t1.Set(strconv.Itoa(i), bl1)
+	}
+	t2 := t1.Set("0", bl2).Delete("1").Set("new", bl2)
+
+	want1 := map[string]*incblame.Blame{}
+	for i := 0; i < 100; i++ {
+		want1[strconv.Itoa(i)] = bl1
+	}
+	assertTree(t, want1, t1)
+
+	want2 := map[string]*incblame.Blame{}
+	for k, v := range want1 {
+		want2[k] = v
+	}
+	want2["0"] = bl2
+	delete(want2, "1")
+	want2["new"] = bl2
+	assertTree(t, want2, t2)
+
+	if t1.Delete("missing") != t1 {
+		t.Fatal("deleting missing file should return the same tree")
+	}
+}
+
+func TestTreeFromFiles(t *testing.T) {
+	bl1 := &incblame.Blame{Commit: "c1"}
+	bl2 := &incblame.Blame{Commit: "c2"}
+	base := NewTree().Set("a", bl1).Set("b", bl1)
+	files := map[string]*incblame.Blame{"a": bl1, "c": bl2}
+	assertTree(t, files, NewTreeFromFiles(base, files))
+	assertTree(t, files, NewTreeFromFiles(nil, files))
+	assertTree(t, map[string]*incblame.Blame{"a": bl1, "b": bl1}, base)
+}
+
+func TestTreeDiff(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	base := NewTree()
+	for i := 0; i < 3000; i++ {
+		base = base.Set("dir/file"+strconv.Itoa(i), &incblame.Blame{Commit: "c1"})
+	}
+	other := base
+	want := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		fp := "dir/file" + strconv.Itoa(rnd.Intn(4000))
+		if rnd.Intn(3) == 0 {
+			other = other.Delete(fp)
+		} else {
+			other = other.Set(fp, &incblame.Blame{Commit: strconv.Itoa(i)})
+		}
+		want[fp] = base.Get(fp) != other.Get(fp)
+	}
+	got := map[string]bool{}
+	base.Diff(other, func(filePath string, blame, otherBlame *incblame.Blame) {
+		if blame != base.Get(filePath) || otherBlame != other.Get(filePath) {
+			t.Fatalf("invalid blames for %v", filePath)
+		}
+		got[filePath] = true
+	})
+	for fp, changed := range want {
+		if got[fp] != changed {
+			t.Fatalf("invalid diff for %v, got %v want %v", fp, got[fp], changed)
+		}
+	}
+	for fp := range got {
+		if _, ok := want[fp]; !ok {
+			t.Fatalf("unchanged file %v in diff", fp)
+		}
+	}
+	NewTree().Diff(other, func(filePath string, blame, otherBlame *incblame.Blame) {
+		if blame != nil || otherBlame != other.Get(filePath) {
+			t.Fatalf("invalid diff with empty tree for %v", filePath)
+		}
+	})
+}
+
+func TestInterner(t *testing.T) {
+	s := NewInterner(2)
+	a := s.Intern([]byte("a"))
+	if &s.Intern([]byte("a"))[0] != &a[0] {
+		t.Fatal("expected the same data for equal lines")
+	}
+	s.Intern([]byte("b"))
+	s.Intern([]byte("c"))
+	if s.Len() != 1 {
+		t.Fatalf("expected table reset after limit, got len %v", s.Len())
+	}
+}
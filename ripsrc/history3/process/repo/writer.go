@@ -1,9 +1,9 @@
 package repo
 
 import (
-	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
 	"time"
 
 	"github.com/cespare/xxhash"
@@ -15,7 +15,8 @@ import (
 
 const checkpointDirName = "checkpoint"
 
-const checkpointVersionFile = "checkpoint-version"
+// checkpointPrevDirName is the previous checkpoint kept while replacing it with a new one
+const checkpointPrevDirName = "checkpoint-prev"
 
 type CheckpointWriter struct {
 	logger logger.Logger
@@ -28,6 +29,11 @@ func NewCheckpointWriter(logger logger.Logger) *CheckpointWriter {
 }
 
 func (s *CheckpointWriter) Write(repo Repo, dir string, lastCommit string) error {
+	return s.WriteHeads(repo, dir, lastCommit, nil)
+}
+
+// WriteHeads writes checkpoint the same as Write, also storing processed commits without processed children in header. See CheckpointHeader.Heads.
+func (s *CheckpointWriter) WriteHeads(repo Repo, dir string, lastCommit string, heads []string) error {
 	if lastCommit == "" {
 		panic("no last commit provided")
 	}
@@ -36,7 +42,10 @@ func (s *CheckpointWriter) Write(repo Repo, dir string, lastCommit string) error
 	defer func() {
 		s.logger.Info("finished writing checkpoint", "duration", time.Since(start))
 	}()
-	s.logger.Info("preparing to write", "len(commits)", len(repo))
+	commits := repo.Commits()
+	// sorted for the same output on the same data
+	sort.Strings(commits)
+	s.logger.Info("preparing to write", "len(commits)", len(commits))
 
 	tmpDir := filepath.Join(dir, "tmp")
 	err := os.RemoveAll(tmpDir)
@@ -44,6 +53,7 @@ func (s *CheckpointWriter) Write(repo Repo, dir string, lastCommit string) error
 		return err
 	}
 
+	prevDir := filepath.Join(dir, checkpointPrevDirName)
 	dir = filepath.Join(dir, checkpointDirName)
 
 	repoWr, err := newMsgWriter(tmpDir, "repo")
@@ -62,6 +72,28 @@ func (s *CheckpointWriter) Write(repo Repo, dir string, lastCommit string) error
 	if err != nil {
 		return err
 	}
+	commitsWr, err := newMsgWriter(tmpDir, "commits")
+	if err != nil {
+		return err
+	}
+	writers := []*msgWriter{repoWr, blamesWr, linesWr, lineDataWr, commitsWr}
+
+	// commits in repo go first, reader restores them in this order
+	commitIndex := map[string]uint32{}
+	var commitRows []disk.Commit
+	for _, ch := range commits {
+		commitIndex[ch] = uint32(len(commitRows))
+		commitRows = append(commitRows, disk.Commit{Hash: ch, InRepo: true})
+	}
+	commitPointer := func(ch string) uint32 {
+		if i, ok := commitIndex[ch]; ok {
+			return i
+		}
+		i := uint32(len(commitRows))
+		commitIndex[ch] = i
+		commitRows = append(commitRows, disk.Commit{Hash: ch})
+		return i
+	}
 
 	blamePointerC := uint64(0)
 	blamePointers := map[*incblame.Blame]uint64{}
@@ -69,112 +101,198 @@ func (s *CheckpointWriter) Write(repo Repo, dir string, lastCommit string) error
 	linePointerC := uint64(0)
 	linePointers := map[*incblame.Line]uint64{}
 
-	lineData := map[uint64]bool{}
+	lineDataPointerC := uint64(0)
+	lineDataPointers := map[uint64]uint64{}
 
-	writeRepoRow := func(commit string, filePath string, blamePointer uint64) {
-		r := &disk.DataRow{}
-		r.Commit = commit
-		r.Path = filePath
-		r.BlamePointer = blamePointer
-		err := repoWr.Write(r)
-		if err != nil {
-			panic(err)
+	writeBlame := func(file *incblame.Blame) (uint64, error) {
+		if blp, ok := blamePointers[file]; ok {
+			return blp, nil
 		}
-	}
-
-	for ch, commit := range repo {
-
-		for fp, file := range commit {
-			if blp, ok := blamePointers[file]; ok {
-				writeRepoRow(ch, fp, blp)
+		bl := &disk.CompactBlame{}
+		bl.Commit = commitPointer(file.Commit)
+		bl.IsBinary = file.IsBinary
+		bl.Lines = make([]int64, 0, len(file.Lines))
+		prevLp := uint64(0)
+		addLine := func(lp uint64) {
+			bl.Lines = append(bl.Lines, int64(lp)-int64(prevLp))
+			prevLp = lp
+		}
+		for _, l := range file.Lines {
+			if lp, ok := linePointers[l]; ok {
+				addLine(lp)
 				continue
 			}
-			blamePointerC++
-			blp := blamePointerC
-			blamePointers[file] = blp
-
-			bl := &sBlame{}
-			bl.Pointer = blamePointerC
-			bl.Commit = file.Commit
-			bl.IsBinary = file.IsBinary
-			bl.LinePointers = make([]uint64, 0, len(file.Lines))
-			for _, l := range file.Lines {
-
-				if lp, ok := linePointers[l]; ok {
-					bl.LinePointers = append(bl.LinePointers, lp)
-					continue
-				}
-				linePointerC++
-				lp := linePointerC
-				linePointers[l] = lp
-
-				// line data
-				dp := xxhash.Sum64(l.Line)
-				if ok := lineData[dp]; !ok {
-					ld := &sLineData{
-						Pointer: dp,
-						Data:    l.Line}
-					err := lineDataWr.Write(ld)
-					if err != nil {
-						return err
-					}
-					lineData[dp] = true
+
+			// line data
+			h := xxhash.Sum64(l.Line)
+			dp, ok := lineDataPointers[h]
+			if !ok {
+				err := lineDataWr.Write(&disk.CompactLineData{Data: l.Line})
+				if err != nil {
+					return 0, err
 				}
+				lineDataPointerC++
+				dp = lineDataPointerC
+				lineDataPointers[h] = dp
+			}
 
-				l2 := &sLine{}
-				l2.Pointer = lp
-				l2.Commit = l.Commit
-				l2.LineDataPointer = dp
+			err := linesWr.Write(&disk.CompactLine{Commit: commitPointer(l.Commit), LineData: dp})
+			if err != nil {
+				return 0, err
+			}
+			linePointerC++
+			linePointers[l] = linePointerC
+			addLine(linePointerC)
+		}
+		err := blamesWr.Write(bl)
+		if err != nil {
+			return 0, err
+		}
+		blamePointerC++
+		blamePointers[file] = blamePointerC
+		return blamePointerC, nil
+	}
 
-				err := linesWr.Write(l2)
+	// only files changed compared to the previous commit are written
+	prev := map[string]*incblame.Blame{}
+	err = rangeCommits(repo, commits, func(ch string, files map[string]*incblame.Blame) error {
+		var paths []string
+		for fp, file := range files {
+			if prev[fp] != file {
+				paths = append(paths, fp)
+			}
+		}
+		for fp := range prev {
+			if _, ok := files[fp]; !ok {
+				paths = append(paths, fp)
+			}
+		}
+		sort.Strings(paths)
+		for _, fp := range paths {
+			row := &disk.Row{}
+			row.Commit = commitIndex[ch]
+			row.Path = fp
+			if file, ok := files[fp]; ok {
+				row.Blame, err = writeBlame(file)
 				if err != nil {
 					return err
 				}
-
-				bl.LinePointers = append(bl.LinePointers, lp)
 			}
-
-			err := blamesWr.Write(bl)
+			err := repoWr.Write(row)
 			if err != nil {
 				return err
 			}
+		}
+		prev = files
+		return nil
+	})
+	if err != nil {
+		return err
+	}
 
-			writeRepoRow(ch, fp, blp)
+	for i := range commitRows {
+		err := commitsWr.Write(&commitRows[i])
+		if err != nil {
+			return err
 		}
 	}
 
-	err = repoWr.Finish()
-	if err != nil {
-		return err
+	header := CheckpointHeader{}
+	header.FormatVersion = CheckpointFormatVersion
+	header.LastCommit = lastCommit
+	if len(heads) > 1 {
+		header.Heads = append([]string(nil), heads...)
+		sort.Strings(header.Heads)
 	}
-	err = blamesWr.Finish()
-	if err != nil {
-		return err
+	header.Created = time.Now().UTC()
+	header.Files = map[string]CheckpointFile{}
+	for _, wr := range writers {
+		info, err := wr.Finish()
+		if err != nil {
+			return err
+		}
+		kind := filepath.Base(wr.loc)
+		header.Files[kind] = info
+		s.logger.Debug("checkpoint file size", "file", kind, "size", info.Size, "raw_size", info.RawSize)
 	}
-	err = linesWr.Finish()
+	size, rawSize := header.Size()
+	s.logger.Info("checkpoint size", "size", size, "raw_size", rawSize, "commits", len(commitRows), "blames", blamePointerC, "lines", linePointerC, "line_data", lineDataPointerC)
+
+	// header is written last, checkpoint without header is incomplete
+	err = writeHeader(tmpDir, header)
 	if err != nil {
 		return err
 	}
-	err = lineDataWr.Finish()
-	if err != nil {
-		return err
+
+	return replaceCheckpoint(dir, prevDir, tmpDir)
+}
+
+// commitRanger is implemented by repos that could read commits without changing what is kept in memory, see DiskRepo.RangeCommits.
+type commitRanger interface {
+	RangeCommits(commits []string, cb func(commitHash string, files map[string]*incblame.Blame))
+}
+
+// rangeCommits calls cb with files of each commit in order. Stops on the first error.
+func rangeCommits(repo Repo, commits []string, cb func(commitHash string, files map[string]*incblame.Blame) error) error {
+	r, ok := repo.(commitRanger)
+	if !ok {
+		for _, ch := range commits {
+			err := cb(ch, repo.GetCommitMust(ch).Map())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
 	}
+	var err error
+	r.RangeCommits(commits, func(ch string, files map[string]*incblame.Blame) {
+		if err != nil {
+			return
+		}
+		err = cb(ch, files)
+	})
+	return err
+}
 
-	err = writeFileAtomic(filepath.Join(tmpDir, checkpointVersionFile), []byte(lastCommit))
-	if err != nil {
-		return err
+// replaceCheckpoint moves complete checkpoint from newDir to dir.
+func replaceCheckpoint(dir string, prevDir string, newDir string) error {
+	// keep the previous checkpoint until the new one is in place, so interrupted write always leaves a complete checkpoint
+	// if checkpoint does not exist, previous one is the only complete checkpoint and is removed after rename
+	if _, err := os.Stat(dir); err == nil {
+		err := os.RemoveAll(prevDir)
+		if err != nil {
+			return err
+		}
+		err = os.Rename(dir, prevDir)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = os.RemoveAll(dir)
+	err := os.Rename(newDir, dir)
 	if err != nil {
 		return err
 	}
 
-	return os.Rename(tmpDir, dir)
+	return os.RemoveAll(prevDir)
 }
 
 func writeFileAtomic(loc string, data []byte) error {
-	err := ioutil.WriteFile(loc+".tmp", data, 0777)
+	f, err := os.Create(loc + ".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Sync()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	err = f.Close()
 	if err != nil {
 		return err
 	}
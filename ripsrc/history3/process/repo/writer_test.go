@@ -2,6 +2,7 @@ package repo
 
 import (
 	"os"
+	"strconv"
 	"testing"
 
 	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
@@ -45,7 +46,7 @@ func BenchmarkWritingCheckpointRandomData(b *testing.B) {
 		repo.AddCommit(ch)
 		for i := 0; i < 10; i++ {
 			fp := randomString(100)
-			repo[ch][fp] = randomBlame(100)
+			repo.SetFile(ch, fp, randomBlame(100))
 		}
 	}
 
@@ -60,3 +61,77 @@ func BenchmarkWritingCheckpointRandomData(b *testing.B) {
 		}
 	}
 }
+
+func TestWriterChangedFilesRoundTrip(t *testing.T) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+
+	repo := New()
+	repo.AddCommit("c0")
+	for i := 0; i < 5; i++ {
+		repo.SetFile("c0", "f"+strconv.Itoa(i), randomBlameLineLen(3, 10))
+	}
+	prev := "c0"
+	for i := 1; i < 10; i++ {
+		ch := "c" + strconv.Itoa(i)
+		repo.ForkCommit(ch, prev)
+		switch i % 3 {
+		case 0:
+			repo.SetFile(ch, "f"+strconv.Itoa(i), randomBlameLineLen(2, 10))
+		case 1:
+			repo.DeleteFile(ch, "f"+strconv.Itoa(i%5))
+		case 2:
+			// no changes
+		}
+		prev = ch
+	}
+	// commit without files
+	repo.AddCommit("empty")
+
+	err := testWriter(t).Write(repo, dir, prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo2, err := testReader(t).Read(dir, prev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.Debug() != repo2.Debug() {
+		t.Fatalf("wanted repo %v\ngot repo %v", repo.Debug(), repo2.Debug())
+	}
+}
+
+func BenchmarkReadingCheckpointRandomData(b *testing.B) {
+	dir := tempDir()
+	defer os.RemoveAll(dir)
+
+	repo := New()
+	prev := ""
+	for i := 0; i < 100; i++ {
+		ch := randomString(32)
+		if prev == "" {
+			repo.AddCommit(ch)
+		} else {
+			repo.ForkCommit(ch, prev)
+		}
+		for i := 0; i < 10; i++ {
+			fp := randomString(100)
+			repo.SetFile(ch, fp, randomBlameLineLen(100, 80))
+		}
+		prev = ch
+	}
+	err := NewCheckpointWriter(logger.NewDefaultLogger(os.Stdout)).Write(repo, dir, prev)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.ResetTimer()
+
+	rd := NewCheckpointReader(logger.NewDefaultLogger(os.Stdout))
+	for i := 0; i < b.N; i++ {
+		_, err := rd.Read(dir, prev)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
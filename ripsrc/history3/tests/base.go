@@ -2,12 +2,15 @@ package tests
 
 import (
 	"context"
+	"os"
 	"testing"
 
 	"github.com/pinpt/ripsrc/ripsrc/gitexec"
 	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
 	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
 	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+	"github.com/pinpt/ripsrc/ripsrc/singlepass"
 )
 
 var gitCommand = "git"
@@ -16,6 +19,8 @@ type Test struct {
 	t        *testing.T
 	repoName string
 	tempDir  string
+	// prepare is called with unzipped repo dir before processing, used to change working tree
+	prepare func(repoDir string)
 }
 
 func NewTest(t *testing.T, repoName string) *Test {
@@ -25,24 +30,61 @@ func NewTest(t *testing.T, repoName string) *Test {
 	return s
 }
 
+// Run processes test repo and returns results. When opts do not select diff source, the repo is also processed with DiffSourceNative and results are checked to be the same. When processing from the beginning, the same is checked for single pass log.
 func (s *Test) Run(opts *process.Opts) []process.Result {
+	if opts == nil {
+		opts = &process.Opts{}
+	}
+	res := s.run(*opts, false)
+	if opts.DiffSource == process.DiffSourceGit {
+		nativeOpts := *opts
+		nativeOpts.DiffSource = process.DiffSourceNative
+		native := s.run(nativeOpts, false)
+		assertResult(s.t, res, native)
+	}
+	if opts.DiffSource == process.DiffSourceGit && opts.CommitFromIncl == "" {
+		singlePass := s.run(*opts, true)
+		assertResult(s.t, res, singlePass)
+	}
+	return res
+}
+
+func (s *Test) run(opts process.Opts, singlePass bool) []process.Result {
 	t := s.t
 	dirs := testutil.UnzipTestRepo(s.repoName)
 	defer dirs.Remove()
 
+	if s.prepare != nil {
+		s.prepare(dirs.RepoDir)
+	}
+
 	ctx := context.Background()
 	err := gitexec.Prepare(ctx, gitCommand, dirs.RepoDir)
 	if err != nil {
 		t.Fatal(err)
 	}
 
-	if opts == nil {
-		opts = &process.Opts{}
-	}
 	opts.RepoDir = dirs.RepoDir
 	opts.DisableCache = true
 
-	p := process.New(*opts)
+	if singlePass {
+		sp, err := singlepass.Run(singlepass.Opts{
+			RepoDir:     dirs.RepoDir,
+			AllBranches: opts.AllBranches,
+			Logger:      logger.NewDefaultLogger(os.Stdout),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer sp.Remove()
+		opts.ParentsGraph = sp.Graph
+		opts.Patches, err = sp.Patches()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := process.New(opts)
 	res, err := p.RunGetAll()
 	if err != nil {
 		t.Fatal(err)
@@ -63,6 +105,9 @@ func assertResult(t *testing.T, want, got []process.Result) {
 			t.Fatalf("invalid commit hash %v at pos %v", w.Commit, i)
 		}
 		commit := w.Commit
+		if w.Ignored != g.Ignored {
+			t.Fatalf("invalid ignored flag %v for commit %v", g.Ignored, commit)
+		}
 		if len(w.Files) != len(g.Files) {
 			t.Fatalf("invalid number of entries %v for commit %v, got\n%v", len(g.Files), commit, g.Files)
 		}
@@ -0,0 +1,334 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+)
+
+func TestCheckpointEveryResume(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("starting_from_commit")
+	defer dirs.Remove()
+	err := gitexec.Prepare(context.Background(), gitCommand, dirs.RepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := NewTest(t, "starting_from_commit").Run(nil)
+	if len(want) != 4 {
+		t.Fatalf("expected 4 commits in test repo, got %v", len(want))
+	}
+
+	checkpointsDir := filepath.Join(dirs.TempWrapper, "checkpoints")
+	interruptedDir := filepath.Join(dirs.TempWrapper, "interrupted")
+
+	opts := process.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = checkpointsDir
+	opts.CheckpointEvery = process.CheckpointEvery{Commits: 2}
+	p := process.New(opts)
+
+	resChan := make(chan process.Result)
+	done := make(chan error)
+	go func() {
+		done <- p.Run(resChan)
+	}()
+	var got []process.Result
+	for r := range resChan {
+		got = append(got, r)
+		if len(got) == 3 {
+			// checkpoint after the second commit is written before the third is processed, and the next one is written only after the fourth commit is received
+			// save it to simulate interrupted run
+			copyDir(t, checkpointsDir, interruptedDir)
+		}
+	}
+	err = <-done
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResult(t, want, got)
+	if p.Timing().CheckpointsCount != 2 {
+		t.Fatalf("expected intermediate and final checkpoint, got %v", p.Timing().CheckpointsCount)
+	}
+
+	last, err := p.LastCheckpointCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != want[3].Commit {
+		t.Fatalf("invalid final checkpoint commit %v", last)
+	}
+
+	opts.CheckpointsDir = interruptedDir
+	p = process.New(opts)
+	last, err = p.LastCheckpointCommit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if last != want[1].Commit {
+		t.Fatalf("invalid intermediate checkpoint commit %v", last)
+	}
+
+	opts.CommitFromIncl = last
+	got, err = process.New(opts).RunGetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResult(t, want[1:], got)
+}
+
+// bundlesStore keeps every checkpoint bundle put into it
+type bundlesStore struct {
+	bundles [][]byte
+}
+
+func (s *bundlesStore) Get() (io.ReadCloser, error) {
+	if len(s.bundles) == 0 {
+		return nil, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(s.bundles[len(s.bundles)-1])), nil
+}
+
+func (s *bundlesStore) Put(r io.Reader) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	s.bundles = append(s.bundles, b)
+	return nil
+}
+
+// TestCheckpointEveryResumeBranches resumes from every intermediate checkpoint written on history with side branch. Checkpoints are written while the branch is not merged, resume processes heads of both branches again.
+func TestCheckpointEveryResumeBranches(t *testing.T) {
+	for _, repoName := range []string{"merge_basic", "merge_branch_select"} {
+		t.Run(repoName, func(t *testing.T) {
+			dirs := testutil.UnzipTestRepo(repoName)
+			defer dirs.Remove()
+			err := gitexec.Prepare(context.Background(), gitCommand, dirs.RepoDir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := NewTest(t, repoName).Run(nil)
+			headers := testCheckpointEveryResume(t, dirs, want, process.CheckpointEvery{Commits: 1})
+			if maxHeads(headers) != 2 {
+				t.Fatalf("expected checkpoint with 2 heads, got %v", maxHeads(headers))
+			}
+		})
+	}
+}
+
+// TestCheckpointEveryLongBranch checks that intermediate checkpoints are written while a long-lived branch is open and that every one of them could be used to resume.
+func TestCheckpointEveryLongBranch(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("basic")
+	defer dirs.Remove()
+	git := func(args ...string) {
+		t.Helper()
+		r, err := gitexec.Exec(context.Background(), gitCommand, dirs.RepoDir, append([]string{"-c", "user.name=A", "-c", "user.email=a@example.com"}, args...))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = ioutil.ReadAll(r)
+		r.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	write := func(name string, i int) {
+		t.Helper()
+		loc := filepath.Join(dirs.RepoDir, name)
+		b, _ := ioutil.ReadFile(loc)
+		b = append(b, []byte("line"+strconv.Itoa(i)+"\n")...)
+		err := ioutil.WriteFile(loc, b, 0666)
+		if err != nil {
+			t.Fatal(err)
+		}
+		git("add", name)
+		git("commit", "-q", "-m", name+strconv.Itoa(i))
+	}
+	git("checkout", "-q", "-b", "long")
+	for i := 0; i < 5; i++ {
+		write("long.txt", i)
+	}
+	git("checkout", "-q", "master")
+	for i := 0; i < 5; i++ {
+		write("main.txt", i)
+	}
+	git("merge", "-q", "--no-edit", "long")
+	write("main.txt", 5)
+
+	want, err := process.New(process.Opts{RepoDir: dirs.RepoDir, DisableCache: true}).RunGetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := testCheckpointEveryResume(t, dirs, want, process.CheckpointEvery{Commits: 3})
+	if maxHeads(headers) != 2 {
+		t.Fatalf("expected checkpoint written while branch is open, got headers %+v", headers)
+	}
+}
+
+// testCheckpointEveryResume processes repo writing intermediate checkpoints and resumes from each of them. Resumed run must return the same results for heads stored in checkpoint and commits that are not their ancestors. Returns headers of all written checkpoints.
+func testCheckpointEveryResume(t *testing.T, dirs testutil.TestRepoDirs, want []process.Result, every process.CheckpointEvery) (headers []process.CheckpointHeader) {
+	t.Helper()
+	store := &bundlesStore{}
+	opts := process.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	opts.CheckpointEvery = every
+	opts.CheckpointStore = store
+	got, err := process.New(opts).RunGetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResult(t, want, got)
+	if len(store.bundles) < 2 {
+		t.Fatalf("expected intermediate checkpoints, got %v", len(store.bundles))
+	}
+
+	for i, b := range store.bundles {
+		opts := process.Opts{}
+		opts.RepoDir = dirs.RepoDir
+		opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "resume", strconv.Itoa(i))
+		opts.CheckpointStore = &bundlesStore{bundles: [][]byte{b}}
+		p := process.New(opts)
+		_, err := p.FetchCheckpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		header, err := p.VerifyCheckpoint()
+		if err != nil {
+			t.Fatal(err)
+		}
+		headers = append(headers, header)
+		opts.CommitFromIncl = header.LastCommit
+		got, err := process.New(opts).RunGetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertResult(t, resumeWant(t, dirs.RepoDir, want, header.ResumeHeads()), got)
+	}
+	return
+}
+
+// resumeWant returns results for commits processed when resuming from heads, the heads and commits that are not ancestors of their parents.
+func resumeWant(t *testing.T, repoDir string, want []process.Result, heads []string) (res []process.Result) {
+	t.Helper()
+	args := []string{"rev-list", "HEAD"}
+	for _, h := range heads {
+		args = append(args, "^"+h+"^@")
+	}
+	r, err := gitexec.Exec(context.Background(), gitCommand, repoDir, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commits := map[string]bool{}
+	for _, c := range strings.Fields(string(b)) {
+		commits[c] = true
+	}
+	for _, r := range want {
+		if commits[r.Commit] {
+			res = append(res, r)
+		}
+	}
+	return
+}
+
+func maxHeads(headers []process.CheckpointHeader) (res int) {
+	for _, h := range headers {
+		if len(h.ResumeHeads()) > res {
+			res = len(h.ResumeHeads())
+		}
+	}
+	return
+}
+
+// failingStore returns error on Put
+type failingStore struct{}
+
+func (s failingStore) Get() (io.ReadCloser, error) {
+	return nil, os.ErrNotExist
+}
+
+func (s failingStore) Put(r io.Reader) error {
+	return errors.New("put failed")
+}
+
+// TestCheckpointErrorRemovesSpill checks that commits moved to disk are removed when Run returns error.
+func TestCheckpointErrorRemovesSpill(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("starting_from_commit")
+	defer dirs.Remove()
+	err := gitexec.Prepare(context.Background(), gitCommand, dirs.RepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	opts := process.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	opts.MaxMemoryMB = 1
+	opts.CheckpointEvery = process.CheckpointEvery{Commits: 1}
+	opts.CheckpointStore = failingStore{}
+	_, err = process.New(opts).RunGetAll()
+	if err == nil || !strings.Contains(err.Error(), "put failed") {
+		t.Fatalf("expected store error, got %v", err)
+	}
+	_, err = os.Stat(filepath.Join(opts.CheckpointsDir, "pp-git-cache", "spill"))
+	if !os.IsNotExist(err) {
+		t.Fatalf("spill dir not removed, stat err %v", err)
+	}
+}
+
+func TestParseCheckpointEvery(t *testing.T) {
+	got, err := process.ParseCheckpointEvery("1000")
+	if err != nil || got.Commits != 1000 || got.Duration != 0 {
+		t.Fatalf("invalid commits interval %+v %v", got, err)
+	}
+	got, err = process.ParseCheckpointEvery("10m")
+	if err != nil || got.Commits != 0 || got.Duration.Minutes() != 10 {
+		t.Fatalf("invalid duration interval %+v %v", got, err)
+	}
+	_, err = process.ParseCheckpointEvery("often")
+	if err == nil {
+		t.Fatal("expected error for invalid interval")
+	}
+}
+
+func copyDir(t *testing.T, from, to string) {
+	t.Helper()
+	err := filepath.Walk(from, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, p)
+		if err != nil {
+			return err
+		}
+		loc := filepath.Join(to, rel)
+		if info.IsDir() {
+			return os.MkdirAll(loc, 0777)
+		}
+		b, err := ioutil.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(loc, b, 0777)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
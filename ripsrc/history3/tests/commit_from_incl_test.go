@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+)
+
+// testCommitFromIncl processes the whole repo to write checkpoint and then again from the commit returned by rev-list with args, using both diff sources. Commits that are not descendants of the commit must not be processed again.
+func testCommitFromIncl(t *testing.T, repoName string, revListArgs ...string) {
+	dirs := testutil.UnzipTestRepo(repoName)
+	defer dirs.Remove()
+	ctx := context.Background()
+	err := gitexec.Prepare(ctx, gitCommand, dirs.RepoDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := gitexec.Exec(ctx, gitCommand, dirs.RepoDir, append([]string{"rev-list"}, revListArgs...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	commit := strings.TrimSpace(string(b))
+	if commit == "" {
+		t.Fatalf("commit not found using rev-list %v", revListArgs)
+	}
+
+	opts := process.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.CheckpointsDir = filepath.Join(dirs.TempWrapper, "checkpoints")
+	all, err := process.New(opts).RunGetAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []process.Result
+	for i, res := range all {
+		if res.Commit == commit {
+			want = all[i:]
+		}
+	}
+
+	for _, src := range []process.DiffSourceType{process.DiffSourceGit, process.DiffSourceNative} {
+		opts := opts
+		opts.DiffSource = src
+		opts.CommitFromIncl = commit
+		// checkpoint is at HEAD and has all commits
+		opts.NoStrictResume = true
+		got, err := process.New(opts).RunGetAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertResult(t, want, got)
+	}
+}
+
+func TestCommitFromInclMerge(t *testing.T) {
+	testCommitFromIncl(t, "merge_basic", "--merges", "-n1", "HEAD")
+}
+
+func TestCommitFromInclRoot(t *testing.T) {
+	testCommitFromIncl(t, "merge_basic", "--max-parents=0", "HEAD")
+}
@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+)
+
+// c2 changes indentation and is listed in .git-blame-ignore-revs added in c3
+func ignoreRevsFileWant(c2Lines string, c2Ignored bool) []process.Result {
+	c1 := "9b618dd2ee2429c51a5c4ff74e11b34f6622efe5"
+	c2 := "98620cc70ae417946a433ceee1677a30f1aadec8"
+	c3 := "4283522c278675651705d047c619797ace3f3a08"
+	return []process.Result{
+		{
+			Commit: c1,
+			Files: map[string]*incblame.Blame{
+				"main.go": file(c1,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func main() {`, c1),
+					line(`  a := 1`, c1),
+					line(`  println(a)`, c1),
+					line(`}`, c1),
+				),
+			},
+		},
+		{
+			Commit:  c2,
+			Ignored: c2Ignored,
+			Files: map[string]*incblame.Blame{
+				"main.go": file(c2,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func main() {`, c1),
+					line(`	a := 1`, c2Lines),
+					line(`	println(a)`, c2Lines),
+					line(`}`, c1),
+				),
+			},
+		},
+		{
+			Commit: c3,
+			Files: map[string]*incblame.Blame{
+				".git-blame-ignore-revs": file(c3,
+					line(`# formatting`, c3),
+					line(c2+` # gofmt`, c3),
+				),
+			},
+		},
+	}
+}
+
+func TestIgnoreRevsFile(t *testing.T) {
+	test := NewTest(t, "ignore_revs_file")
+	got := test.Run(nil)
+	assertResult(t, ignoreRevsFileWant("9b618dd2ee2429c51a5c4ff74e11b34f6622efe5", true), got)
+}
+
+func TestIgnoreRevsFileDisabled(t *testing.T) {
+	test := NewTest(t, "ignore_revs_file")
+	got := test.Run(&process.Opts{NoIgnoreRevsFile: true})
+	assertResult(t, ignoreRevsFileWant("98620cc70ae417946a433ceee1677a30f1aadec8", false), got)
+}
+
+func TestIgnoreRevsFileDefaultInvalidLine(t *testing.T) {
+	test := NewTest(t, "ignore_revs_file")
+	test.prepare = func(repoDir string) {
+		appendFile(t, filepath.Join(repoDir, process.DefaultIgnoreRevsFile), "98620cc\n")
+	}
+	got := test.Run(nil)
+	assertResult(t, ignoreRevsFileWant("9b618dd2ee2429c51a5c4ff74e11b34f6622efe5", true), got)
+}
+
+func TestIgnoreRevsFileExplicitInvalidLine(t *testing.T) {
+	dirs := testutil.UnzipTestRepo("ignore_revs_file")
+	defer dirs.Remove()
+	appendFile(t, filepath.Join(dirs.RepoDir, process.DefaultIgnoreRevsFile), "98620cc\n")
+
+	opts := process.Opts{}
+	opts.RepoDir = dirs.RepoDir
+	opts.DisableCache = true
+	opts.IgnoreRevsFile = process.DefaultIgnoreRevsFile
+	_, err := process.New(opts).RunGetAll()
+	if err == nil {
+		t.Fatal("expected error for invalid line in ignore revs file set in options")
+	}
+}
+
+func appendFile(t *testing.T, loc string, data string) {
+	f, err := os.OpenFile(loc, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	_, err = f.WriteString(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseIgnoreRevs(t *testing.T) {
+	data := `
+# formatting
+98620CC70AE417946A433CEEE1677A30F1AADEC8 # gofmt
+
+9b618dd2ee2429c51a5c4ff74e11b34f6622efe5
+`
+	got, err := process.ParseIgnoreRevs(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"98620cc70ae417946a433ceee1677a30f1aadec8", "9b618dd2ee2429c51a5c4ff74e11b34f6622efe5"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("got %v, wanted %v", got, want)
+	}
+
+	_, err = process.ParseIgnoreRevs(strings.NewReader("98620cc\n"))
+	if err == nil {
+		t.Fatal("expected error for abbreviated hash")
+	}
+}
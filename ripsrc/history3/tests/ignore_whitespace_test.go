@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+)
+
+// c2 changes indentation from spaces to tabs, c3 changes value on line 4
+const (
+	iwC1 = "1cb9ff9b3cb35f4ea88cfc718800b1a546f0014b"
+	iwC2 = "b8b573d1de4b3a9b6890efc8fb7702a13136c220"
+	iwC3 = "962434c3bbfe0d0fa7875e08f7b2962367bf97be"
+)
+
+func ignoreWhitespaceWant(c2Lines, c3Line4, c3Line5 string) []process.Result {
+	c1 := iwC1
+	c2 := iwC2
+	c3 := iwC3
+	return []process.Result{
+		{
+			Commit: c1,
+			Files: map[string]*incblame.Blame{
+				"main.go": file(c1,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func main() {`, c1),
+					line(`  a := 1`, c1),
+					line(`  println(a)`, c1),
+					line(`}`, c1),
+				),
+			},
+		},
+		{
+			Commit: c2,
+			Files: map[string]*incblame.Blame{
+				"main.go": file(c2,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func main() {`, c1),
+					line(`	a := 1`, c2Lines),
+					line(`	println(a)`, c2Lines),
+					line(`}`, c1),
+				),
+			},
+		},
+		{
+			Commit: c3,
+			Files: map[string]*incblame.Blame{
+				"main.go": file(c3,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func main() {`, c1),
+					line(`	a := 2`, c3Line4),
+					line(`	println(a)`, c3Line5),
+					line(`}`, c1),
+				),
+			},
+		},
+	}
+}
+
+func TestIgnoreWhitespaceDisabled(t *testing.T) {
+	test := NewTest(t, "ignore_whitespace")
+	got := test.Run(nil)
+	assertResult(t, ignoreWhitespaceWant(iwC2, iwC3, iwC2), got)
+}
+
+func TestIgnoreWhitespace(t *testing.T) {
+	test := NewTest(t, "ignore_whitespace")
+	got := test.Run(&process.Opts{IgnoreWhitespace: true})
+	assertResult(t, ignoreWhitespaceWant(iwC1, iwC3, iwC1), got)
+}
+
+func TestIgnoreRevs(t *testing.T) {
+	test := NewTest(t, "ignore_whitespace")
+	got := test.Run(&process.Opts{IgnoreRevs: []string{iwC2, iwC3}})
+	want := ignoreWhitespaceWant(iwC1, iwC1, iwC1)
+	want[1].Ignored = true
+	want[2].Ignored = true
+	assertResult(t, want, got)
+}
+
+func TestIgnoreRevsWithWhitespaceChange(t *testing.T) {
+	test := NewTest(t, "ignore_whitespace")
+	got := test.Run(&process.Opts{IgnoreRevs: []string{iwC3}})
+	want := ignoreWhitespaceWant(iwC2, iwC2, iwC2)
+	want[2].Ignored = true
+	assertResult(t, want, got)
+}
@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+)
+
+// helper function moved from a.go to new file b.go in c2, util2 copied from c.go not changed in c2
+const (
+	movesC1 = "6c57415c1b58caeddc209c37d2b1b5d4f229061e"
+	movesC2 = "05cf600941030414914cdffadbe3db7b6be1a7a3"
+)
+
+func movesWant(utilCommit string) []process.Result {
+	c1 := movesC1
+	c2 := movesC2
+	return []process.Result{
+		{
+			Commit: c1,
+			Files: map[string]*incblame.Blame{
+				"a.go": file(c1,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func main() {`, c1),
+					line(`	helper()`, c1),
+					line(`}`, c1),
+					line(``, c1),
+					line(`func helper() {`, c1),
+					line(`	println("helper function output with a longer message")`, c1),
+					line(`}`, c1),
+				),
+				"c.go": file(c1,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func util(value int) int {`, c1),
+					line(`	return multiplyValueByTwoAndAddOne(value)`, c1),
+					line(`}`, c1),
+				),
+			},
+		},
+		{
+			Commit: c2,
+			Files: map[string]*incblame.Blame{
+				"a.go": file(c2,
+					line(`package main`, c1),
+					line(``, c1),
+					line(`func main() {`, c1),
+					line(`	helper()`, c1),
+					line(`}`, c1),
+				),
+				"b.go": file(c2,
+					line(`package main`, c2),
+					line(``, c2),
+					line(`func helper() {`, c1),
+					line(`	println("helper function output with a longer message")`, c1),
+					line(`}`, c1),
+					line(``, c2),
+					line(`func util2(value int) int {`, c2),
+					line(`	return multiplyValueByTwoAndAddOne(value)`, utilCommit),
+					line(`}`, utilCommit),
+				),
+			},
+		},
+	}
+}
+
+func TestMovesDisabled(t *testing.T) {
+	test := NewTest(t, "moves")
+	got := test.Run(nil)
+	want := movesWant(movesC2)
+	// without move detection all lines in b.go are attributed to c2
+	for _, l := range want[1].Files["b.go"].Lines {
+		l.Commit = movesC2
+	}
+	assertResult(t, want, got)
+}
+
+func TestMovesChangedFiles(t *testing.T) {
+	test := NewTest(t, "moves")
+	got := test.Run(&process.Opts{DetectMoves: true})
+	assertResult(t, movesWant(movesC2), got)
+}
+
+func TestMovesAllFiles(t *testing.T) {
+	test := NewTest(t, "moves")
+	got := test.Run(&process.Opts{DetectMoves: true, DetectCopiesAllFiles: true})
+	// git blame -C -C -C requires 40 alphanumeric chars for copies from other files, so it would not attribute these lines to c1
+	assertResult(t, movesWant(movesC1), got)
+}
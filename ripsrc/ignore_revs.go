@@ -0,0 +1,17 @@
+package ripsrc
+
+import (
+	"context"
+)
+
+// resolveIgnoreRevs returns full hashes for Opts.IgnoreRevs.
+func (s *Ripsrc) resolveIgnoreRevs(ctx context.Context) (res []string, _ error) {
+	for _, ref := range s.opts.IgnoreRevs {
+		sha, err := s.revParseCommit(ctx, ref)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, sha)
+	}
+	return
+}
@@ -0,0 +1,97 @@
+package ripsrc
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// LicensesOpts controls the commits for which Licenses inventory is returned.
+type LicensesOpts struct {
+	// Commits to create inventory at. If empty, inventory is created for the last processed commit (HEAD).
+	Commits []string
+}
+
+// LicenseInventory contains all licenses found in repo at a specific commit.
+type LicenseInventory struct {
+	Commit string
+	Date   time.Time
+	// Licenses detected in license files with confidence, sorted by Filename.
+	Licenses []LicenseSummary
+	// Declared are the SPDX-License-Identifier tags found in files, sorted by Filename and Line.
+	Declared []DeclaredLicense
+	// Counts is the number of license files and tags by license name or expression.
+	Counts map[string]int
+	// CopyrightHolders are the holders from copyright notices sorted by Files desc.
+	CopyrightHolders []CopyrightHolderSummary
+}
+
+// DeclaredLicense is a license expression declared in file using SPDX-License-Identifier tag.
+type DeclaredLicense struct {
+	Filename string
+	// Line is the line number of the tag starting from 1.
+	Line    int
+	License string
+	// Skipped is the reason the file was not analyzed as source, for example vendored file.
+	Skipped string
+}
+
+// CopyrightHolderSummary is the number of files with copyright notice for holder.
+type CopyrightHolderSummary struct {
+	Holder string
+	Files  int
+}
+
+// Licenses returns license inventory at HEAD or at the passed commits: licenses detected in license files, SPDX license tags and copyright holders in files. Processes all commits since CommitFromIncl.
+func (s *Ripsrc) Licenses(ctx context.Context, opts LicensesOpts) (res []LicenseInventory, _ error) {
+	err := s.codeAtCommits(ctx, opts.Commits, func(commit Commit, files map[string]BlameResult) {
+		res = append(res, licenseInventory(commit, files))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func licenseInventory(commit Commit, files map[string]BlameResult) (res LicenseInventory) {
+	res.Commit = commit.SHA
+	res.Date = commit.Date
+	res.Counts = map[string]int{}
+	holders := map[string]int{}
+	for _, f := range files {
+		if f.License != nil {
+			res.Licenses = append(res.Licenses, LicenseSummary{Filename: f.Filename, License: *f.License})
+			res.Counts[f.License.Name]++
+		}
+		for _, tag := range f.LicenseTags {
+			res.Declared = append(res.Declared, DeclaredLicense{Filename: f.Filename, Line: tag.Line, License: tag.License, Skipped: f.Skipped})
+			res.Counts[tag.License]++
+		}
+		for _, h := range f.CopyrightHolders {
+			holders[h]++
+		}
+	}
+	sort.Slice(res.Licenses, func(i, j int) bool {
+		return res.Licenses[i].Filename < res.Licenses[j].Filename
+	})
+	sort.Slice(res.Declared, func(i, j int) bool {
+		a := res.Declared[i]
+		b := res.Declared[j]
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line
+	})
+	for h, c := range holders {
+		res.CopyrightHolders = append(res.CopyrightHolders, CopyrightHolderSummary{Holder: h, Files: c})
+	}
+	sort.Slice(res.CopyrightHolders, func(i, j int) bool {
+		a := res.CopyrightHolders[i]
+		b := res.CopyrightHolders[j]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Holder < b.Holder
+	})
+	return
+}
@@ -0,0 +1,211 @@
+package parentsgraph
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/parentsgraph/parentsp"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/msgpfile"
+)
+
+// storedGraphVersion is increased on incompatible changes, stored graph with other version is read again from git
+const storedGraphVersion = 1
+
+// readCached loads graph stored at CacheLoc and updates it with commits added and removed since, then stores the result. All commits are read from git if stored graph is missing or could not be updated.
+func (s *Graph) readCached() error {
+	tips, err := s.tips()
+	if err != nil {
+		return err
+	}
+	updated := false
+	st, err := loadStored(s.opts.CacheLoc)
+	switch {
+	case os.IsNotExist(err):
+		s.opts.Logger.Info("parentsgraph: no stored graph, reading all commits")
+	case err != nil:
+		s.opts.Logger.Info("parentsgraph: could not load stored graph, reading all commits", "err", err)
+	case st.Version != storedGraphVersion || st.AllBranches != s.opts.AllBranches:
+		s.opts.Logger.Info("parentsgraph: stored graph is for other version or options, reading all commits")
+	default:
+		err := s.update(st, tips)
+		if err != nil {
+			s.opts.Logger.Info("parentsgraph: could not update stored graph, reading all commits", "err", err)
+		} else {
+			updated = true
+		}
+	}
+	if !updated {
+		err := s.retrieveParents(tips)
+		if err != nil {
+			return err
+		}
+	}
+	return msgpfile.Save(s.opts.CacheLoc, s.toStored(tips))
+}
+
+// Save stores graph at opts.CacheLoc, so that Read with CacheLoc could update it later. Used for graph built without Read, for example by singlepass. RepoDir and AllBranches have to be the same as used when building the graph. Graph is not stored if current tips are not in it, for example when branch was updated after graph was built.
+func (s *Graph) Save(opts Opts) error {
+	s.opts = opts
+	tips, err := s.tips()
+	if err != nil {
+		return err
+	}
+	for _, tip := range tips {
+		if _, ok := s.Parents[tip]; !ok {
+			s.opts.Logger.Info("parentsgraph: graph does not have all tips, not storing", "tip", tip)
+			return nil
+		}
+	}
+	return msgpfile.Save(s.opts.CacheLoc, s.toStored(tips))
+}
+
+// tips returns commits that graph is read from, HEAD and with AllBranches also all refs
+func (s *Graph) tips() ([]string, error) {
+	args := []string{"rev-list", "--no-walk", "HEAD"}
+	if s.opts.AllBranches {
+		args = append(args, "--all")
+	}
+	res, err := s.gitLines(args)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(res)
+	return res, nil
+}
+
+// update sets Parents to stored graph with commits reachable from new tips added and commits no longer reachable removed.
+func (s *Graph) update(st *storedGraph, tips []string) error {
+	parents, err := st.parents()
+	if err != nil {
+		return err
+	}
+	s.Parents = parents
+	if equalStrings(st.Tips, tips) {
+		s.opts.Logger.Info("parentsgraph: stored graph is up to date", "commits", len(parents))
+		return nil
+	}
+
+	// git log fails if old tips are no longer in repo, in that case the whole graph is read again
+	args := []string{"log", "--no-abbrev-commit", "--pretty=format:%H@%P"}
+	args = append(args, tips...)
+	args = append(args, "--not")
+	args = append(args, st.Tips...)
+	args = append(args, "--")
+	r, err := gitexec.Exec(context.Background(), "git", s.opts.RepoDir, args)
+	if err != nil {
+		return err
+	}
+	added, err := parentsp.New(r).Run()
+	if err != nil {
+		return err
+	}
+	for commit, p := range added {
+		s.Parents[commit] = p
+	}
+
+	args = []string{"rev-list"}
+	args = append(args, st.Tips...)
+	args = append(args, "--not")
+	args = append(args, tips...)
+	args = append(args, "--")
+	removed, err := s.gitLines(args)
+	if err != nil {
+		return err
+	}
+	for _, commit := range removed {
+		delete(s.Parents, commit)
+	}
+	s.opts.Logger.Info("parentsgraph: updated stored graph", "added", len(added), "removed", len(removed), "commits", len(s.Parents))
+	return nil
+}
+
+func (s *Graph) gitLines(args []string) (res []string, _ error) {
+	r, err := gitexec.Exec(context.Background(), "git", s.opts.RepoDir, args)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" {
+			res = append(res, line)
+		}
+	}
+	return res, scanner.Err()
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *Graph) toStored(tips []string) *storedGraph {
+	res := &storedGraph{}
+	res.Version = storedGraphVersion
+	res.AllBranches = s.opts.AllBranches
+	res.Tips = tips
+	// sorted for the same output on the same data
+	for commit := range s.Parents {
+		res.Hashes = append(res.Hashes, commit)
+	}
+	sort.Strings(res.Hashes)
+	res.Commits = len(res.Hashes)
+	index := map[string]uint32{}
+	for i, commit := range res.Hashes {
+		index[commit] = uint32(i)
+	}
+	for _, commit := range res.Hashes[:res.Commits] {
+		var parents []uint32
+		for _, p := range s.Parents[commit] {
+			i, ok := index[p]
+			if !ok {
+				i = uint32(len(res.Hashes))
+				index[p] = i
+				res.Hashes = append(res.Hashes, p)
+			}
+			parents = append(parents, i)
+		}
+		res.Parents = append(res.Parents, parents)
+	}
+	return res
+}
+
+func (s *storedGraph) parents() (map[string][]string, error) {
+	if s.Commits > len(s.Hashes) || s.Commits != len(s.Parents) {
+		return nil, fmt.Errorf("invalid stored graph, commits %v hashes %v parents %v", s.Commits, len(s.Hashes), len(s.Parents))
+	}
+	res := map[string][]string{}
+	for i, commit := range s.Hashes[:s.Commits] {
+		var parents []string
+		for _, p := range s.Parents[i] {
+			if int(p) >= len(s.Hashes) {
+				return nil, fmt.Errorf("invalid stored graph, parent position %v out of range", p)
+			}
+			parents = append(parents, s.Hashes[p])
+		}
+		res[commit] = parents
+	}
+	return res, nil
+}
+
+func loadStored(loc string) (*storedGraph, error) {
+	res := &storedGraph{}
+	err := msgpfile.Load(loc, res)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
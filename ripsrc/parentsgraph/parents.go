@@ -21,6 +21,8 @@ type Opts struct {
 	RepoDir     string
 	AllBranches bool
 	Logger      logger.Logger
+	// CacheLoc is the file to keep graph in between runs. When set, Read loads the stored graph and reads from git only commits added since it was stored, removing commits that are no longer reachable. Optional, all commits are read from git if empty.
+	CacheLoc string
 }
 
 func New(opts Opts) *Graph {
@@ -42,7 +44,12 @@ func (s *Graph) Read() error {
 	defer func() {
 		s.opts.Logger.Info("parentsgraph: completed reading", "d", time.Since(start))
 	}()
-	err := s.retrieveParents()
+	var err error
+	if s.opts.CacheLoc != "" {
+		err = s.readCached()
+	} else {
+		err = s.retrieveParents(nil)
+	}
 	if err != nil {
 		return err
 	}
@@ -66,8 +73,9 @@ func (s *Graph) childrenFromParents() {
 	}
 }
 
-func (s *Graph) retrieveParents() error {
-	r, err := s.gitLogParents()
+// retrieveParents reads all commits reachable from revs, or from HEAD (all branches with AllBranches) if revs is empty.
+func (s *Graph) retrieveParents(revs []string) error {
+	r, err := s.gitLogParents(revs)
 	if err != nil {
 		return err
 	}
@@ -83,7 +91,7 @@ func (s *Graph) retrieveParents() error {
 	return nil
 }
 
-func (s *Graph) gitLogParents() (io.ReadCloser, error) {
+func (s *Graph) gitLogParents(revs []string) (io.ReadCloser, error) {
 	args := []string{
 		"log",
 		"-m",
@@ -92,7 +100,9 @@ func (s *Graph) gitLogParents() (io.ReadCloser, error) {
 		"--pretty=format:%H@%P",
 	}
 
-	if s.opts.AllBranches {
+	if len(revs) != 0 {
+		args = append(args, revs...)
+	} else if s.opts.AllBranches {
 		args = append(args, "--all")
 	}
 
@@ -0,0 +1,17 @@
+//go:generate msgp -unexported
+
+package parentsgraph
+
+// storedGraph is the graph kept between runs, see Opts.CacheLoc. Hashes are stored once and parents refer to them by position.
+type storedGraph struct {
+	Version     int  `msg:"v"`
+	AllBranches bool `msg:"a"`
+	// Tips are the commits graph was read from, the next run reads only commits not reachable from them.
+	Tips []string `msg:"t"`
+	// Hashes are the commits in graph, followed by parents that are not in graph, for example in shallow clones.
+	Hashes []string `msg:"h"`
+	// Commits is the number of commits in graph, the first entries in Hashes.
+	Commits int `msg:"c"`
+	// Parents are the parents of each commit in graph as positions in Hashes.
+	Parents [][]uint32 `msg:"p"`
+}
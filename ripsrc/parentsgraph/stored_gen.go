@@ -0,0 +1,390 @@
+package parentsgraph
+
+// Code generated by github.com/tinylib/msgp DO NOT EDIT.
+
+import (
+	"github.com/tinylib/msgp/msgp"
+)
+
+// DecodeMsg implements msgp.Decodable
+func (z *storedGraph) DecodeMsg(dc *msgp.Reader) (err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, err = dc.ReadMapHeader()
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, err = dc.ReadMapKeyPtr()
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "v":
+			z.Version, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "Version")
+				return
+			}
+		case "a":
+			z.AllBranches, err = dc.ReadBool()
+			if err != nil {
+				err = msgp.WrapError(err, "AllBranches")
+				return
+			}
+		case "t":
+			var zb0002 uint32
+			zb0002, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Tips")
+				return
+			}
+			if cap(z.Tips) >= int(zb0002) {
+				z.Tips = (z.Tips)[:zb0002]
+			} else {
+				z.Tips = make([]string, zb0002)
+			}
+			for za0001 := range z.Tips {
+				z.Tips[za0001], err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Tips", za0001)
+					return
+				}
+			}
+		case "h":
+			var zb0003 uint32
+			zb0003, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Hashes")
+				return
+			}
+			if cap(z.Hashes) >= int(zb0003) {
+				z.Hashes = (z.Hashes)[:zb0003]
+			} else {
+				z.Hashes = make([]string, zb0003)
+			}
+			for za0002 := range z.Hashes {
+				z.Hashes[za0002], err = dc.ReadString()
+				if err != nil {
+					err = msgp.WrapError(err, "Hashes", za0002)
+					return
+				}
+			}
+		case "c":
+			z.Commits, err = dc.ReadInt()
+			if err != nil {
+				err = msgp.WrapError(err, "Commits")
+				return
+			}
+		case "p":
+			var zb0004 uint32
+			zb0004, err = dc.ReadArrayHeader()
+			if err != nil {
+				err = msgp.WrapError(err, "Parents")
+				return
+			}
+			if cap(z.Parents) >= int(zb0004) {
+				z.Parents = (z.Parents)[:zb0004]
+			} else {
+				z.Parents = make([][]uint32, zb0004)
+			}
+			for za0003 := range z.Parents {
+				var zb0005 uint32
+				zb0005, err = dc.ReadArrayHeader()
+				if err != nil {
+					err = msgp.WrapError(err, "Parents", za0003)
+					return
+				}
+				if cap(z.Parents[za0003]) >= int(zb0005) {
+					z.Parents[za0003] = (z.Parents[za0003])[:zb0005]
+				} else {
+					z.Parents[za0003] = make([]uint32, zb0005)
+				}
+				for za0004 := range z.Parents[za0003] {
+					z.Parents[za0003][za0004], err = dc.ReadUint32()
+					if err != nil {
+						err = msgp.WrapError(err, "Parents", za0003, za0004)
+						return
+					}
+				}
+			}
+		default:
+			err = dc.Skip()
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	return
+}
+
+// EncodeMsg implements msgp.Encodable
+func (z *storedGraph) EncodeMsg(en *msgp.Writer) (err error) {
+	// map header, size 6
+	// write "v"
+	err = en.Append(0x86, 0xa1, 0x76)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.Version)
+	if err != nil {
+		err = msgp.WrapError(err, "Version")
+		return
+	}
+	// write "a"
+	err = en.Append(0xa1, 0x61)
+	if err != nil {
+		return
+	}
+	err = en.WriteBool(z.AllBranches)
+	if err != nil {
+		err = msgp.WrapError(err, "AllBranches")
+		return
+	}
+	// write "t"
+	err = en.Append(0xa1, 0x74)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Tips)))
+	if err != nil {
+		err = msgp.WrapError(err, "Tips")
+		return
+	}
+	for za0001 := range z.Tips {
+		err = en.WriteString(z.Tips[za0001])
+		if err != nil {
+			err = msgp.WrapError(err, "Tips", za0001)
+			return
+		}
+	}
+	// write "h"
+	err = en.Append(0xa1, 0x68)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Hashes)))
+	if err != nil {
+		err = msgp.WrapError(err, "Hashes")
+		return
+	}
+	for za0002 := range z.Hashes {
+		err = en.WriteString(z.Hashes[za0002])
+		if err != nil {
+			err = msgp.WrapError(err, "Hashes", za0002)
+			return
+		}
+	}
+	// write "c"
+	err = en.Append(0xa1, 0x63)
+	if err != nil {
+		return
+	}
+	err = en.WriteInt(z.Commits)
+	if err != nil {
+		err = msgp.WrapError(err, "Commits")
+		return
+	}
+	// write "p"
+	err = en.Append(0xa1, 0x70)
+	if err != nil {
+		return
+	}
+	err = en.WriteArrayHeader(uint32(len(z.Parents)))
+	if err != nil {
+		err = msgp.WrapError(err, "Parents")
+		return
+	}
+	for za0003 := range z.Parents {
+		err = en.WriteArrayHeader(uint32(len(z.Parents[za0003])))
+		if err != nil {
+			err = msgp.WrapError(err, "Parents", za0003)
+			return
+		}
+		for za0004 := range z.Parents[za0003] {
+			err = en.WriteUint32(z.Parents[za0003][za0004])
+			if err != nil {
+				err = msgp.WrapError(err, "Parents", za0003, za0004)
+				return
+			}
+		}
+	}
+	return
+}
+
+// MarshalMsg implements msgp.Marshaler
+func (z *storedGraph) MarshalMsg(b []byte) (o []byte, err error) {
+	o = msgp.Require(b, z.Msgsize())
+	// map header, size 6
+	// string "v"
+	o = append(o, 0x86, 0xa1, 0x76)
+	o = msgp.AppendInt(o, z.Version)
+	// string "a"
+	o = append(o, 0xa1, 0x61)
+	o = msgp.AppendBool(o, z.AllBranches)
+	// string "t"
+	o = append(o, 0xa1, 0x74)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Tips)))
+	for za0001 := range z.Tips {
+		o = msgp.AppendString(o, z.Tips[za0001])
+	}
+	// string "h"
+	o = append(o, 0xa1, 0x68)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Hashes)))
+	for za0002 := range z.Hashes {
+		o = msgp.AppendString(o, z.Hashes[za0002])
+	}
+	// string "c"
+	o = append(o, 0xa1, 0x63)
+	o = msgp.AppendInt(o, z.Commits)
+	// string "p"
+	o = append(o, 0xa1, 0x70)
+	o = msgp.AppendArrayHeader(o, uint32(len(z.Parents)))
+	for za0003 := range z.Parents {
+		o = msgp.AppendArrayHeader(o, uint32(len(z.Parents[za0003])))
+		for za0004 := range z.Parents[za0003] {
+			o = msgp.AppendUint32(o, z.Parents[za0003][za0004])
+		}
+	}
+	return
+}
+
+// UnmarshalMsg implements msgp.Unmarshaler
+func (z *storedGraph) UnmarshalMsg(bts []byte) (o []byte, err error) {
+	var field []byte
+	_ = field
+	var zb0001 uint32
+	zb0001, bts, err = msgp.ReadMapHeaderBytes(bts)
+	if err != nil {
+		err = msgp.WrapError(err)
+		return
+	}
+	for zb0001 > 0 {
+		zb0001--
+		field, bts, err = msgp.ReadMapKeyZC(bts)
+		if err != nil {
+			err = msgp.WrapError(err)
+			return
+		}
+		switch msgp.UnsafeString(field) {
+		case "v":
+			z.Version, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Version")
+				return
+			}
+		case "a":
+			z.AllBranches, bts, err = msgp.ReadBoolBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "AllBranches")
+				return
+			}
+		case "t":
+			var zb0002 uint32
+			zb0002, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Tips")
+				return
+			}
+			if cap(z.Tips) >= int(zb0002) {
+				z.Tips = (z.Tips)[:zb0002]
+			} else {
+				z.Tips = make([]string, zb0002)
+			}
+			for za0001 := range z.Tips {
+				z.Tips[za0001], bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Tips", za0001)
+					return
+				}
+			}
+		case "h":
+			var zb0003 uint32
+			zb0003, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Hashes")
+				return
+			}
+			if cap(z.Hashes) >= int(zb0003) {
+				z.Hashes = (z.Hashes)[:zb0003]
+			} else {
+				z.Hashes = make([]string, zb0003)
+			}
+			for za0002 := range z.Hashes {
+				z.Hashes[za0002], bts, err = msgp.ReadStringBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Hashes", za0002)
+					return
+				}
+			}
+		case "c":
+			z.Commits, bts, err = msgp.ReadIntBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Commits")
+				return
+			}
+		case "p":
+			var zb0004 uint32
+			zb0004, bts, err = msgp.ReadArrayHeaderBytes(bts)
+			if err != nil {
+				err = msgp.WrapError(err, "Parents")
+				return
+			}
+			if cap(z.Parents) >= int(zb0004) {
+				z.Parents = (z.Parents)[:zb0004]
+			} else {
+				z.Parents = make([][]uint32, zb0004)
+			}
+			for za0003 := range z.Parents {
+				var zb0005 uint32
+				zb0005, bts, err = msgp.ReadArrayHeaderBytes(bts)
+				if err != nil {
+					err = msgp.WrapError(err, "Parents", za0003)
+					return
+				}
+				if cap(z.Parents[za0003]) >= int(zb0005) {
+					z.Parents[za0003] = (z.Parents[za0003])[:zb0005]
+				} else {
+					z.Parents[za0003] = make([]uint32, zb0005)
+				}
+				for za0004 := range z.Parents[za0003] {
+					z.Parents[za0003][za0004], bts, err = msgp.ReadUint32Bytes(bts)
+					if err != nil {
+						err = msgp.WrapError(err, "Parents", za0003, za0004)
+						return
+					}
+				}
+			}
+		default:
+			bts, err = msgp.Skip(bts)
+			if err != nil {
+				err = msgp.WrapError(err)
+				return
+			}
+		}
+	}
+	o = bts
+	return
+}
+
+// Msgsize returns an upper bound estimate of the number of bytes occupied by the serialized message
+func (z *storedGraph) Msgsize() (s int) {
+	s = 1 + 2 + msgp.IntSize + 2 + msgp.BoolSize + 2 + msgp.ArrayHeaderSize
+	for za0001 := range z.Tips {
+		s += msgp.StringPrefixSize + len(z.Tips[za0001])
+	}
+	s += 2 + msgp.ArrayHeaderSize
+	for za0002 := range z.Hashes {
+		s += msgp.StringPrefixSize + len(z.Hashes[za0002])
+	}
+	s += 2 + msgp.IntSize + 2 + msgp.ArrayHeaderSize
+	for za0003 := range z.Parents {
+		s += msgp.ArrayHeaderSize + (len(z.Parents[za0003]) * (msgp.Uint32Size))
+	}
+	return
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/pinpt/ripsrc/ripsrc/gitexec"
 	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+	"github.com/pinpt/ripsrc/ripsrc/singlepass"
 )
 
 type Test struct {
@@ -50,6 +51,18 @@ func (s *Test) Run() *parentsgraph.Graph {
 	if err != nil {
 		t.Fatal(err)
 	}
+
+	// graph from single pass log should be the same
+	sp, err := singlepass.Run(singlepass.Opts{
+		RepoDir:     dirs.RepoDir,
+		AllBranches: opts.AllBranches,
+		Logger:      opts.Logger,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sp.Remove()
+	assertResult(t, sp.Graph, pg.Parents, pg.Children)
 	return pg
 }
 
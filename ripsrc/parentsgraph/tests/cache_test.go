@@ -0,0 +1,175 @@
+package tests
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/parentsgraph"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/testutil"
+)
+
+type cacheTest struct {
+	t        *testing.T
+	repoDir  string
+	cacheLoc string
+	opts     parentsgraph.Opts
+}
+
+func newCacheTest(t *testing.T, repoName string, allBranches bool) (*cacheTest, func()) {
+	dirs := testutil.UnzipTestRepo(repoName)
+	s := &cacheTest{}
+	s.t = t
+	s.repoDir = dirs.RepoDir
+	s.cacheLoc = filepath.Join(dirs.TempWrapper, "cache", "graph")
+	s.opts = parentsgraph.Opts{
+		RepoDir:     dirs.RepoDir,
+		AllBranches: allBranches,
+		Logger:      logger.NewDefaultLogger(os.Stdout),
+	}
+	return s, dirs.Remove
+}
+
+func (s *cacheTest) git(args ...string) {
+	s.t.Helper()
+	_, err := gitexec.Exec(context.Background(), "git", s.repoDir, args)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+}
+
+// assertCached reads graph using cache and checks that it is the same as read from git
+func (s *cacheTest) assertCached() {
+	t := s.t
+	t.Helper()
+	want := parentsgraph.New(s.opts)
+	err := want.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := s.opts
+	opts.CacheLoc = s.cacheLoc
+	got := parentsgraph.New(opts)
+	err = got.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertResult(t, got, want.Parents, want.Children)
+	if _, err := os.Stat(s.cacheLoc); err != nil {
+		t.Fatal("graph was not stored", err)
+	}
+}
+
+func TestCacheHead(t *testing.T) {
+	test, remove := newCacheTest(t, "multiple_branches", false)
+	defer remove()
+
+	c1 := "bdf8c8cfa9c027e58f1aea5c532ba0e9ef74bc4c"
+
+	test.git("checkout", "-q", "--detach", c1)
+	test.assertCached()
+	// unchanged
+	test.assertCached()
+	// commits added
+	test.git("checkout", "-q", "master")
+	test.assertCached()
+	// commits removed
+	test.git("checkout", "-q", "--detach", c1)
+	test.assertCached()
+}
+
+func TestCacheAllBranches(t *testing.T) {
+	test, remove := newCacheTest(t, "multiple_branches", true)
+	defer remove()
+
+	c2 := "d3a93f475772c90918ebc34e144e1c3554163a9f"
+	c4 := "3f18a2ea07832a18d0645df2aa666b339cee1a06"
+
+	test.assertCached()
+	// branches removed
+	test.git("branch", "-D", "b", "c")
+	test.assertCached()
+	// branches added
+	test.git("branch", "b", c2)
+	test.git("branch", "c", c4)
+	test.assertCached()
+}
+
+func TestCacheOldTipMissing(t *testing.T) {
+	test, remove := newCacheTest(t, "multiple_branches", true)
+	defer remove()
+
+	test.git("checkout", "-q", "-b", "d")
+	test.git("-c", "user.name=A", "-c", "user.email=a@b", "commit", "-q", "--allow-empty", "-m", "c5")
+	test.assertCached()
+
+	// stored tip no longer in repo, graph is read again
+	test.git("checkout", "-q", "master")
+	test.git("branch", "-D", "d")
+	test.git("reflog", "expire", "--expire=now", "--all")
+	test.git("gc", "-q", "--prune=now")
+	test.assertCached()
+}
+
+func TestCacheInvalid(t *testing.T) {
+	test, remove := newCacheTest(t, "multiple_branches", true)
+	defer remove()
+
+	err := os.MkdirAll(filepath.Dir(test.cacheLoc), 0777)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(test.cacheLoc, []byte("invalid"), 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	test.assertCached()
+}
+
+func TestCacheSave(t *testing.T) {
+	test, remove := newCacheTest(t, "multiple_branches", true)
+	defer remove()
+
+	want := parentsgraph.New(test.opts)
+	err := want.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := test.opts
+	opts.CacheLoc = test.cacheLoc
+
+	// graph without one of the branch tips
+	parents := map[string][]string{}
+	for commit, p := range want.Parents {
+		parents[commit] = p
+	}
+	for commit := range want.Children {
+		if len(want.Children[commit]) == 0 {
+			delete(parents, commit)
+			break
+		}
+	}
+	err = parentsgraph.NewFromMap(parents).Save(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(test.cacheLoc); !os.IsNotExist(err) {
+		t.Fatal("graph without all tips was stored", err)
+	}
+
+	err = parentsgraph.NewFromMap(want.Parents).Save(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(test.cacheLoc); err != nil {
+		t.Fatal("graph was not stored", err)
+	}
+	// stored graph is used and updated
+	test.git("checkout", "-q", "-b", "d")
+	test.git("-c", "user.name=A", "-c", "user.email=a@b", "commit", "-q", "--allow-empty", "-m", "c5")
+	test.assertCached()
+}
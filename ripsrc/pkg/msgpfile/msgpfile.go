@@ -0,0 +1,71 @@
+// Package msgpfile reads and writes single msgp object compressed with zstd, used for data kept between runs next to checkpoints.
+package msgpfile
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/tinylib/msgp/msgp"
+)
+
+// Load decodes obj from file at loc. Returns error satisfying os.IsNotExist if file does not exist.
+func Load(loc string, obj msgp.Decodable) error {
+	f, err := os.Open(loc)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+	return obj.DecodeMsg(msgp.NewReader(zr))
+}
+
+// Save writes obj to temp file and renames it to loc, so that interrupted write does not leave partial file at loc.
+func Save(loc string, obj msgp.Encodable) error {
+	err := os.MkdirAll(filepath.Dir(loc), 0777)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(loc + ".tmp")
+	if err != nil {
+		return err
+	}
+	err = write(f, obj)
+	if err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), loc)
+}
+
+func write(f *os.File, obj msgp.Encodable) error {
+	zw, err := zstd.NewWriter(f)
+	if err != nil {
+		return err
+	}
+	wr := msgp.NewWriter(zw)
+	err = obj.EncodeMsg(wr)
+	if err != nil {
+		return err
+	}
+	err = wr.Flush()
+	if err != nil {
+		return err
+	}
+	// close writes the end of zstd frame with checksum, used to detect truncated files
+	err = zw.Close()
+	if err != nil {
+		return err
+	}
+	// sync before rename, so file is complete after crash
+	err = f.Sync()
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,55 @@
+package msgpfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/tinylib/msgp/msgp"
+)
+
+func TestSaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ripsrc-msgpfile-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	loc := filepath.Join(dir, "sub", "data")
+
+	var got msgp.Raw
+	err = Load(loc, &got)
+	if !os.IsNotExist(err) {
+		t.Fatalf("expected not exist error, got %v", err)
+	}
+
+	want := msgp.Raw(msgp.AppendString(nil, "data"))
+	err = Save(loc, want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Load(loc, &got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("got %q, wanted %q", got, want)
+	}
+
+	entries, err := ioutil.ReadDir(filepath.Dir(loc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected only saved file in dir, got %v entries", len(entries))
+	}
+
+	err = ioutil.WriteFile(loc, want[:2], 0666)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Load(loc, &got)
+	if err == nil {
+		t.Fatal("expected error for invalid file")
+	}
+}
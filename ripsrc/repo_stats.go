@@ -0,0 +1,263 @@
+package ripsrc
+
+import (
+	"time"
+
+	"github.com/cespare/xxhash"
+)
+
+// RepoStats contains the running totals for the whole repo after a commit.
+type RepoStats struct {
+	Commit string
+	Date   time.Time
+
+	// Files is the number of all files in repo, including skipped.
+	Files int
+	// SourceFiles is the number of files that were not skipped.
+	SourceFiles int
+
+	Loc        int64
+	Sloc       int64
+	Comments   int64
+	Blanks     int64
+	Complexity int64
+
+	// Languages sorted by Sloc desc.
+	Languages []LanguageSummary
+	// Authors is the number of distinct author emails having at least one line in source files.
+	Authors int
+}
+
+type repoStatsFile struct {
+	skipped    bool
+	language   string
+	loc        int64
+	sloc       int64
+	comments   int64
+	blanks     int64
+	complexity int64
+	authors    map[string]int64
+}
+
+// statsShards is the number of shards in statsFiles
+const statsShards = 256
+
+// statsFiles is a map of file path to stats split into shards by path hash. Copies share shards and a shard is copied on the first change after copy, so copy does not depend on the number of files and a commit changing a few files only copies a few shards.
+type statsFiles struct {
+	// owner marks shards that could be modified in place, other shards are shared with copies
+	owner  *int
+	shards []*statsShard
+	size   int
+}
+
+type statsShard struct {
+	owner *int
+	files map[string]repoStatsFile
+}
+
+func newStatsFiles() *statsFiles {
+	s := &statsFiles{}
+	s.owner = new(int)
+	s.shards = make([]*statsShard, statsShards)
+	return s
+}
+
+// copy returns a copy to be updated separately. Shards become shared by both.
+func (s *statsFiles) copy() *statsFiles {
+	res := &statsFiles{}
+	res.owner = new(int)
+	res.shards = make([]*statsShard, len(s.shards))
+	copy(res.shards, s.shards)
+	res.size = s.size
+	s.owner = new(int)
+	return res
+}
+
+func shardIndex(filePath string) int {
+	return int(xxhash.Sum64String(filePath) % statsShards)
+}
+
+func (s *statsFiles) Get(filePath string) (repoStatsFile, bool) {
+	sh := s.shards[shardIndex(filePath)]
+	if sh == nil {
+		return repoStatsFile{}, false
+	}
+	f, ok := sh.files[filePath]
+	return f, ok
+}
+
+func (s *statsFiles) Set(filePath string, f repoStatsFile) {
+	sh := s.writable(shardIndex(filePath))
+	if _, ok := sh.files[filePath]; !ok {
+		s.size++
+	}
+	sh.files[filePath] = f
+}
+
+func (s *statsFiles) Delete(filePath string) {
+	if _, ok := s.Get(filePath); !ok {
+		return
+	}
+	delete(s.writable(shardIndex(filePath)).files, filePath)
+	s.size--
+}
+
+func (s *statsFiles) Len() int {
+	return s.size
+}
+
+// writable returns shard owned by s, copying it if it is shared.
+func (s *statsFiles) writable(i int) *statsShard {
+	sh := s.shards[i]
+	if sh != nil && sh.owner == s.owner {
+		return sh
+	}
+	res := &statsShard{owner: s.owner}
+	if sh == nil {
+		res.files = map[string]repoStatsFile{}
+	} else {
+		res.files = make(map[string]repoStatsFile, len(sh.files)+1)
+		for fp, f := range sh.files {
+			res.files[fp] = f
+		}
+	}
+	s.shards[i] = res
+	return res
+}
+
+// repoStats keeps the stats for each file and updates the totals when files change.
+type repoStats struct {
+	files       *statsFiles
+	sourceFiles int
+	total       LanguageSummary
+	languages   map[string]*LanguageSummary
+	authors     map[string]int64
+	// excludeBots skips bot lines when counting authors
+	excludeBots bool
+}
+
+func newRepoStats(excludeBots bool) *repoStats {
+	s := &repoStats{}
+	s.excludeBots = excludeBots
+	s.files = newStatsFiles()
+	s.languages = map[string]*LanguageSummary{}
+	s.authors = map[string]int64{}
+	return s
+}
+
+// copy returns a copy to be updated separately. Files are shared until changed, see statsFiles.
+func (s *repoStats) copy() *repoStats {
+	res := newRepoStats(s.excludeBots)
+	res.files = s.files.copy()
+	res.sourceFiles = s.sourceFiles
+	res.total = s.total
+	for k, l := range s.languages {
+		v := *l
+		res.languages[k] = &v
+	}
+	for k, v := range s.authors {
+		res.authors[k] = v
+	}
+	return res
+}
+
+func (s *repoStats) Remove(filename string) {
+	f, ok := s.files.Get(filename)
+	if !ok {
+		return
+	}
+	s.files.Delete(filename)
+	if f.skipped {
+		return
+	}
+	s.sourceFiles--
+	s.total.Loc -= f.loc
+	s.total.Sloc -= f.sloc
+	s.total.Comments -= f.comments
+	s.total.Blanks -= f.blanks
+	s.total.Complexity -= f.complexity
+
+	lang := s.languages[f.language]
+	lang.Files--
+	lang.Loc -= f.loc
+	lang.Sloc -= f.sloc
+	lang.Comments -= f.comments
+	lang.Blanks -= f.blanks
+	lang.Complexity -= f.complexity
+	if lang.Files == 0 {
+		delete(s.languages, f.language)
+	}
+
+	for email, c := range f.authors {
+		s.authors[email] -= c
+		if s.authors[email] == 0 {
+			delete(s.authors, email)
+		}
+	}
+}
+
+func (s *repoStats) Set(r BlameResult) {
+	s.Remove(r.Filename)
+	f := repoStatsFile{}
+	if r.Skipped != "" {
+		f.skipped = true
+		s.files.Set(r.Filename, f)
+		return
+	}
+	f.language = r.Language
+	f.loc = r.Loc
+	f.sloc = r.Sloc
+	f.comments = r.Comments
+	f.blanks = r.Blanks
+	f.complexity = r.Complexity
+	f.authors = map[string]int64{}
+	for _, l := range r.Lines {
+		if l.Bot && s.excludeBots {
+			continue
+		}
+		f.authors[l.Email]++
+	}
+	s.files.Set(r.Filename, f)
+
+	s.sourceFiles++
+	s.total.Loc += f.loc
+	s.total.Sloc += f.sloc
+	s.total.Comments += f.comments
+	s.total.Blanks += f.blanks
+	s.total.Complexity += f.complexity
+
+	lang, ok := s.languages[f.language]
+	if !ok {
+		lang = &LanguageSummary{Language: f.language}
+		s.languages[f.language] = lang
+	}
+	lang.Files++
+	lang.Loc += f.loc
+	lang.Sloc += f.sloc
+	lang.Comments += f.comments
+	lang.Blanks += f.blanks
+	lang.Complexity += f.complexity
+
+	for email, c := range f.authors {
+		s.authors[email] += c
+	}
+}
+
+func (s *repoStats) Stats(commit Commit) *RepoStats {
+	res := &RepoStats{}
+	res.Commit = commit.SHA
+	res.Date = commit.Date
+	res.Files = s.files.Len()
+	res.SourceFiles = s.sourceFiles
+	res.Loc = s.total.Loc
+	res.Sloc = s.total.Sloc
+	res.Comments = s.total.Comments
+	res.Blanks = s.total.Blanks
+	res.Complexity = s.total.Complexity
+	for _, l := range s.languages {
+		res.Languages = append(res.Languages, *l)
+	}
+	sortLanguages(res.Languages)
+	res.Authors = len(s.authors)
+	return res
+}
@@ -3,6 +3,7 @@ package ripsrc
 import (
 	"context"
 	"os"
+	"regexp"
 	"time"
 
 	"github.com/pinpt/ripsrc/ripsrc/parentsgraph"
@@ -11,11 +12,14 @@ import (
 	"github.com/pinpt/ripsrc/ripsrc/fileinfo"
 	"github.com/pinpt/ripsrc/ripsrc/gitexec"
 	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+	"github.com/pinpt/ripsrc/ripsrc/secrets"
 
 	"github.com/pinpt/ripsrc/ripsrc/history3/process"
+	"github.com/pinpt/ripsrc/ripsrc/history3/process/repo"
 )
 
 // Opts is configuration for running ripsrc on a single repo.
+// Checkpoints store resulting blame data, so changing options that affect blame (IgnoreWhitespace, IgnoreRevs, DetectMoves, BotLines) requires processing from the start to get consistent results.
 type Opts struct {
 	// RepoDir git repo to run commands on.
 	RepoDir string
@@ -25,6 +29,7 @@ type Opts struct {
 
 	// CheckpointsDir is the directory to store incremental data cache for this repo.
 	// If empty, directory is created inside repoDir.
+	// Commit graph and commit meta are kept there as well, so that incremental runs read only new commits from git. They are not included in CheckpointStore bundles and are read from git again when missing.
 	CheckpointsDir string
 
 	// NoStrictResume forces incremental processing to avoid checking that it continues from the same commit in previously finished on. Since incrementals save a large number of previous commits, it works even starting on another commit.
@@ -50,8 +55,87 @@ type Opts struct {
 
 	// PullRequestSHAs is a list of custom sha references to process similar to branches returned from the repo.
 	PullRequestSHAs []string
+
+	// CodeAgeBuckets are the upper bounds of buckets used in code age histograms. Default is 1 month, 3 months, 6 months, 1 year, 2 years and 5 years.
+	CodeAgeBuckets []time.Duration
+
+	// CodeAgeByCommit set to true to calculate line age distribution for the whole repo after each commit, returned in CommitCode.Age.
+	CodeAgeByCommit bool
+
+	// RepoStatsByCommit set to true to calculate running totals for the whole repo (sloc by language, files, authors, complexity) after each commit, returned in CommitCode.Stats.
+	RepoStatsByCommit bool
+
+	// LicenseMinConfidence is the minimum confidence for license to be detected in license file. Default is 0.85.
+	LicenseMinConfidence float32
+
+	// CustomLicenses are license texts matched against license files in addition to the known licenses, for example internal licenses.
+	// Custom licenses are also detected when built with no_license tag.
+	CustomLicenses []CustomLicense
+
+	// ScanSecrets set to true to check lines added in each commit for secrets such as keys and tokens, returned in BlameResult.Secrets.
+	// Secrets removed in later commits are still found in the commit that added them.
+	ScanSecrets bool
+
+	// SecretRules are used to find secrets when ScanSecrets is true. Default is secrets.DefaultRules.
+	SecretRules []SecretRule
+
+	// TODOs set to true to find comment lines with TODO tags, returned in BlameResult.TODOs.
+	TODOs bool
+
+	// TODOTags are the tags matched in comments when TODOs is true. Default is DefaultTODOTags.
+	TODOTags []string
+
+	// DetectMoves set to true to keep the original author and commit for lines moved or copied from files changed in the same commit, similar to git blame -M -C.
+	DetectMoves bool
+
+	// DetectCopiesAllFiles set to true to also look for copied lines in all files of the parent commit, similar to git blame -C -C -C. Requires DetectMoves. Slow for large repos.
+	DetectCopiesAllFiles bool
+
+	// IgnoreWhitespace set to true to keep the previous author for lines where only whitespace changed, for example after running a formatter.
+	IgnoreWhitespace bool
+
+	// IgnoreRevs are the commits that should not take ownership of the lines they change, similar to git blame --ignore-rev. Changed lines keep the previous author, new lines are still attributed to these commits.
+	// Accepts any commit reference. Only applies to regular (non-merge) commits.
+	IgnoreRevs []string
+
+	// IgnoreRevsFile is the file with commits to ignore in git blame --ignore-revs-file format, in addition to IgnoreRevs. Relative to RepoDir.
+	// If empty, blame.ignoreRevsFile from git config is used, and then .git-blame-ignore-revs if it exists. Invalid lines in .git-blame-ignore-revs are logged and skipped, while invalid lines in a file set in options or git config return an error.
+	IgnoreRevsFile string
+
+	// NoIgnoreRevsFile set to true to skip reading ignore revs file.
+	NoIgnoreRevsFile bool
+
+	// BotAuthors are case-insensitive regular expressions matched against author name and email to find bot and service account commits. Default is DefaultBotAuthors.
+	BotAuthors []string
+
+	// BotLines defines how lines from bot commits are handled. Default is BotLinesKeep.
+	BotLines BotLinesMode
+
+	// MaxMemoryMB is the approximate memory budget for blame data kept while processing. When exceeded, least recently used commits are moved to disk inside CheckpointsDir. Default is 0, keeping all data in memory.
+	MaxMemoryMB int
+
+	// CheckpointEvery enables writing intermediate checkpoints during CodeByCommit, so a long initial run could be resumed using Resume if interrupted. By default checkpoint is written only at the end. Checkpoint also stores heads of branches not merged into the current commit, these are processed again on resume.
+	CheckpointEvery CheckpointEvery
+
+	// Resume set to true to continue from the latest checkpoint in CheckpointsDir, sets CommitFromIncl to the checkpoint commit. The checkpoint commit and heads of branches open at checkpoint are processed again. Ignored if CommitFromIncl is set. Processes from the beginning if there is no checkpoint.
+	Resume bool
+
+	// RebuildInvalidCheckpoint set to true to process from the beginning when checkpoint for CommitFromIncl is missing, corrupted or written in unsupported format version. By default CodeByCommit returns the checkpoint error.
+	RebuildInvalidCheckpoint bool
+
+	// CheckpointStore keeps checkpoint bundle outside of CheckpointsDir, for example when workers are ephemeral. Checkpoint is fetched from store before resuming (Resume or CommitFromIncl) and put into store after each checkpoint write. Use NewLocalCheckpointStore for a file on local disk, other storage could be implemented outside of ripsrc. Optional.
+	CheckpointStore CheckpointStore
+
+	// DiffSource selects where diffs of processed commits come from. Default is DiffSourceGit, parsing git log -p output. DiffSourceNative reads objects directly and is expected to return the same results, except that .gitattributes diff settings are not used by either source.
+	DiffSource DiffSource
+
+	// SinglePassLog set to true to read commit graph, commit meta and patches using one git log instead of three, which roughly halves git time on large repos. Patches are kept in a compressed temp file until processed. Only used with DiffSourceGit when processing from the beginning, incremental runs read only new commits anyway.
+	SinglePassLog bool
 }
 
+// CustomLicense is a license text with name used in license detection
+type CustomLicense = fileinfo.CustomLicense
+
 // Ripsrc runs on a single repo.
 type Ripsrc struct {
 	GitProcessTimings process.Timing
@@ -61,10 +145,27 @@ type Ripsrc struct {
 	gitExecPrepared bool
 
 	commitMeta map[string]commitmeta.Commit
+	// commitMetaCache has commits processed in previous runs, used for blame lines
+	commitMetaCache *commitmeta.Cache
 
 	fileInfo *fileinfo.Process
 
 	commitGraph *parentsgraph.Graph
+
+	// snapshot is called after each commit with its results and blame tree, used to get the state of all files at commits. Called before results are returned, from the same goroutine that processes commits.
+	snapshot func(commit Commit, rs []BlameResult, tree *repo.Tree) error
+
+	// commitStates has the repo state after commit for commits with children not processed yet
+	commitStates map[string]*commitState
+	// commitStatesChildren is the number of processed children of commit
+	commitStatesChildren map[string]int
+
+	secrets *secrets.Scanner
+
+	todoRegexp *regexp.Regexp
+
+	botRegexp  *regexp.Regexp
+	botCommits map[string]bool
 }
 
 func New(opts Opts) *Ripsrc {
@@ -76,7 +177,14 @@ func New(opts Opts) *Ripsrc {
 	s := &Ripsrc{}
 	s.opts = opts
 	s.CodeInfoTimings = &CodeInfoTimings{}
-	s.fileInfo = fileinfo.New()
+	s.fileInfo = fileinfo.NewWithOpts(fileinfo.Opts{
+		LicenseMinConfidence: opts.LicenseMinConfidence,
+		CustomLicenses:       opts.CustomLicenses,
+	})
+	if opts.ScanSecrets {
+		s.secrets = secrets.New(opts.SecretRules)
+	}
+	s.todoRegexp = todoRegexp(opts.TODOTags)
 	return s
 }
 
@@ -98,6 +206,7 @@ func (s *Ripsrc) buildCommitGraph(ctx context.Context) error {
 		RepoDir:     s.opts.RepoDir,
 		AllBranches: s.opts.AllBranches,
 		Logger:      s.opts.Logger,
+		CacheLoc:    s.cacheLoc("graph"),
 	})
 
 	return s.commitGraph.Read()
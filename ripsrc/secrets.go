@@ -0,0 +1,51 @@
+package ripsrc
+
+import (
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+	"github.com/pinpt/ripsrc/ripsrc/secrets"
+)
+
+// SecretRule matches one kind of secret, see secrets.DefaultRules for examples.
+type SecretRule = secrets.Rule
+
+// Secret is a possible secret found in line added in commit.
+type Secret struct {
+	Commit   string
+	Filename string
+	// Line is the line number in file at commit, starting from 1.
+	Line int
+	// Rule is the ID of the rule that matched.
+	Rule string
+	// Redacted is the beginning of the secret value.
+	Redacted    string
+	AuthorName  string
+	AuthorEmail string
+	Date        time.Time
+}
+
+// scanSecrets checks lines added by diff of commit, including lines that keep the previous commit in blame, for example in ignored commits. For merges these are only the lines not coming from any of the parents.
+func (s *Ripsrc) scanSecrets(commit Commit, filePath string, bl *incblame.Blame, added []int) (res []Secret) {
+	if bl.IsBinary {
+		return nil
+	}
+	for _, n := range added {
+		if n < 1 || n > len(bl.Lines) {
+			continue
+		}
+		for _, m := range s.secrets.Scan(bl.Lines[n-1].Line) {
+			res = append(res, Secret{
+				Commit:      commit.SHA,
+				Filename:    filePath,
+				Line:        n,
+				Rule:        m.Rule,
+				Redacted:    m.Redacted,
+				AuthorName:  commit.AuthorName,
+				AuthorEmail: commit.AuthorEmail,
+				Date:        commit.Date,
+			})
+		}
+	}
+	return
+}
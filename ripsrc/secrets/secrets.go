@@ -0,0 +1,116 @@
+// Package secrets finds credentials and other secrets in source lines using regular expressions and entropy checks.
+package secrets
+
+import (
+	"math"
+	"regexp"
+)
+
+// Rule matches one kind of secret.
+type Rule struct {
+	// ID identifies the rule in findings.
+	ID string
+	// Regexp matches the secret. If it contains a capture group, the first group is used as the secret value, otherwise the full match.
+	Regexp *regexp.Regexp
+	// MinEntropy is the minimum Shannon entropy in bits per char of the secret value. 0 disables the check.
+	MinEntropy float64
+}
+
+// DefaultRules are used when no rules are passed to New.
+var DefaultRules = []Rule{
+	{ID: "aws-access-key-id", Regexp: regexp.MustCompile(`\b((?:AKIA|ASIA)[0-9A-Z]{16})\b`)},
+	{ID: "aws-secret-access-key", Regexp: regexp.MustCompile(`(?i)aws.{0,20}(?:secret|key).{0,20}['"]([A-Za-z0-9/+=]{40})['"]`), MinEntropy: 3.5},
+	{ID: "private-key", Regexp: regexp.MustCompile(`-----BEGIN (?:RSA |EC |DSA |OPENSSH |PGP |ENCRYPTED )?PRIVATE KEY(?: BLOCK)?-----`)},
+	{ID: "github-token", Regexp: regexp.MustCompile(`\b(gh[pousr]_[A-Za-z0-9]{36,})\b`)},
+	{ID: "slack-token", Regexp: regexp.MustCompile(`\b(xox[baprs]-[A-Za-z0-9-]{10,})\b`)},
+	{ID: "google-api-key", Regexp: regexp.MustCompile(`\b(AIza[0-9A-Za-z_\-]{35})\b`)},
+	{ID: "stripe-key", Regexp: regexp.MustCompile(`\b((?:sk|rk)_live_[0-9a-zA-Z]{24,})\b`)},
+	{ID: "generic-secret", Regexp: regexp.MustCompile(`(?i)(?:secret|token|passw(?:or)?d|pwd|api[_-]?key)[\w.-]*['"]?\s*[:=]+\s*['"]([^'"\s]{12,})['"]`), MinEntropy: 3.5},
+	{ID: "high-entropy-string", Regexp: regexp.MustCompile(`['"]([A-Za-z0-9+/=_\-]{32,})['"]`), MinEntropy: 4.5},
+}
+
+// Match is a secret found in line.
+type Match struct {
+	Rule string
+	// Redacted is the beginning of the secret value, safe to output in logs.
+	Redacted string
+}
+
+// Scanner checks lines against rules.
+type Scanner struct {
+	rules []Rule
+}
+
+// New creates scanner using the passed rules. If rules is empty, DefaultRules are used.
+func New(rules []Rule) *Scanner {
+	if len(rules) == 0 {
+		rules = DefaultRules
+	}
+	s := &Scanner{}
+	s.rules = rules
+	return s
+}
+
+// maxLineLen is the max length of line checked, longer lines are usually minified or generated code
+const maxLineLen = 4096
+
+// Scan returns all secrets found in line. Only one match is returned for each rule and for each secret value, using the first matching rule.
+func (s *Scanner) Scan(line []byte) (res []Match) {
+	if len(line) > maxLineLen {
+		return nil
+	}
+	var seen map[string]bool
+	for _, r := range s.rules {
+		m := r.Regexp.FindSubmatch(line)
+		if m == nil {
+			continue
+		}
+		val := m[0]
+		if len(m) > 1 && len(m[1]) != 0 {
+			val = m[1]
+		}
+		if r.MinEntropy != 0 && Entropy(val) < r.MinEntropy {
+			continue
+		}
+		if seen[string(val)] {
+			continue
+		}
+		if seen == nil {
+			seen = map[string]bool{}
+		}
+		seen[string(val)] = true
+		res = append(res, Match{Rule: r.ID, Redacted: redact(val)})
+	}
+	return
+}
+
+// Entropy returns Shannon entropy of data in bits per byte.
+func Entropy(data []byte) float64 {
+	if len(data) == 0 {
+		return 0
+	}
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	res := 0.0
+	l := float64(len(data))
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / l
+		res -= p * math.Log2(p)
+	}
+	return res
+}
+
+// redactKeep is the number of chars of the secret kept in Redacted
+const redactKeep = 4
+
+func redact(val []byte) string {
+	if len(val) <= redactKeep*2 {
+		return "***"
+	}
+	return string(val[:redactKeep]) + "***"
+}
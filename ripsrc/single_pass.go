@@ -0,0 +1,40 @@
+package ripsrc
+
+import (
+	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
+	"github.com/pinpt/ripsrc/ripsrc/parentsgraph"
+	"github.com/pinpt/ripsrc/ripsrc/singlepass"
+)
+
+// useSinglePass returns true if commit graph, commit meta and patches should be read using one git log, see Opts.SinglePassLog. Single pass covers the whole history, so it is only used when processing from the beginning.
+func (s *Ripsrc) useSinglePass() bool {
+	return s.opts.SinglePassLog && s.opts.CommitFromIncl == "" && !s.opts.Resume && s.opts.DiffSource == DiffSourceGit
+}
+
+// runSinglePass sets commit graph and commit meta from one git log pass. Graph is stored in cache the same as in buildCommitGraph, so the next run only reads new commits. Returned result has patches for processing, call Remove when done.
+func (s *Ripsrc) runSinglePass() (*singlepass.Result, error) {
+	res, err := singlepass.Run(singlepass.Opts{
+		RepoDir:     s.opts.RepoDir,
+		AllBranches: s.opts.AllBranches,
+		Logger:      s.opts.Logger,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = res.Graph.Save(parentsgraph.Opts{
+		RepoDir:     s.opts.RepoDir,
+		AllBranches: s.opts.AllBranches,
+		Logger:      s.opts.Logger,
+		CacheLoc:    s.cacheLoc("graph"),
+	})
+	if err != nil {
+		res.Remove()
+		return nil, err
+	}
+	s.commitGraph = res.Graph
+	s.commitMeta = map[string]commitmeta.Commit{}
+	for _, c := range res.Commits {
+		s.commitMeta[c.SHA] = c
+	}
+	return res, nil
+}
@@ -0,0 +1,169 @@
+// Package singlepass runs one git log for the whole history and splits its output into commit graph, commit metadata and patches, so that parentsgraph, commitmeta and history3 process do not walk history separately.
+package singlepass
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/parentsgraph"
+	"github.com/pinpt/ripsrc/ripsrc/pkg/logger"
+)
+
+type Opts struct {
+	RepoDir string
+	// AllBranches set to true to process all branches. If false, processes commits reachable from HEAD only.
+	AllBranches bool
+	Logger      logger.Logger
+}
+
+// Result is the data from one git log pass.
+type Result struct {
+	// Graph is the same as returned by parentsgraph.Graph.Read.
+	Graph *parentsgraph.Graph
+	// Commits are the same as returned by commitmeta.Processor.
+	Commits []commitmeta.Commit
+
+	patchesLoc string
+}
+
+// Run executes git log and reads all of its output. Patches are kept in a compressed temp file, because commit graph and metadata have to be complete before history3 process starts. Call Remove when done.
+func Run(opts Opts) (_ *Result, rerr error) {
+	start := time.Now()
+	opts.Logger.Info("singlepass: starting git log")
+	defer func() {
+		opts.Logger.Info("singlepass: completed git log", "d", time.Since(start))
+	}()
+
+	r, err := gitLog(opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	f, err := ioutil.TempFile("", "ripsrc-patches-")
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{}
+	res.patchesLoc = f.Name()
+	defer func() {
+		if rerr != nil {
+			f.Close()
+			res.Remove()
+		}
+	}()
+	zw, err := zstd.NewWriter(f, zstd.WithEncoderLevel(zstd.SpeedFastest))
+	if err != nil {
+		return nil, err
+	}
+	patches := bufio.NewWriter(zw)
+
+	metaR, metaW := io.Pipe()
+	metaDone := make(chan error)
+	go func() {
+		commits, err := commitmeta.NewFromLog(opts.RepoDir, metaR).RunSlice()
+		res.Commits = commits
+		metaDone <- err
+	}()
+
+	meta := bufio.NewWriter(metaW)
+	sp := newSplitter(patches, meta)
+	err = sp.Run(r)
+	if err == nil {
+		err = meta.Flush()
+	}
+	metaW.CloseWithError(err)
+	metaErr := <-metaDone
+	if err != nil {
+		return nil, err
+	}
+	if metaErr != nil {
+		return nil, metaErr
+	}
+
+	err = patches.Flush()
+	if err != nil {
+		return nil, err
+	}
+	err = zw.Close()
+	if err != nil {
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+	res.Graph = parentsgraph.NewFromMap(sp.parents)
+	return res, nil
+}
+
+func gitLog(opts Opts) (io.ReadCloser, error) {
+	// empty file at temp location to set an empty attributesFile
+	f, err := ioutil.TempFile("", "ripsrc")
+	if err != nil {
+		return nil, err
+	}
+	err = f.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// combines arguments used by commitmeta and history3 process, --pretty=raw includes parents and metadata for every merge part
+	args := []string{
+		"-c", "core.attributesFile=" + f.Name(),
+		"-c", "diff.renameLimit=10000",
+		"log",
+		"-p",
+		"-m",
+		"--raw",
+		"--numstat",
+		"--date-order",
+		"--reverse",
+		"--no-abbrev",
+		"--pretty=raw",
+	}
+	if opts.AllBranches {
+		args = append(args, "--all")
+	}
+	return gitexec.ExecPiped(context.Background(), "git", opts.RepoDir, args)
+}
+
+// Patches returns git log -p output in the format read by history3 process.
+func (s *Result) Patches() (io.ReadCloser, error) {
+	f, err := os.Open(s.patchesLoc)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &patchesReader{f: f, zr: zr}, nil
+}
+
+// Remove deletes temp file with patches.
+func (s *Result) Remove() error {
+	return os.Remove(s.patchesLoc)
+}
+
+type patchesReader struct {
+	f  *os.File
+	zr *zstd.Decoder
+}
+
+func (s *patchesReader) Read(p []byte) (int, error) {
+	return s.zr.Read(p)
+}
+
+func (s *patchesReader) Close() error {
+	s.zr.Close()
+	return s.f.Close()
+}
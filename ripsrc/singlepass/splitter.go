@@ -0,0 +1,299 @@
+package singlepass
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc/commitmeta"
+)
+
+// splitter reads git log -p -m --raw --numstat --pretty=raw output. Writes patches in the format of git log -p -m --pretty=short used by history3 process, and commit headers with --raw and --numstat lines in the format used by commitmeta. Collects parents for commit graph.
+//
+// For merges commitmeta runs git log -c, which prints numstat against the first parent and combined raw lines that are not used by its parser. Here numstat of the part diffed against the first parent is passed instead, giving the same result.
+type splitter struct {
+	patches io.Writer
+	meta    io.Writer
+	parents map[string][]string
+
+	state state
+	part  part
+	// parts is the number of commit parts written to patches
+	parts int
+
+	// commit is the commit meta for the current hash, written when the next commit starts
+	commit      *commitmeta.Commit
+	commitFiles []string
+}
+
+type state int
+
+const (
+	stNotStarted state = iota
+	stHeader
+	stMessage
+	stDiff
+	stPatch
+)
+
+// part is one commit in log output, merge commits are repeated for each parent
+type part struct {
+	hash          string
+	mergeDiffFrom string
+	commit        commitmeta.Commit
+	message       []string
+	raw           []string
+	numstat       []string
+}
+
+func newSplitter(patches, meta io.Writer) *splitter {
+	s := &splitter{}
+	s.patches = patches
+	s.meta = meta
+	s.parents = map[string][]string{}
+	return s
+}
+
+const mb = 1000 * 1000
+const maxLine = 100 * mb
+
+func (s *splitter) Run(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxLine)
+	for scanner.Scan() {
+		err := s.line(scanner.Text())
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if s.state != stNotStarted {
+		err := s.endPart()
+		if err != nil {
+			return err
+		}
+	}
+	return s.endCommit()
+}
+
+func (s *splitter) line(line string) error {
+	switch s.state {
+	case stNotStarted:
+		return s.startPart(line)
+	case stHeader:
+		if line == "" {
+			s.state = stMessage
+			return nil
+		}
+		return s.headerLine(line)
+	case stMessage:
+		if strings.HasPrefix(line, messageIndent) {
+			s.part.message = append(s.part.message, line[len(messageIndent):])
+			return nil
+		}
+		s.state = stDiff
+		return s.line(line)
+	case stDiff:
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "commit "):
+			return s.nextPart(line)
+		case strings.HasPrefix(line, ":"):
+			s.part.raw = append(s.part.raw, line)
+		case strings.HasPrefix(line, "diff "):
+			s.state = stPatch
+			return s.line(line)
+		default:
+			s.part.numstat = append(s.part.numstat, line)
+		}
+	case stPatch:
+		switch {
+		case line == "":
+			// only used as separator between commits, patch lines always have a prefix
+		case strings.HasPrefix(line, "commit "):
+			return s.nextPart(line)
+		default:
+			return s.writePatch(line)
+		}
+	default:
+		panic(fmt.Errorf("unknown state %v", s.state))
+	}
+	return nil
+}
+
+const messageIndent = "    "
+
+func (s *splitter) startPart(line string) error {
+	data := strings.TrimPrefix(line, "commit ")
+	if data == line {
+		return fmt.Errorf("expected commit line, got %v", line)
+	}
+	s.part = part{}
+	if i := strings.Index(data, " "); i != -1 {
+		s.part.hash = data[:i]
+		s.part.mergeDiffFrom = strings.TrimSuffix(strings.TrimPrefix(data[i+1:], "(from "), ")")
+	} else {
+		s.part.hash = data
+	}
+	s.part.commit.SHA = s.part.hash
+	s.state = stHeader
+
+	// header in --pretty=short format, history3 parser skips the author line
+	if s.parts != 0 {
+		// separator between commits, same as in git output
+		err := s.writePatch("")
+		if err != nil {
+			return err
+		}
+	}
+	s.parts++
+	err := s.writePatch(line)
+	if err != nil {
+		return err
+	}
+	return s.writePatch("Author: -")
+}
+
+func (s *splitter) nextPart(line string) error {
+	err := s.endPart()
+	if err != nil {
+		return err
+	}
+	return s.startPart(line)
+}
+
+func (s *splitter) headerLine(line string) error {
+	if strings.HasPrefix(line, " ") {
+		// continuation of multiline header, such as gpgsig
+		return nil
+	}
+	c := &s.part.commit
+	kv := strings.SplitN(line, " ", 2)
+	if len(kv) != 2 {
+		return nil
+	}
+	k, v := kv[0], kv[1]
+	switch k {
+	case "parent":
+		c.Parents = append(c.Parents, v)
+	case "author":
+		name, email, date, err := parseIdent(v)
+		if err != nil {
+			return fmt.Errorf("commit %v: %v", c.SHA, err)
+		}
+		c.AuthorName = name
+		c.AuthorEmail = email
+		c.Date = date
+	case "committer":
+		name, email, _, err := parseIdent(v)
+		if err != nil {
+			return fmt.Errorf("commit %v: %v", c.SHA, err)
+		}
+		c.CommitterName = name
+		c.CommitterEmail = email
+	}
+	return nil
+}
+
+func (s *splitter) endPart() error {
+	p := s.part
+	if s.commit == nil || s.commit.SHA != p.hash {
+		err := s.endCommit()
+		if err != nil {
+			return err
+		}
+		c := p.commit
+		c.Message = subject(p.message)
+		s.commit = &c
+		s.parents[p.hash] = c.Parents
+	}
+	parents := s.commit.Parents
+	if len(parents) <= 1 {
+		s.commitFiles = append(s.commitFiles, p.raw...)
+		s.commitFiles = append(s.commitFiles, p.numstat...)
+	} else if p.mergeDiffFrom == parents[0] {
+		s.commitFiles = append(s.commitFiles, p.numstat...)
+	}
+	return nil
+}
+
+func (s *splitter) endCommit() error {
+	if s.commit == nil {
+		return nil
+	}
+	data := &strings.Builder{}
+	data.WriteString(commitmeta.LogHeader(*s.commit))
+	data.WriteString("\n")
+	for _, l := range s.commitFiles {
+		data.WriteString(l)
+		data.WriteString("\n")
+	}
+	data.WriteString("\n")
+	s.commit = nil
+	s.commitFiles = nil
+	_, err := io.WriteString(s.meta, data.String())
+	return err
+}
+
+func (s *splitter) writePatch(line string) error {
+	_, err := io.WriteString(s.patches, line+"\n")
+	return err
+}
+
+// subject returns the same as git %s, lines of the first paragraph joined with space
+func subject(message []string) string {
+	var res []string
+	for _, l := range message {
+		if strings.TrimSpace(l) == "" {
+			if len(res) == 0 {
+				continue
+			}
+			break
+		}
+		res = append(res, strings.TrimRight(l, " \t\r\n\v\f"))
+	}
+	return strings.Join(res, " ")
+}
+
+// parseIdent parses author or committer line of raw commit, in Name <email> timestamp timezone format. Returns date in the zone from commit, same as git %aI.
+func parseIdent(v string) (name, email string, date time.Time, _ error) {
+	lt := strings.Index(v, "<")
+	if lt == -1 {
+		return "", "", date, fmt.Errorf("invalid ident, no email: %v", v)
+	}
+	gt := strings.Index(v[lt:], ">")
+	if gt == -1 {
+		return "", "", date, fmt.Errorf("invalid ident, no email end: %v", v)
+	}
+	gt += lt
+	name = strings.TrimRight(v[:lt], " \t")
+	email = v[lt+1 : gt]
+	fields := strings.Fields(v[gt+1:])
+	if len(fields) != 2 {
+		return "", "", date, fmt.Errorf("invalid ident, no date: %v", v)
+	}
+	ts, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return "", "", date, fmt.Errorf("invalid ident date: %v", v)
+	}
+	tz := fields[1]
+	if len(tz) != 5 {
+		return "", "", date, fmt.Errorf("invalid ident timezone: %v", v)
+	}
+	hh, err1 := strconv.Atoi(tz[1:3])
+	mm, err2 := strconv.Atoi(tz[3:5])
+	if err1 != nil || err2 != nil {
+		return "", "", date, fmt.Errorf("invalid ident timezone: %v", v)
+	}
+	offset := hh*3600 + mm*60
+	if tz[0] == '-' {
+		offset = -offset
+	}
+	date = time.Unix(ts, 0).In(time.FixedZone("", offset))
+	return name, email, date, nil
+}
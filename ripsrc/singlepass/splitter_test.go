@@ -0,0 +1,199 @@
+package singlepass
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const c1 = "1111111111111111111111111111111111111111"
+const c2 = "2222222222222222222222222222222222222222"
+const c3 = "3333333333333333333333333333333333333333"
+
+func TestSplitterMerge(t *testing.T) {
+	data := `commit ` + c1 + `
+tree aaff74984cccd156a469afa7d9ab10e4777beb24
+author A B <a@b> 1545944136 +0100
+committer C <c@d> 1545944136 +0100
+
+    subject
+    continued
+    ` + `
+    body
+
+:000000 100644 0000000000000000000000000000000000000000 78981922613b2afb6025042ff6bd878ac1994e85 A	a
+1	0	a
+
+diff --git a/a b/a
+new file mode 100644
+index 0000000..7898192
+--- /dev/null
++++ b/a
+@@ -0,0 +1 @@
++a
+
+commit ` + c3 + ` (from ` + c1 + `)
+tree aaff74984cccd156a469afa7d9ab10e4777beb24
+parent ` + c1 + `
+parent ` + c2 + `
+author A B <a@b> 1545944136 -0130
+committer A B <a@b> 1545944136 -0130
+gpgsig -----BEGIN PGP SIGNATURE-----
+ data
+ -----END PGP SIGNATURE-----
+
+    merge
+
+:000000 100644 0000000000000000000000000000000000000000 61780798228d17af2d34fce4cfbdf35556832472 A	b
+1	0	b
+
+diff --git a/b b/b
+new file mode 100644
+index 0000000..6178079
+--- /dev/null
++++ b/b
+@@ -0,0 +1 @@
++b
+
+commit ` + c3 + ` (from ` + c2 + `)
+tree aaff74984cccd156a469afa7d9ab10e4777beb24
+parent ` + c1 + `
+parent ` + c2 + `
+author A B <a@b> 1545944136 -0130
+committer A B <a@b> 1545944136 -0130
+
+    merge
+
+:100644 100644 78981922613b2afb6025042ff6bd878ac1994e85 61780798228d17af2d34fce4cfbdf35556832472 M	a
+1	1	a
+
+diff --git a/a b/a
+index 7898192..6178079 100644
+--- a/a
++++ b/a
+@@ -1 +1 @@
+-a
++b
+`
+	patches := &bytes.Buffer{}
+	meta := &bytes.Buffer{}
+	sp := newSplitter(patches, meta)
+	err := sp.Run(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	wantPatches := `commit ` + c1 + `
+Author: -
+diff --git a/a b/a
+new file mode 100644
+index 0000000..7898192
+--- /dev/null
++++ b/a
+@@ -0,0 +1 @@
++a
+
+commit ` + c3 + ` (from ` + c1 + `)
+Author: -
+diff --git a/b b/b
+new file mode 100644
+index 0000000..6178079
+--- /dev/null
++++ b/b
+@@ -0,0 +1 @@
++b
+
+commit ` + c3 + ` (from ` + c2 + `)
+Author: -
+diff --git a/a b/a
+index 7898192..6178079 100644
+--- a/a
++++ b/a
+@@ -1 +1 @@
+-a
++b
+`
+	assert.Equal(t, wantPatches, patches.String())
+
+	// merges only have numstat against the first parent, same as git log -c
+	wantMeta := `!SHA: ` + c1 + `
+!Parents: ` + `
+!Committer: c@d
+!CName: C
+!Author: a@b
+!AName: A B
+!Date: 2018-12-27T21:55:36+01:00
+!Message: subject continued
+
+:000000 100644 0000000000000000000000000000000000000000 78981922613b2afb6025042ff6bd878ac1994e85 A	a
+1	0	a
+
+!SHA: ` + c3 + `
+!Parents: ` + c1 + ` ` + c2 + `
+!Committer: a@b
+!CName: A B
+!Author: a@b
+!AName: A B
+!Date: 2018-12-27T19:25:36-01:30
+!Message: merge
+
+1	0	b
+
+`
+	assert.Equal(t, wantMeta, meta.String())
+
+	wantParents := map[string][]string{
+		c1: nil,
+		c3: {c1, c2},
+	}
+	assert.Equal(t, wantParents, sp.parents)
+}
+
+func TestSplitterEmptyCommit(t *testing.T) {
+	data := `commit ` + c1 + `
+tree aaff74984cccd156a469afa7d9ab10e4777beb24
+author A <a@b> 1545944136 +0000
+committer A <a@b> 1545944136 +0000
+
+commit ` + c2 + `
+tree aaff74984cccd156a469afa7d9ab10e4777beb24
+parent ` + c1 + `
+author A <a@b> 1545944136 +0000
+committer A <a@b> 1545944136 +0000
+
+    empty
+`
+	patches := &bytes.Buffer{}
+	meta := &bytes.Buffer{}
+	sp := newSplitter(patches, meta)
+	err := sp.Run(strings.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPatches := `commit ` + c1 + `
+Author: -
+
+commit ` + c2 + `
+Author: -
+`
+	assert.Equal(t, wantPatches, patches.String())
+	assert.Equal(t, map[string][]string{c1: nil, c2: {c1}}, sp.parents)
+}
+
+func TestParseIdent(t *testing.T) {
+	name, email, date, err := parseIdent("A B <a@b> 1545944136 +0545")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "A B", name)
+	assert.Equal(t, "a@b", email)
+	assert.Equal(t, "2018-12-28T02:40:36+05:45", date.Format(time.RFC3339))
+
+	_, _, _, err = parseIdent("A B a@b 1545944136 +0545")
+	if err == nil {
+		t.Fatal("expected error for ident without email")
+	}
+}
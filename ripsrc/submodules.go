@@ -0,0 +1,127 @@
+package ripsrc
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/pinpt/ripsrc/ripsrc/gitexec"
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+)
+
+// SubmoduleChange is a change of submodule commit pointer (gitlink) in commit. Submodules are not returned as files in CommitCode.Blames.
+type SubmoduleChange struct {
+	Path   string
+	Status CommitStatus
+	// From is the submodule commit before change. Empty for added submodules and in merge commits.
+	From string
+	// To is the submodule commit after change. Empty for removed submodules.
+	To string
+}
+
+// Submodule is a submodule in repo at HEAD.
+type Submodule struct {
+	Path   string
+	Commit string
+	// Dir is the location of submodule checkout. Empty if submodule is not checked out, for example in bare repos.
+	Dir string
+}
+
+// git log -p shows gitlinks as a file with this single line
+var submoduleLine = regexp.MustCompile(`^Subproject commit ([0-9a-f]{40})$`)
+
+// submoduleCommit returns the commit of submodule if blame data looks like a gitlink. Regular file could have the same content, so only used for merges where mode of files is not known.
+func submoduleCommit(bl *incblame.Blame) (string, bool) {
+	if bl == nil || bl.IsBinary || len(bl.Lines) != 1 {
+		return "", false
+	}
+	m := submoduleLine.FindSubmatch(bl.Lines[0].Line)
+	if m == nil {
+		return "", false
+	}
+	return string(m[1]), true
+}
+
+// submoduleChange returns change if file is a submodule. Uses 160000 mode from commit meta, for merges also checks blame data, since commit meta does not mark submodules in merges.
+func submoduleChange(filePath string, f *CommitFile, bl *incblame.Blame, merge bool) (res SubmoduleChange, _ bool) {
+	to, ok := "", false
+	if merge {
+		to, ok = submoduleCommit(bl)
+	}
+	if !f.Submodule && !ok {
+		return res, false
+	}
+	res.Path = filePath
+	res.Status = f.Status
+	res.From = f.SubmoduleFrom
+	res.To = f.SubmoduleTo
+	if res.To == "" && res.Status != GitFileCommitStatusRemoved {
+		res.To = to
+	}
+	return res, true
+}
+
+// Submodules returns submodules at HEAD.
+func (s *Ripsrc) Submodules(ctx context.Context) (res []Submodule, _ error) {
+	res, err := s.treeSubmodules(ctx, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	for i := range res {
+		sub := &res[i]
+		dir := filepath.Join(s.opts.RepoDir, filepath.FromSlash(sub.Path))
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			sub.Dir = dir
+		}
+	}
+	return
+}
+
+// treeSubmodules returns gitlinks (tree entries with 160000 mode) in commit. Dir is not set.
+func (s *Ripsrc) treeSubmodules(ctx context.Context, commit string) (res []Submodule, _ error) {
+	r, err := gitexec.Exec(ctx, gitCommand, s.opts.RepoDir, []string{"ls-tree", "-r", "-z", commit})
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range bytes.Split(data, []byte{0}) {
+		// <mode> SP <type> SP <object> TAB <file>
+		tab := bytes.IndexByte(entry, '\t')
+		if tab == -1 {
+			continue
+		}
+		meta := bytes.Fields(entry[:tab])
+		if len(meta) != 3 {
+			return nil, fmt.Errorf("unexpected ls-tree output: %s", entry)
+		}
+		if string(meta[0]) != "160000" {
+			continue
+		}
+		sub := Submodule{}
+		sub.Path = string(entry[tab+1:])
+		sub.Commit = string(meta[2])
+		res = append(res, sub)
+	}
+	return
+}
+
+// submodulePaths returns paths of submodules in commit.
+func (s *Ripsrc) submodulePaths(ctx context.Context, commit string) (map[string]bool, error) {
+	subs, err := s.treeSubmodules(ctx, commit)
+	if err != nil {
+		return nil, err
+	}
+	res := map[string]bool{}
+	for _, sub := range subs {
+		res[sub.Path] = true
+	}
+	return res, nil
+}
@@ -0,0 +1,195 @@
+package ripsrc
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// SummaryOpts controls the commits for which Summary is returned.
+type SummaryOpts struct {
+	// Commits to create summary at. If empty, summary is created for the last processed commit (HEAD).
+	Commits []string
+}
+
+// Summary contains the totals for repo at a specific commit.
+type Summary struct {
+	Commit string
+	Date   time.Time
+
+	// Files is the number of all files in repo, including skipped.
+	Files int
+	// SourceFiles is the number of files that were not skipped.
+	SourceFiles int
+
+	Loc        int64
+	Sloc       int64
+	Comments   int64
+	Blanks     int64
+	Complexity int64
+
+	// Languages sorted by Sloc desc.
+	Languages []LanguageSummary
+	// Authors of the lines in source files sorted by Lines desc. Bots are not included when Opts.BotLines is BotLinesExclude.
+	Authors []AuthorSummary
+	// Licenses detected in license files sorted by Filename.
+	Licenses []LicenseSummary
+	// Skipped files by reason sorted by Files desc. Numbers in reasons are replaced with N.
+	Skipped []SkippedSummary
+
+	// LFSFiles is the number of git lfs pointer files. These are included in Skipped.
+	LFSFiles int
+	// LFSSize is the total size of files stored in git lfs.
+	LFSSize int64
+}
+
+// LanguageSummary contains totals for a language.
+type LanguageSummary struct {
+	Language   string
+	Files      int
+	Loc        int64
+	Sloc       int64
+	Comments   int64
+	Blanks     int64
+	Complexity int64
+}
+
+// AuthorSummary contains the number of lines by author in blame.
+type AuthorSummary struct {
+	Name  string
+	Email string
+	// Bot is true if author matches Opts.BotAuthors.
+	Bot bool
+	// Lines is the number of all lines, including blank and comments.
+	Lines int64
+	// Sloc is the number of code lines.
+	Sloc int64
+}
+
+// LicenseSummary is a license detected in a license file.
+type LicenseSummary struct {
+	Filename string
+	License
+}
+
+// SkippedSummary is the number of files skipped for a reason.
+type SkippedSummary struct {
+	Reason string
+	Files  int
+}
+
+// Summary returns totals at HEAD or at the passed commits: languages, files, authors, licenses and skipped file reasons. Processes all commits since CommitFromIncl.
+func (s *Ripsrc) Summary(ctx context.Context, opts SummaryOpts) (res []Summary, _ error) {
+	err := s.codeAtCommits(ctx, opts.Commits, func(commit Commit, files map[string]BlameResult) {
+		res = append(res, s.summarize(commit, files))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func sortLanguages(langs []LanguageSummary) {
+	sort.Slice(langs, func(i, j int) bool {
+		a := langs[i]
+		b := langs[j]
+		if a.Sloc != b.Sloc {
+			return a.Sloc > b.Sloc
+		}
+		return a.Language < b.Language
+	})
+}
+
+var skippedNumbers = regexp.MustCompile(`\d+`)
+
+func (s *Ripsrc) summarize(commit Commit, files map[string]BlameResult) (res Summary) {
+	res.Commit = commit.SHA
+	res.Date = commit.Date
+
+	languages := map[string]*LanguageSummary{}
+	authors := map[string]*AuthorSummary{}
+	skipped := map[string]int{}
+
+	for _, f := range files {
+		res.Files++
+		if f.License != nil {
+			res.Licenses = append(res.Licenses, LicenseSummary{Filename: f.Filename, License: *f.License})
+		}
+		if f.LFS != nil {
+			res.LFSFiles++
+			res.LFSSize += f.LFS.Size
+		}
+		if f.Skipped != "" {
+			skipped[skippedNumbers.ReplaceAllString(f.Skipped, "N")]++
+			continue
+		}
+		res.SourceFiles++
+		res.Loc += f.Loc
+		res.Sloc += f.Sloc
+		res.Comments += f.Comments
+		res.Blanks += f.Blanks
+		res.Complexity += f.Complexity
+
+		lang, ok := languages[f.Language]
+		if !ok {
+			lang = &LanguageSummary{Language: f.Language}
+			languages[f.Language] = lang
+		}
+		lang.Files++
+		lang.Loc += f.Loc
+		lang.Sloc += f.Sloc
+		lang.Comments += f.Comments
+		lang.Blanks += f.Blanks
+		lang.Complexity += f.Complexity
+
+		for _, l := range f.Lines {
+			if !s.ownsLine(l) {
+				continue
+			}
+			a, ok := authors[l.Email]
+			if !ok {
+				a = &AuthorSummary{Name: l.Name, Email: l.Email, Bot: l.Bot}
+				authors[l.Email] = a
+			}
+			a.Lines++
+			if l.Code {
+				a.Sloc++
+			}
+		}
+	}
+
+	for _, l := range languages {
+		res.Languages = append(res.Languages, *l)
+	}
+	sortLanguages(res.Languages)
+
+	for _, a := range authors {
+		res.Authors = append(res.Authors, *a)
+	}
+	sort.Slice(res.Authors, func(i, j int) bool {
+		a := res.Authors[i]
+		b := res.Authors[j]
+		if a.Lines != b.Lines {
+			return a.Lines > b.Lines
+		}
+		return a.Email < b.Email
+	})
+
+	sort.Slice(res.Licenses, func(i, j int) bool {
+		return res.Licenses[i].Filename < res.Licenses[j].Filename
+	})
+
+	for reason, c := range skipped {
+		res.Skipped = append(res.Skipped, SkippedSummary{Reason: reason, Files: c})
+	}
+	sort.Slice(res.Skipped, func(i, j int) bool {
+		a := res.Skipped[i]
+		b := res.Skipped[j]
+		if a.Files != b.Files {
+			return a.Files > b.Files
+		}
+		return a.Reason < b.Reason
+	})
+	return
+}
@@ -0,0 +1,167 @@
+package ripsrc
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pinpt/ripsrc/ripsrc/history3/incblame"
+)
+
+// DefaultTODOTags are the tags matched in comments when Opts.TODOTags is empty.
+var DefaultTODOTags = []string{"TODO", "FIXME", "HACK"}
+
+// TODO is a comment line containing one of the tags.
+type TODO struct {
+	Filename string
+	// Line is the line number in file starting from 1.
+	Line int
+	// Tag is the matched tag, for example TODO.
+	Tag string
+	// Text is the comment text after the tag.
+	Text string
+	// Name, Email, SHA and Date are from the blame of the line.
+	Name  string
+	Email string
+	SHA   string
+	Date  time.Time
+	// Age is the age of the line relative to the commit date.
+	Age time.Duration
+}
+
+func todoRegexp(tags []string) *regexp.Regexp {
+	if len(tags) == 0 {
+		tags = DefaultTODOTags
+	}
+	var quoted []string
+	for _, t := range tags {
+		quoted = append(quoted, regexp.QuoteMeta(t))
+	}
+	return regexp.MustCompile(`\b(` + strings.Join(quoted, "|") + `)\b(?:\([^)]*\))?:?\s*(.*)`)
+}
+
+// commentEnd removes block comment terminators from the end of the text
+var commentEnd = regexp.MustCompile(`\s*(\*/|-->|\*\)|"""|''')\s*$`)
+
+// findTODOs returns comment lines with tags. Only lines marked as comment by scc are checked.
+func (s *Ripsrc) findTODOs(res BlameResult, bl *incblame.Blame) (todos []TODO) {
+	for i, l := range res.Lines {
+		if !l.Comment {
+			continue
+		}
+		m := s.todoRegexp.FindSubmatch(bl.Lines[i].Line)
+		if m == nil {
+			continue
+		}
+		age := res.Commit.Date.Sub(l.Date)
+		if age < 0 {
+			age = 0
+		}
+		todos = append(todos, TODO{
+			Filename: res.Filename,
+			Line:     i + 1,
+			Tag:      string(m[1]),
+			Text:     commentEnd.ReplaceAllString(strings.TrimSpace(string(m[2])), ""),
+			Name:     l.Name,
+			Email:    l.Email,
+			SHA:      l.SHA,
+			Date:     l.Date,
+			Age:      age,
+		})
+	}
+	return
+}
+
+// TODOsOpts controls the commits for which TODOs are returned.
+type TODOsOpts struct {
+	// Commits to return TODOs at. If empty, TODOs are returned for the last processed commit (HEAD).
+	Commits []string
+}
+
+// TODOReport contains all TODOs in repo at a specific commit.
+type TODOReport struct {
+	Commit string
+	Date   time.Time
+	// TODOs sorted by Age desc.
+	TODOs []TODO
+	// Authors sorted by TODOs desc.
+	Authors []TODOAuthorSummary
+}
+
+// TODOAuthorSummary is the number of TODOs by author with age stats.
+type TODOAuthorSummary struct {
+	Name   string
+	Email  string
+	TODOs  int
+	Oldest time.Duration
+	Median time.Duration
+}
+
+// TODOs returns comments with TODO tags at HEAD or at the passed commits, with author and age from blame. Opts.TODOs does not need to be set. Processes all commits since CommitFromIncl.
+func (s *Ripsrc) TODOs(ctx context.Context, opts TODOsOpts) (res []TODOReport, _ error) {
+	s.opts.TODOs = true
+	err := s.codeAtCommits(ctx, opts.Commits, func(commit Commit, files map[string]BlameResult) {
+		res = append(res, todoReport(commit, files))
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+func todoReport(commit Commit, files map[string]BlameResult) (res TODOReport) {
+	res.Commit = commit.SHA
+	res.Date = commit.Date
+	ages := map[string][]time.Duration{}
+	authors := map[string]*TODOAuthorSummary{}
+	for _, f := range files {
+		for _, t := range f.TODOs {
+			// age relative to the requested commit, not the one that last modified the file
+			t.Age = commit.Date.Sub(t.Date)
+			if t.Age < 0 {
+				t.Age = 0
+			}
+			res.TODOs = append(res.TODOs, t)
+			a, ok := authors[t.Email]
+			if !ok {
+				a = &TODOAuthorSummary{Name: t.Name, Email: t.Email}
+				authors[t.Email] = a
+			}
+			a.TODOs++
+			if t.Age > a.Oldest {
+				a.Oldest = t.Age
+			}
+			ages[t.Email] = append(ages[t.Email], t.Age)
+		}
+	}
+	sort.Slice(res.TODOs, func(i, j int) bool {
+		a := res.TODOs[i]
+		b := res.TODOs[j]
+		if a.Age != b.Age {
+			return a.Age > b.Age
+		}
+		if a.Filename != b.Filename {
+			return a.Filename < b.Filename
+		}
+		return a.Line < b.Line
+	})
+	for email, a := range authors {
+		aa := ages[email]
+		sort.Slice(aa, func(i, j int) bool {
+			return aa[i] < aa[j]
+		})
+		a.Median = aa[len(aa)-(len(aa)+1)/2]
+		res.Authors = append(res.Authors, *a)
+	}
+	sort.Slice(res.Authors, func(i, j int) bool {
+		a := res.Authors[i]
+		b := res.Authors[j]
+		if a.TODOs != b.TODOs {
+			return a.TODOs > b.TODOs
+		}
+		return a.Email < b.Email
+	})
+	return
+}